@@ -9,11 +9,20 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"adapter-matrix/internal/app"
+	"adapter-matrix/internal/matrix"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate-payload" {
+		os.Exit(runValidatePayload(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "read-receipts" {
+		os.Exit(runReadReceipts(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	logger := log.New(os.Stdout, "", log.LstdFlags|log.LUTC)
 
 	cfg, err := loadConfig()
@@ -27,7 +36,7 @@ func main() {
 			logger.Printf("[DEBUG] "+format, args...)
 		}
 	}
-	debugf("config loaded: homeserver_url=%s matrix_user_id=%s outbox_tables=%v adapter_outbox=%s poll_interval=%s max_retries=%d batch_size=%d allowed_room_ids=%v",
+	debugf("config loaded: homeserver_url=%s matrix_user_id=%s outbox_tables=%v adapter_outbox=%s poll_interval=%s max_retries=%d batch_size=%d allowed_room_ids=%v templates_dir=%s template_refresh=%s default_locale=%s timezone=%s reject_timetable_anomalies=%t timetable_calendar=%t default_msgtype=%s allowed_msgtypes=%v topic_interval=%s media_root=%s media_columns=%v receipt_summary_interval=%s command_power_levels=%v inbox_table=%s inbox_room_ids=%v inbox_msgtypes=%v",
 		cfg.HomeserverURL,
 		cfg.MatrixUserID,
 		cfg.OutboxTables,
@@ -36,6 +45,22 @@ func main() {
 		cfg.MaxRetries,
 		cfg.OutboxBatchSize,
 		cfg.AllowedRoomIDs,
+		cfg.TemplatesDir,
+		cfg.TemplateRefresh,
+		cfg.DefaultLocale,
+		cfg.Timezone,
+		cfg.RejectTimetableAnomalies,
+		cfg.TimetableCalendar,
+		cfg.DefaultMsgType,
+		cfg.AllowedMsgTypes,
+		cfg.TopicInterval,
+		cfg.MediaRoot,
+		cfg.MediaColumns,
+		cfg.ReceiptSummaryInterval,
+		cfg.CommandPowerLevels,
+		cfg.InboxTable,
+		cfg.InboxRoomIDs,
+		cfg.InboxMsgTypes,
 	)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -68,6 +93,13 @@ func loadConfig() (app.Config, error) {
 	cfg.MatrixUserID = strings.TrimSpace(os.Getenv("MATRIX_USER_ID"))
 	cfg.AccessToken = strings.TrimSpace(os.Getenv("MATRIX_ACCESS_TOKEN"))
 	cfg.AdapterOutbox = strings.TrimSpace(getEnv("ADAPTER_OUTBOX_TABLE", "adapter_outbox"))
+	cfg.TemplatesDir = strings.TrimSpace(os.Getenv("TEMPLATES_DIR"))
+	cfg.DefaultLocale = strings.TrimSpace(getEnv("DEFAULT_LOCALE", "en"))
+	cfg.MediaRoot = strings.TrimSpace(os.Getenv("MEDIA_ROOT"))
+	cfg.MediaColumns = splitCSV(os.Getenv("MEDIA_BYTEA_COLUMNS"))
+	cfg.InboxTable = strings.TrimSpace(getEnv("INBOX_TABLE", "adapter_inbox"))
+	cfg.InboxRoomIDs = splitCSV(os.Getenv("INBOX_ROOM_IDS"))
+	cfg.InboxMsgTypes = splitCSV(getEnv("INBOX_MSGTYPES", "text"))
 
 	pollIntervalStr := strings.TrimSpace(getEnv("POLL_INTERVAL", "5s"))
 	pollInterval, err := time.ParseDuration(pollIntervalStr)
@@ -90,11 +122,62 @@ func loadConfig() (app.Config, error) {
 	}
 	cfg.OutboxBatchSize = batchSize
 
+	timezoneStr := strings.TrimSpace(getEnv("INSTITUTE_TIMEZONE", "Asia/Kolkata"))
+	timezone, err := time.LoadLocation(timezoneStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Timezone = timezone
+
+	rejectAnomaliesStr := strings.TrimSpace(getEnv("TIMETABLE_REJECT_ANOMALIES", "false"))
+	rejectAnomalies, err := strconv.ParseBool(rejectAnomaliesStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.RejectTimetableAnomalies = rejectAnomalies
+
+	calendarStr := strings.TrimSpace(getEnv("TIMETABLE_ICS_ATTACHMENT", "true"))
+	calendar, err := strconv.ParseBool(calendarStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.TimetableCalendar = calendar
+
+	templateRefreshStr := strings.TrimSpace(getEnv("TEMPLATE_REFRESH_INTERVAL", "30s"))
+	templateRefresh, err := time.ParseDuration(templateRefreshStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.TemplateRefresh = templateRefresh
+
+	topicIntervalStr := strings.TrimSpace(getEnv("TOPIC_MIN_INTERVAL", "10m"))
+	topicInterval, err := time.ParseDuration(topicIntervalStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.TopicInterval = topicInterval
+
+	receiptSummaryStr := strings.TrimSpace(getEnv("RECEIPT_SUMMARY_INTERVAL", "1h"))
+	receiptSummary, err := time.ParseDuration(receiptSummaryStr)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ReceiptSummaryInterval = receiptSummary
+
+	commandLevels, err := parsePowerLevels(os.Getenv("COMMAND_POWER_LEVELS"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.CommandPowerLevels = commandLevels
+
 	allowedRoomsStr := strings.TrimSpace(getEnv("ALLOWED_ROOM_IDS", ""))
 	if allowedRoomsStr != "" {
 		cfg.AllowedRoomIDs = splitCSV(allowedRoomsStr)
 	}
 
+	cfg.DefaultMsgType = strings.TrimSpace(getEnv("DEFAULT_MSGTYPE", "text"))
+	cfg.AllowedMsgTypes = splitCSV(getEnv("ALLOWED_MSGTYPES", "text,notice,emote"))
+
 	outboxTablesStr := strings.TrimSpace(os.Getenv("OUTBOX_TABLES"))
 	if outboxTablesStr != "" {
 		cfg.OutboxTables = splitCSV(outboxTablesStr)
@@ -118,6 +201,9 @@ func loadConfig() (app.Config, error) {
 	if cfg.OutboxBatchSize < 1 {
 		return cfg, errInvalidBatchSize
 	}
+	if !containsMsgType(cfg.AllowedMsgTypes, cfg.DefaultMsgType) {
+		return cfg, errInvalidMsgType
+	}
 
 	return cfg, nil
 }
@@ -133,6 +219,21 @@ func isValidMatrixUserID(value string) bool {
 	return strings.Contains(trimmed, ":")
 }
 
+// containsMsgType reports whether value is a msgtype the adapter can send and
+// allowed lists it, comparing as matrix.NormalizeMsgType does.
+func containsMsgType(allowed []string, value string) bool {
+	msgType, ok := matrix.NormalizeMsgType(value)
+	if !ok {
+		return false
+	}
+	for _, candidate := range allowed {
+		if normalized, ok := matrix.NormalizeMsgType(candidate); ok && normalized == msgType {
+			return true
+		}
+	}
+	return false
+}
+
 func splitCSV(input string) []string {
 	parts := strings.Split(input, ",")
 	out := make([]string, 0, len(parts))
@@ -145,6 +246,25 @@ func splitCSV(input string) []string {
 	return out
 }
 
+// parsePowerLevels parses "name=level,..." into a map keyed by lowercase
+// command name.
+func parsePowerLevels(input string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, entry := range splitCSV(input) {
+		name, levelStr, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, errInvalidCommandLevels
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(levelStr))
+		if err != nil {
+			return nil, errInvalidCommandLevels
+		}
+		out[name] = level
+	}
+	return out, nil
+}
+
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -153,12 +273,14 @@ func getEnv(key, fallback string) string {
 }
 
 var (
-	errMissingEnv          = &configError{"required env vars missing: DATABASE_URL, MATRIX_HOMESERVER_URL, MATRIX_ACCESS_TOKEN"}
-	errMissingMatrixUserID = &configError{"MATRIX_USER_ID is required"}
-	errInvalidMatrixUserID = &configError{"MATRIX_USER_ID must look like @user:domain"}
-	errMissingOutboxTables = &configError{"OUTBOX_TABLES is required"}
-	errInvalidMaxRetries   = &configError{"MAX_RETRIES must be >= 1"}
-	errInvalidBatchSize    = &configError{"OUTBOX_BATCH_SIZE must be >= 1"}
+	errMissingEnv           = &configError{"required env vars missing: DATABASE_URL, MATRIX_HOMESERVER_URL, MATRIX_ACCESS_TOKEN"}
+	errMissingMatrixUserID  = &configError{"MATRIX_USER_ID is required"}
+	errInvalidMatrixUserID  = &configError{"MATRIX_USER_ID must look like @user:domain"}
+	errMissingOutboxTables  = &configError{"OUTBOX_TABLES is required"}
+	errInvalidMaxRetries    = &configError{"MAX_RETRIES must be >= 1"}
+	errInvalidBatchSize     = &configError{"OUTBOX_BATCH_SIZE must be >= 1"}
+	errInvalidMsgType       = &configError{"DEFAULT_MSGTYPE must be one of ALLOWED_MSGTYPES"}
+	errInvalidCommandLevels = &configError{"COMMAND_POWER_LEVELS must look like name=level,name=level"}
 )
 
 type configError struct {
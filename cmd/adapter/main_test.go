@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestContainsMsgType(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		value   string
+		want    bool
+	}{
+		{"exact", []string{"text", "notice"}, "notice", true},
+		{"prefixed and mixed case", []string{"m.Text"}, " TEXT ", true},
+		{"prefixed default", []string{"notice"}, "m.notice", true},
+		{"not listed", []string{"text"}, "emote", false},
+		{"not sendable", []string{"image"}, "m.image", false},
+		{"unknown entries are skipped", []string{"shout", "emote"}, "emote", true},
+	}
+	for _, tc := range cases {
+		if got := containsMsgType(tc.allowed, tc.value); got != tc.want {
+			t.Errorf("%s: containsMsgType(%v, %q) = %t, want %t", tc.name, tc.allowed, tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/repository"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// runReadReceipts implements `adapter read-receipts`, which prints the read
+// counts the adapter has collected for one message or a room's recent
+// messages.
+func runReadReceipts(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("read-receipts", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	eventID := fs.String("event-id", "", "outbox event ID of the message")
+	roomID := fs.String("room", "", "Matrix room ID whose messages to list")
+	since := fs.Duration("since", 7*24*time.Hour, "with -room, how far back to list messages")
+	readers := fs.Bool("readers", false, "also list each reader and when they read")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: adapter read-receipts (-event-id <uuid> | -room <room id> [-since 168h]) [-readers]")
+		fmt.Fprintln(stderr, "reads DATABASE_URL from the environment")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*eventID == "") == (*roomID == "") {
+		fs.Usage()
+		return 2
+	}
+
+	databaseURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
+	if databaseURL == "" {
+		fmt.Fprintln(stderr, "DATABASE_URL is required")
+		return 2
+	}
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	repo, err := repository.NewReceiptRepository(db, getEnv("ADAPTER_OUTBOX_TABLE", "adapter_outbox"))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	var summaries []repository.ReadSummary
+	if *eventID != "" {
+		summary, found, err := repo.Summary(ctx, strings.TrimSpace(*eventID))
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if !found {
+			fmt.Fprintf(stderr, "no delivered message for %s\n", *eventID)
+			return 1
+		}
+		summaries = append(summaries, summary)
+	} else {
+		summaries, err = repo.RoomSummaries(ctx, strings.TrimSpace(*roomID), time.Now().Add(-*since))
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	for _, summary := range summaries {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\t%d\n", summary.EventID, summary.MatrixEventID, summary.SentAt.UTC().Format(time.RFC3339), len(summary.Readers))
+		if *readers {
+			for _, reader := range summary.Readers {
+				fmt.Fprintf(stdout, "\t%s\t%s\n", reader.MatrixUserID, reader.ReadAt.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+	return 0
+}
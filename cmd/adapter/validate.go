@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"adapter-matrix/internal/handler"
+	"adapter-matrix/internal/handler/timetable"
+	"adapter-matrix/internal/schema"
+)
+
+// runValidatePayload implements `adapter validate-payload`, which checks
+// sample outbox payloads against the embedded JSON Schemas and then runs the
+// event type's handler validation, as the consumer does before rendering.
+func runValidatePayload(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate-payload", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	eventType := fs.String("event-type", "", "event type whose schema to validate against (e.g. DailyTimetableAnnounced, Message)")
+	list := fs.Bool("list", false, "list known event types and schema versions, then exit")
+	rejectAnomalies := fs.Bool("reject-anomalies", false, "also fail timetables with hard slot anomalies, as with TIMETABLE_REJECT_ANOMALIES=true")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: adapter validate-payload -event-type <type> [file ...]")
+		fmt.Fprintln(stderr, "reads the payload from stdin when no files are given")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	set := schema.Default()
+	if *list {
+		for _, name := range set.EventTypes() {
+			latest, _ := set.Lookup(name, 0)
+			fmt.Fprintf(stdout, "%s\tv%d\t%s\n", name, latest.Version, latest.ID)
+		}
+		return 0
+	}
+
+	name := strings.TrimSpace(*eventType)
+	if name == "" {
+		fs.Usage()
+		return 2
+	}
+	if !set.Has(name) {
+		fmt.Fprintf(stderr, "unknown event type %q\n", name)
+		return 2
+	}
+
+	handlers, err := validationHandlers(*rejectAnomalies)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	failed := false
+	for _, input := range inputs {
+		var payload []byte
+		var err error
+		if input == "-" {
+			payload, err = io.ReadAll(stdin)
+		} else {
+			payload, err = os.ReadFile(input)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", input, err)
+			failed = true
+			continue
+		}
+
+		err = set.Validate(name, payload)
+		if err == nil {
+			err = handlers.Check(context.Background(), name, payload)
+		}
+		if err != nil {
+			failed = true
+			var verrs schema.ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, verr := range verrs {
+					fmt.Fprintf(stdout, "%s: %s\n", input, verr.Error())
+				}
+				continue
+			}
+			fmt.Fprintf(stdout, "%s: %v\n", input, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", input)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// validationHandlers registers every handler that has a schema, without the
+// database-backed dependencies rendering needs.
+func validationHandlers(rejectAnomalies bool) (*handler.Registry, error) {
+	r := handler.NewRegistry()
+	for _, h := range []handler.Handler{
+		handler.NewMessageHandler(nil),
+		handler.NewRetractionHandler(),
+		handler.NewPollHandler(nil),
+	} {
+		if err := r.Register(h); err != nil {
+			return nil, err
+		}
+	}
+	if err := timetable.Register(r, timetable.Options{RejectAnomalies: rejectAnomalies}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
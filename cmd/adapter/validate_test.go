@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidatePayload(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(valid, []byte(`{"room_id":"!room:example.org","body":"hi","format":"plain"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte(`{"room_id":"room","body":"hi","format":"plain"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout []string
+		wantStderr string
+	}{
+		{
+			name:       "valid stdin",
+			args:       []string{"-event-type", "Message"},
+			stdin:      `{"room_id":"!room:example.org","body":"hi","format":"markdown"}`,
+			wantStdout: []string{"-: ok"},
+		},
+		{
+			name:       "invalid stdin",
+			args:       []string{"-event-type", "Message"},
+			stdin:      `{"room_id":"!room:example.org","body":"","format":"rtf"}`,
+			wantCode:   1,
+			wantStdout: []string{"-: /body: must be at least 1 characters", "-: /format: does not match pattern"},
+		},
+		{
+			name:       "files",
+			args:       []string{"-event-type", "Message", valid, invalid},
+			wantCode:   1,
+			wantStdout: []string{valid + ": ok", invalid + ": /room_id: does not match pattern"},
+		},
+		{
+			name:       "unknown schema version",
+			args:       []string{"-event-type", "Message"},
+			stdin:      `{"schema_version":99,"room_id":"!room:example.org","body":"hi","format":"plain"}`,
+			wantCode:   1,
+			wantStdout: []string{`-: no schema version 99 for event type "Message"`},
+		},
+		{
+			name:       "handler rejects an unparseable date",
+			args:       []string{"-event-type", "DailyTimetableAnnounced"},
+			stdin:      `{"schema_version":2,"class_id":"c1","date":"14.10.2024","matrix_room_id":"!room:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"soon"}]}`,
+			wantCode:   1,
+			wantStdout: []string{"-: /date: is not a recognised date", "-: /slots/0/end_time: is not a recognised time"},
+		},
+		{
+			name:       "anomalies pass by default",
+			args:       []string{"-event-type", "DailyTimetableAnnounced"},
+			stdin:      `{"class_id":"c1","date":"2024-10-14","matrix_room_id":"!room:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"10:00","end_time":"09:00"}]}`,
+			wantStdout: []string{"-: ok"},
+		},
+		{
+			name:       "anomalies rejected on request",
+			args:       []string{"-event-type", "DailyTimetableAnnounced", "-reject-anomalies"},
+			stdin:      `{"class_id":"c1","date":"2024-10-14","matrix_room_id":"!room:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"10:00","end_time":"09:00"}]}`,
+			wantCode:   1,
+			wantStdout: []string{"-: /slots/0/end_time: must be after start_time"},
+		},
+		{
+			name:       "missing file",
+			args:       []string{"-event-type", "Message", filepath.Join(dir, "missing.json")},
+			wantCode:   1,
+			wantStderr: "missing.json",
+		},
+		{
+			name:       "list",
+			args:       []string{"-list"},
+			wantStdout: []string{"Message\tv1\t", "DailyTimetableAnnounced\tv2\t"},
+		},
+		{
+			name:       "missing event type",
+			wantCode:   2,
+			wantStderr: "usage: adapter validate-payload",
+		},
+		{
+			name:       "unknown event type",
+			args:       []string{"-event-type", "Nope"},
+			wantCode:   2,
+			wantStderr: `unknown event type "Nope"`,
+		},
+		{
+			name:       "bad flag",
+			args:       []string{"-bogus"},
+			wantCode:   2,
+			wantStderr: "flag provided but not defined",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runValidatePayload(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("exit code = %d, want %d (stdout %q, stderr %q)", code, tc.wantCode, stdout.String(), stderr.String())
+			}
+			for _, want := range tc.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("stdout = %q, want it to contain %q", stdout.String(), want)
+				}
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tc.wantStderr)
+			}
+		})
+	}
+}
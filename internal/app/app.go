@@ -7,9 +7,18 @@ import (
 	"log"
 	"time"
 
+	"adapter-matrix/internal/commands"
 	"adapter-matrix/internal/consumer"
+	"adapter-matrix/internal/handler"
+	"adapter-matrix/internal/handler/timetable"
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/inbound"
 	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/matrixstore"
+	"adapter-matrix/internal/media"
+	"adapter-matrix/internal/polls"
 	"adapter-matrix/internal/repository"
+	"adapter-matrix/internal/templates"
 	adaptermigrations "adapter-matrix/migrations"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -26,15 +35,38 @@ type Config struct {
 	OutboxTables    []string
 	AdapterOutbox   string
 	OutboxBatchSize int
+	TemplatesDir    string
+	TemplateRefresh time.Duration
+	DefaultLocale   string
+	Timezone        *time.Location
+	TopicInterval   time.Duration
+	MediaRoot       string
+	MediaColumns    []string
+
+	ReceiptSummaryInterval   time.Duration
+	CommandPowerLevels       map[string]int
+	InboxTable               string
+	InboxRoomIDs             []string
+	InboxMsgTypes            []string
+	RejectTimetableAnomalies bool
+	TimetableCalendar        bool
+	DefaultMsgType           string
+	AllowedMsgTypes          []string
 }
 
 type App struct {
-	cfg      Config
-	logger   *log.Logger
-	db       *sql.DB
-	matrix   *matrix.Client
-	consumer *consumer.OutboxConsumer
-	syncStop func()
+	cfg         Config
+	logger      *log.Logger
+	db          *sql.DB
+	matrix      *matrix.Client
+	consumer    *consumer.OutboxConsumer
+	templates   *templates.Store
+	polls       *polls.Service
+	receipts    *inbound.Receipts
+	syncStop    func()
+	refreshStop func()
+	pollsStop   func()
+	summaryStop func()
 }
 
 func New(cfg Config, logger *log.Logger) (*App, error) {
@@ -73,23 +105,158 @@ func New(cfg Config, logger *log.Logger) (*App, error) {
 		return nil, err
 	}
 
+	matrixStoreRepo, err := repository.NewMatrixStoreRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	matrixStore, err := matrixstore.New(matrixStoreRepo)
+	if err != nil {
+		return nil, err
+	}
+	matrixClient.UseStore(matrixStore, matrixStore)
+
+	templateRepo, err := repository.NewTemplateRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	templateStore, err := templates.NewStore(
+		&templateSource{dir: cfg.TemplatesDir, repo: templateRepo},
+		func(set *templates.Set) error { return set.Validate(timetable.SampleViews()) },
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := templateStore.Load(context.Background()); err != nil {
+		return nil, err
+	}
+
+	roomSettings, err := repository.NewRoomSettingsRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	locales, err := i18n.NewResolver(i18n.Default(), func(ctx context.Context, roomID string) (i18n.RoomPreferences, error) {
+		settings, err := roomSettings.Get(ctx, roomID)
+		return i18n.RoomPreferences{Locale: settings.Locale, Timezone: settings.Timezone}, err
+	}, cfg.DefaultLocale, cfg.Timezone, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := repository.NewDirectoryRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	users, err := repository.NewUserDirectoryRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := repository.NewRoomTopicRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	pollRepo, err := repository.NewPollRepository(db, cfg.AdapterOutbox)
+	if err != nil {
+		return nil, err
+	}
+	pollService, err := polls.NewService(pollRepo, users, matrixClient, locales, logger)
+	if err != nil {
+		return nil, err
+	}
+	matrixClient.OnPollResponse(pollService.HandleResponse)
+	matrixClient.OnPollEnd(pollService.HandleEnd)
+
+	acks, err := inbound.NewAcknowledgements(repo, users, logger)
+	if err != nil {
+		return nil, err
+	}
+	matrixClient.OnReaction(acks.HandleReaction)
+
+	receiptRepo, err := repository.NewReceiptRepository(db, cfg.AdapterOutbox)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := inbound.NewReceipts(receiptRepo, users, matrixClient, logger)
+	if err != nil {
+		return nil, err
+	}
+	matrixClient.OnSentEvent(receipts.HandleSentEvent)
+	matrixClient.OnReadReceipt(receipts.HandleReadReceipt)
+
+	commandRouter, err := commands.NewRouter(matrixClient, locales, cfg.CommandPowerLevels, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := commands.RegisterBuiltins(commandRouter, repo); err != nil {
+		return nil, err
+	}
+	matrixClient.OnMessage(commandRouter.HandleMessage)
+
+	if len(cfg.InboxRoomIDs) > 0 {
+		inboxRepo, err := repository.NewInboxRepository(db, cfg.InboxTable)
+		if err != nil {
+			return nil, err
+		}
+		inbox, err := inbound.NewInbox(inboxRepo, users, cfg.InboxRoomIDs, cfg.InboxMsgTypes, commandRouter.IsCommand, logger)
+		if err != nil {
+			return nil, err
+		}
+		matrixClient.OnMessage(inbox.HandleMessage)
+	}
+
+	mediaRepo, err := repository.NewMediaRepository(db, cfg.MediaColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	handlers := handler.NewRegistry()
+	if err := handlers.Register(handler.NewMessageHandler(media.NewLoader(cfg.MediaRoot, mediaRepo))); err != nil {
+		return nil, err
+	}
+	if err := handlers.Register(handler.NewRetractionHandler()); err != nil {
+		return nil, err
+	}
+	if err := handlers.Register(handler.NewPollHandler(locales)); err != nil {
+		return nil, err
+	}
+	if err := timetable.Register(handlers, timetable.Options{
+		Templates:          templateStore,
+		Locales:            locales,
+		Directory:          directory,
+		RejectAnomalies:    cfg.RejectTimetableAnomalies,
+		CalendarAttachment: cfg.TimetableCalendar,
+		Logger:             logger,
+	}); err != nil {
+		return nil, err
+	}
+
 	consumer := consumer.NewOutboxConsumer(
 		db,
 		repo,
 		matrixClient,
+		handlers,
+		roomSettings,
+		users,
+		topics,
+		pollRepo,
 		cfg.OutboxTables,
 		cfg.PollInterval,
 		cfg.MaxRetries,
 		cfg.OutboxBatchSize,
+		consumer.MsgTypePolicy{Default: cfg.DefaultMsgType, Allowed: cfg.AllowedMsgTypes},
+		cfg.TopicInterval,
 		logger,
 	)
 
 	return &App{
-		cfg:      cfg,
-		logger:   logger,
-		db:       db,
-		matrix:   matrixClient,
-		consumer: consumer,
+		cfg:       cfg,
+		logger:    logger,
+		db:        db,
+		matrix:    matrixClient,
+		consumer:  consumer,
+		templates: templateStore,
+		polls:     pollService,
+		receipts:  receipts,
 	}, nil
 }
 
@@ -106,6 +273,22 @@ func (a *App) Start(ctx context.Context) error {
 		}
 	}()
 
+	if a.cfg.TemplateRefresh > 0 {
+		refreshCtx, cancel := context.WithCancel(ctx)
+		a.refreshStop = cancel
+		go a.templates.Run(refreshCtx, a.cfg.TemplateRefresh)
+	}
+
+	pollsCtx, cancelPolls := context.WithCancel(ctx)
+	a.pollsStop = cancelPolls
+	go a.polls.Run(pollsCtx, a.cfg.PollInterval)
+
+	if a.cfg.ReceiptSummaryInterval > 0 {
+		summaryCtx, cancel := context.WithCancel(ctx)
+		a.summaryStop = cancel
+		go a.receipts.Run(summaryCtx, a.cfg.ReceiptSummaryInterval)
+	}
+
 	return nil
 }
 
@@ -113,6 +296,15 @@ func (a *App) Stop(ctx context.Context) error {
 	if a.syncStop != nil {
 		a.syncStop()
 	}
+	if a.refreshStop != nil {
+		a.refreshStop()
+	}
+	if a.pollsStop != nil {
+		a.pollsStop()
+	}
+	if a.summaryStop != nil {
+		a.summaryStop()
+	}
 
 	if err := a.consumer.Stop(ctx); err != nil {
 		a.logger.Printf("consumer stop error: %v", err)
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"adapter-matrix/internal/repository"
+	"adapter-matrix/internal/templates"
+)
+
+// templateSource layers file templates from dir (if set) under rows from
+// adapter_templates, so DB entries win for the same event type, room and
+// locale.
+type templateSource struct {
+	dir  string
+	repo *repository.TemplateRepository
+}
+
+func (s *templateSource) Load(ctx context.Context) ([]templates.Record, error) {
+	var records []templates.Record
+	if s.dir != "" {
+		fileRecords, err := templates.LoadDir(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, fileRecords...)
+	}
+
+	dbRecords, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range dbRecords {
+		records = append(records, templates.Record{
+			EventType: rec.EventType,
+			RoomID:    rec.RoomID,
+			Locale:    rec.Locale,
+			Variant:   rec.Variant,
+			Body:      rec.Body,
+			Origin:    "adapter_templates:" + rec.EventType + "/" + rec.RoomID + "/" + rec.Locale + "/" + rec.Variant,
+		})
+	}
+	return records, nil
+}
+
+func (s *templateSource) Fingerprint(ctx context.Context) (string, error) {
+	h := sha256.New()
+	if s.dir != "" {
+		fileRecords, err := templates.LoadDir(s.dir)
+		if err != nil {
+			return "", err
+		}
+		for _, rec := range fileRecords {
+			h.Write([]byte(rec.Origin))
+			h.Write([]byte{0})
+			h.Write([]byte(rec.Body))
+			h.Write([]byte{0})
+		}
+	}
+	dbFingerprint, err := s.repo.Fingerprint(ctx)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(dbFingerprint))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
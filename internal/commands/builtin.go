@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/handler/timetable"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// statusPowerLevel is the default level for !status, which shows delivery
+// errors and so is limited to moderators.
+const statusPowerLevel = 50
+
+// RegisterBuiltins adds !timetable and !status to r.
+func RegisterBuiltins(r *Router, state *repository.AdapterStateRepository) error {
+	for _, cmd := range []Command{
+		{Name: "timetable", HelpKey: "command.help.timetable", Run: timetableCommand(state)},
+		{Name: "status", HelpKey: "command.help.status", PowerLevel: statusPowerLevel, Run: statusCommand(state)},
+	} {
+		if err := r.Register(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timetableCommand links the day's timetable announcements in the room.
+// The day is today, tomorrow or a YYYY-MM-DD date in the room's timezone.
+func timetableCommand(state *repository.AdapterStateRepository) func(context.Context, Request) (string, error) {
+	return func(ctx context.Context, req Request) (string, error) {
+		now := time.Now().In(req.Loc.Location())
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if len(req.Args) > 0 {
+			switch arg := strings.ToLower(req.Args[0]); arg {
+			case "today":
+			case "tomorrow":
+				day = day.AddDate(0, 0, 1)
+			default:
+				parsed, err := time.ParseInLocation("2006-01-02", arg, now.Location())
+				if err != nil {
+					return req.Loc.T("command.timetable.usage"), nil
+				}
+				day = parsed
+			}
+		}
+
+		posts, err := state.AnchoredEvents(ctx, req.Message.RoomID, timetable.AnnouncementAnchorPattern(day))
+		if err != nil {
+			return "", err
+		}
+		dateText := req.Loc.FormatDate(day)
+		if len(posts) == 0 {
+			return req.Loc.T("command.timetable.none", dateText), nil
+		}
+		lines := make([]string, 0, len(posts))
+		for _, post := range posts {
+			lines = append(lines, req.Loc.T("command.timetable.found", dateText, matrix.Permalink(post.RoomID, post.MatrixEventID)))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// statusCommand reports the delivery state of an outbox event targeting or
+// delivered to the room the command was sent in.
+func statusCommand(state *repository.AdapterStateRepository) func(context.Context, Request) (string, error) {
+	return func(ctx context.Context, req Request) (string, error) {
+		if len(req.Args) != 1 {
+			return req.Loc.T("command.status.usage"), nil
+		}
+		eventID := strings.TrimSpace(req.Args[0])
+		if _, err := uuid.Parse(eventID); err != nil {
+			return req.Loc.T("command.status.usage"), nil
+		}
+
+		current, found, err := state.EventState(ctx, eventID)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return req.Loc.T("command.status.unknown", eventID), nil
+		}
+		delivered, err := state.DeliveredEvents(ctx, eventID)
+		if err != nil {
+			return "", err
+		}
+		// Events for other rooms are reported as unknown so their errors,
+		// and their existence, stay private to those rooms.
+		if !inRoom(req.Message.RoomID, current, delivered) {
+			return req.Loc.T("command.status.unknown", eventID), nil
+		}
+
+		updated := req.Loc.FormatShortDate(current.UpdatedAt) + " " + req.Loc.FormatClock(current.UpdatedAt) + " " + req.Loc.ZoneAbbrev(current.UpdatedAt)
+		lines := []string{req.Loc.T("command.status.result", eventID, current.Status, current.Attempts, updated)}
+		if len(delivered) > 0 {
+			redacted := 0
+			for _, d := range delivered {
+				if d.RedactedAt != nil {
+					redacted++
+				}
+			}
+			lines = append(lines, req.Loc.T("command.status.delivered", len(delivered), redacted))
+		}
+		if current.LastError != "" {
+			lines = append(lines, req.Loc.T("command.status.error", current.LastError))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+func inRoom(roomID string, current repository.EventState, delivered []repository.DeliveredEvent) bool {
+	if current.RoomID == roomID {
+		return true
+	}
+	for _, d := range delivered {
+		if d.RoomID == roomID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Package commands answers bot commands such as !timetable and !status sent
+// in Matrix rooms.
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/matrix"
+)
+
+// Prefix starts every command.
+const Prefix = "!"
+
+// Request is one parsed command invocation.
+type Request struct {
+	Message matrix.IncomingMessage
+	Name    string
+	Args    []string
+	Loc     *i18n.Localizer
+}
+
+// Command is a bot command. Users below PowerLevel in the room are refused.
+// HelpKey names the i18n message describing it in !help.
+type Command struct {
+	Name       string
+	HelpKey    string
+	PowerLevel int
+	Run        func(ctx context.Context, req Request) (string, error)
+}
+
+// Router dispatches room messages that start with Prefix to registered
+// commands and replies in the message's thread.
+type Router struct {
+	mu          sync.RWMutex
+	commands    map[string]Command
+	powerLevels map[string]int
+	matrix      *matrix.Client
+	locales     *i18n.Resolver
+	startedAt   time.Time
+	logger      *log.Logger
+}
+
+// NewRouter returns a router with !help registered. powerLevels overrides
+// the power level commands require, by name.
+func NewRouter(matrixClient *matrix.Client, locales *i18n.Resolver, powerLevels map[string]int, logger *log.Logger) (*Router, error) {
+	if matrixClient == nil || locales == nil {
+		return nil, errors.New("matrix client and locale resolver are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	r := &Router{
+		commands:    make(map[string]Command),
+		powerLevels: powerLevels,
+		matrix:      matrixClient,
+		locales:     locales,
+		startedAt:   time.Now(),
+		logger:      logger,
+	}
+	if err := r.Register(Command{Name: "help", HelpKey: "command.help.help", Run: r.help}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Router) Register(cmd Command) error {
+	name := strings.ToLower(strings.TrimSpace(cmd.Name))
+	if name == "" || cmd.Run == nil {
+		return errors.New("command name and run function are required")
+	}
+	if level, ok := r.powerLevels[name]; ok {
+		cmd.PowerLevel = level
+	}
+	cmd.Name = name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.commands[name]; exists {
+		return fmt.Errorf("command %s already registered", name)
+	}
+	r.commands[name] = cmd
+	return nil
+}
+
+// HandleMessage runs the command in msg, if any. Edits and messages sent
+// before the router started are ignored so nothing is answered twice.
+func (r *Router) HandleMessage(ctx context.Context, msg matrix.IncomingMessage) {
+	if msg.ReplacesID != "" || msg.At.Before(r.startedAt) {
+		return
+	}
+	// Unknown names may belong to another bot in the room, so stay silent.
+	cmd, args, ok := r.parse(msg)
+	if !ok {
+		return
+	}
+	name := cmd.Name
+	loc := r.locales.Localizer(ctx, msg.RoomID, "")
+
+	if cmd.PowerLevel > 0 {
+		level, err := r.matrix.UserPowerLevel(ctx, msg.RoomID, msg.Sender)
+		if err != nil {
+			r.logger.Printf("commands: power level of %s in %s: %v", msg.Sender, msg.RoomID, err)
+			r.reply(ctx, msg, loc.T("command.failed"))
+			return
+		}
+		if level < cmd.PowerLevel {
+			r.reply(ctx, msg, loc.T("command.denied", cmd.PowerLevel, Prefix+name))
+			return
+		}
+	}
+
+	text, err := cmd.Run(ctx, Request{Message: msg, Name: name, Args: args, Loc: loc})
+	if err != nil {
+		r.logger.Printf("commands: %s%s in %s failed: %v", Prefix, name, msg.RoomID, err)
+		text = loc.T("command.failed")
+	}
+	r.reply(ctx, msg, text)
+}
+
+// IsCommand reports whether msg invokes a registered command, whether or not
+// HandleMessage answers it.
+func (r *Router) IsCommand(msg matrix.IncomingMessage) bool {
+	_, _, ok := r.parse(msg)
+	return ok
+}
+
+func (r *Router) parse(msg matrix.IncomingMessage) (Command, []string, bool) {
+	if msg.MsgType != "m.text" {
+		return Command{}, nil, false
+	}
+	fields := strings.Fields(msg.Body)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], Prefix) || len(fields[0]) == len(Prefix) {
+		return Command{}, nil, false
+	}
+	name := strings.ToLower(strings.TrimPrefix(fields[0], Prefix))
+
+	r.mu.RLock()
+	cmd, ok := r.commands[name]
+	r.mu.RUnlock()
+	return cmd, fields[1:], ok
+}
+
+func (r *Router) help(_ context.Context, req Request) (string, error) {
+	r.mu.RLock()
+	cmds := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	r.mu.RUnlock()
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+
+	lines := []string{req.Loc.T("command.help.header")}
+	for _, cmd := range cmds {
+		lines = append(lines, req.Loc.T(cmd.HelpKey))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r *Router) reply(ctx context.Context, to matrix.IncomingMessage, text string) {
+	_, err := r.matrix.Reply(ctx, to, matrix.Message{Body: text, Format: "plain", MsgType: matrix.MsgTypeNotice})
+	if err != nil {
+		r.logger.Printf("commands: reply in %s failed: %v", to.RoomID, err)
+	}
+}
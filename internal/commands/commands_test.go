@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"adapter-matrix/internal/matrix"
+)
+
+func TestIsCommand(t *testing.T) {
+	r := &Router{commands: make(map[string]Command)}
+	run := func(context.Context, Request) (string, error) { return "", nil }
+	for _, name := range []string{"help", "Status"} {
+		if err := r.Register(Command{Name: name, Run: run}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		msg  matrix.IncomingMessage
+		want bool
+	}{
+		{name: "registered", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "!help"}, want: true},
+		{name: "arguments and case", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "  !STATUS now"}, want: true},
+		{name: "edit", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "!help", ReplacesID: "$old"}, want: true},
+		{name: "unknown name", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "!weather"}},
+		{name: "prefix alone", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "! help"}},
+		{name: "not at the start", msg: matrix.IncomingMessage{MsgType: "m.text", Body: "try !help"}},
+		{name: "notice", msg: matrix.IncomingMessage{MsgType: "m.notice", Body: "!help"}},
+		{name: "empty", msg: matrix.IncomingMessage{MsgType: "m.text"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.IsCommand(tc.msg); got != tc.want {
+				t.Errorf("IsCommand(%q) = %t, want %t", tc.msg.Body, got, tc.want)
+			}
+		})
+	}
+}
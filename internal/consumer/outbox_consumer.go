@@ -3,7 +3,6 @@ package consumer
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -11,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"adapter-matrix/internal/handler"
 	"adapter-matrix/internal/matrix"
 	"adapter-matrix/internal/repository"
 )
@@ -19,10 +19,17 @@ type OutboxConsumer struct {
 	db           *sql.DB
 	repo         *repository.AdapterStateRepository
 	matrix       *matrix.Client
+	handlers     *handler.Registry
+	roomSettings *repository.RoomSettingsRepository
+	users        *repository.UserDirectoryRepository
+	topics       *repository.RoomTopicRepository
+	polls        *repository.PollRepository
 	outboxTables []string
 	pollInterval time.Duration
 	maxRetries   int
 	batchSize    int
+	msgTypes     MsgTypePolicy
+	topicEvery   time.Duration
 	logger       *log.Logger
 
 	stopOnce sync.Once
@@ -30,56 +37,57 @@ type OutboxConsumer struct {
 	wg       sync.WaitGroup
 }
 
-type MessagePayload struct {
-	RoomID string `json:"room_id"`
-	Body   string `json:"body"`
-	Format string `json:"format"`
+// MsgTypePolicy is the adapter-wide msgtype default and allow-list. An
+// empty Allowed list permits every msgtype the Matrix client supports.
+type MsgTypePolicy struct {
+	Default string
+	Allowed []string
 }
 
-type timetableSlotPayload struct {
-	SlotIndex  int    `json:"slot_index"`
-	CourseCode string `json:"course_code"`
-	StartTime  string `json:"start_time"`
-	EndTime    string `json:"end_time"`
-	Venue      string `json:"venue"`
-	Status     string `json:"status"`
-}
-
-type timetableAnnouncedPayload struct {
-	ClassID      string                 `json:"class_id"`
-	Date         string                 `json:"date"`
-	MatrixRoomID string                 `json:"matrix_room_id"`
-	Template     string                 `json:"template"`
-	Slots        []timetableSlotPayload `json:"slots"`
-}
-
-type timetableUpdatedPayload struct {
-	ClassID        string                 `json:"class_id"`
-	Date           string                 `json:"date"`
-	MatrixRoomID   string                 `json:"matrix_room_id"`
-	UpdateTemplate string                 `json:"update_template"`
-	Slots          []timetableSlotPayload `json:"slots"`
-	UpdatedBy      string                 `json:"updated_by"`
+func (p MsgTypePolicy) allows(msgType string) bool {
+	if len(p.Allowed) == 0 {
+		return true
+	}
+	for _, allowed := range p.Allowed {
+		if normalized, ok := matrix.NormalizeMsgType(allowed); ok && normalized == msgType {
+			return true
+		}
+	}
+	return false
 }
 
 func NewOutboxConsumer(
 	db *sql.DB,
 	repo *repository.AdapterStateRepository,
 	matrixClient *matrix.Client,
+	handlers *handler.Registry,
+	roomSettings *repository.RoomSettingsRepository,
+	users *repository.UserDirectoryRepository,
+	topics *repository.RoomTopicRepository,
+	polls *repository.PollRepository,
 	outboxTables []string,
 	pollInterval time.Duration,
 	maxRetries int,
 	batchSize int,
+	msgTypes MsgTypePolicy,
+	topicEvery time.Duration,
 	logger *log.Logger,
 ) *OutboxConsumer {
 	return &OutboxConsumer{
 		db:           db,
 		repo:         repo,
 		matrix:       matrixClient,
+		handlers:     handlers,
+		roomSettings: roomSettings,
+		users:        users,
+		topics:       topics,
+		polls:        polls,
 		outboxTables: outboxTables,
 		pollInterval: pollInterval,
 		maxRetries:   maxRetries,
 		batchSize:    batchSize,
+		msgTypes:     msgTypes,
+		topicEvery:   topicEvery,
 		logger:       logger,
 		stopCh:       make(chan struct{}),
 	}
@@ -131,7 +139,7 @@ func (c *OutboxConsumer) pollOnce(ctx context.Context) error {
 			return err
 		}
 	}
-	return nil
+	return c.flushTopics(ctx)
 }
 
 func (c *OutboxConsumer) pollTable(ctx context.Context, table string) error {
@@ -157,114 +165,506 @@ func (c *OutboxConsumer) pollTable(ctx context.Context, table string) error {
 	return rows.Err()
 }
 
+// processEvent claims eventID and only then renders and sends it, so rows
+// already sent or failed are skipped without rendering the payload or
+// loading its media.
 func (c *OutboxConsumer) processEvent(ctx context.Context, table, eventID, eventType string, payloadBytes []byte) error {
-	payload, err := decodeEventPayload(eventType, payloadBytes)
+	attempts, claimed, err := c.repo.ClaimEvent(ctx, eventID)
 	if err != nil {
-		return c.handleFailure(ctx, eventID, fmt.Errorf("payload decode: %w", err))
+		return err
 	}
-	payload.Format = strings.ToLower(strings.TrimSpace(payload.Format))
-	if payload.RoomID == "" || payload.Body == "" || payload.Format == "" {
-		return c.handleFailure(ctx, eventID, errors.New("payload missing required fields"))
+	if !claimed {
+		return nil
 	}
-	if payload.Format != "plain" && payload.Format != "markdown" && payload.Format != "html" {
-		return c.handleFailure(ctx, eventID, errors.New("unsupported payload format"))
+
+	send, err := c.prepare(ctx, eventID, eventType, payloadBytes)
+	if err == nil {
+		err = send()
 	}
+	if err != nil {
+		return c.handleFailure(ctx, eventID, attempts, err)
+	}
+	return c.repo.MarkSent(ctx, eventID)
+}
 
-	attempts, claimed, err := c.repo.ClaimEvent(ctx, eventID)
+// prepare renders the payload and returns the function that sends it.
+func (c *OutboxConsumer) prepare(ctx context.Context, eventID, eventType string, payloadBytes []byte) (func() error, error) {
+	msg, err := c.handlers.Handle(ctx, eventType, payloadBytes)
+	if err != nil {
+		return nil, fmt.Errorf("payload decode: %w", err)
+	}
+	if msg.RoomID != "" {
+		if err := c.repo.SetEventRoom(ctx, eventID, msg.RoomID); err != nil {
+			return nil, err
+		}
+	}
+	if msg.Redaction != nil {
+		return func() error { return redactDelivered(ctx, c.repo, c.matrix, eventID, *msg.Redaction) }, nil
+	}
+	if msg.Poll != nil {
+		if msg.RoomID == "" {
+			return nil, errors.New("payload missing required fields")
+		}
+		return func() error { return c.startPoll(ctx, eventID, msg) }, nil
+	}
+	msg.Format = strings.ToLower(strings.TrimSpace(msg.Format))
+	if msg.RoomID == "" || msg.Body == "" || msg.Format == "" {
+		return nil, errors.New("payload missing required fields")
+	}
+	if msg.Format != "plain" && msg.Format != "markdown" && msg.Format != "html" {
+		return nil, errors.New("unsupported payload format")
+	}
+	msgType, err := c.resolveMsgType(ctx, msg)
 	if err != nil {
+		return nil, err
+	}
+	msg.MsgType = msgType
+	// Reject oversized files before the text goes out, so a failed event
+	// leaves nothing behind in the room.
+	for _, attachment := range msg.Attachments {
+		if err := c.matrix.CheckUploadSize(ctx, matrixAttachment(attachment)); err != nil {
+			return nil, err
+		}
+	}
+	return func() error { return c.deliver(ctx, eventID, msg) }, nil
+}
+
+// startPoll tracks msg.Poll and sends it. The poll is stored before it is
+// sent so responses that arrive before its Matrix event ID is recorded are
+// kept. A poll already sent by an earlier attempt is not resent.
+func (c *OutboxConsumer) startPoll(ctx context.Context, eventID string, msg handler.Message) error {
+	tracked := repository.Poll{
+		EventID:       eventID,
+		PollID:        msg.Poll.PollID,
+		RoomID:        msg.RoomID,
+		Question:      msg.Poll.Question,
+		MaxSelections: msg.Poll.MaxSelections,
+		Context:       msg.Poll.Context,
+		ClosesAt:      msg.Poll.ClosesAt,
+	}
+	for _, answer := range msg.Poll.Answers {
+		tracked.Answers = append(tracked.Answers, repository.PollAnswer{ID: answer.ID, Text: answer.Text})
+	}
+	if err := c.polls.Create(ctx, tracked); err != nil {
 		return err
 	}
-	if !claimed {
-		return nil
+
+	delivered, err := c.repo.DeliveredEvents(ctx, eventID)
+	if err != nil {
+		return err
 	}
 
-	if err := c.matrix.SendMessage(ctx, payload.RoomID, payload.Body, payload.Format); err != nil {
-		if attempts >= c.maxRetries {
-			return c.handlePermanentFailure(ctx, eventID, err)
+	var pollEventID string
+	if len(delivered) > 0 {
+		pollEventID = delivered[0].MatrixEventID
+	} else {
+		poll := matrix.Poll{
+			Question:      msg.Poll.Question,
+			Disclosed:     msg.Poll.Disclosed,
+			MaxSelections: msg.Poll.MaxSelections,
+			Fallback:      msg.Body,
+		}
+		for _, answer := range msg.Poll.Answers {
+			poll.Answers = append(poll.Answers, matrix.PollAnswer{ID: answer.ID, Text: answer.Text})
+		}
+		pollEventID, err = c.matrix.SendPoll(ctx, msg.RoomID, poll)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.RecordDelivery(ctx, eventID, 1, 1, msg.RoomID, pollEventID, msg.Anchor); err != nil {
+			// A retry would post a second poll and orphan this one.
+			return permanentError{fmt.Errorf("poll %s sent but not recorded: %w", pollEventID, err)}
 		}
-		return c.repo.MarkRetry(ctx, eventID, err.Error())
 	}
+	return c.polls.SetPollEvent(ctx, eventID, pollEventID)
+}
 
-	return c.repo.MarkSent(ctx, eventID)
+type redactionStore interface {
+	DeliveredEvents(ctx context.Context, eventID string) ([]repository.DeliveredEvent, error)
+	MarkRedacted(ctx context.Context, matrixEventID, retractionEventID, redactionEventID, reason string) error
+}
+
+type redactor interface {
+	Redact(ctx context.Context, roomID, eventID, reason string) (string, error)
+}
+
+// redactDelivered redacts every Matrix event delivered for the retracted
+// outbox event. Events redacted by an earlier attempt are skipped. Nothing
+// delivered yet is an error, so the retraction is retried until the original
+// goes out or the retries run out.
+func redactDelivered(ctx context.Context, repo redactionStore, matrixClient redactor, eventID string, redaction handler.Redaction) error {
+	delivered, err := repo.DeliveredEvents(ctx, redaction.OriginalEventID)
+	if err != nil {
+		return err
+	}
+	if len(delivered) == 0 {
+		return fmt.Errorf("no delivered events for %s", redaction.OriginalEventID)
+	}
+	for _, d := range delivered {
+		if d.RedactedAt != nil {
+			continue
+		}
+		redactionEventID, err := matrixClient.Redact(ctx, d.RoomID, d.MatrixEventID, redaction.Reason)
+		if err != nil {
+			return err
+		}
+		if err := repo.MarkRedacted(ctx, d.MatrixEventID, eventID, redactionEventID, redaction.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver sends msg, split into parts if it exceeds the event size limit,
+// followed by its attachments, and records every resulting event ID.
+// Attachments are numbered as parts after the text. Parts already recorded by
+// an earlier attempt are skipped so a retry does not duplicate them; if the
+// part count changed since, the earlier parts are redacted and all are sent
+// again.
+func (c *OutboxConsumer) deliver(ctx context.Context, eventID string, msg handler.Message) error {
+	mentions, err := c.resolveMentions(ctx, msg.Mentions)
+	if err != nil {
+		return err
+	}
+	threadRootID, err := resolveThread(ctx, c.roomSettings, c.repo, msg, c.logger)
+	if err != nil {
+		return err
+	}
+	out := c.matrix.ApplyMentions(ctx, msg.RoomID, matrix.Message{
+		Body:          msg.Body,
+		FormattedBody: msg.FormattedBody,
+		Format:        msg.Format,
+		MsgType:       msg.MsgType,
+		Mentions:      mentions,
+		ThreadRootID:  threadRootID,
+	})
+	parts, err := matrix.SplitMessage(out, matrix.MaxContentBytes)
+	if err != nil {
+		return err
+	}
+
+	delivered, err := c.repo.DeliveredEvents(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	total, sent, stale := planParts(len(parts), len(msg.Attachments), delivered)
+	if err := c.dropStaleParts(ctx, eventID, total, stale); err != nil {
+		return err
+	}
+
+	for i, part := range parts {
+		if _, ok := sent[i+1]; ok {
+			continue
+		}
+		matrixEventID, err := c.matrix.SendMessage(ctx, msg.RoomID, part)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.RecordDelivery(ctx, eventID, i+1, total, msg.RoomID, matrixEventID, msg.Anchor); err != nil {
+			return err
+		}
+		sent[i+1] = matrixEventID
+	}
+	for i, attachment := range msg.Attachments {
+		part := len(parts) + i + 1
+		if _, ok := sent[part]; ok {
+			continue
+		}
+		matrixEventID, err := c.matrix.SendAttachment(ctx, msg.RoomID, matrixAttachment(attachment), threadRootID)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.RecordDelivery(ctx, eventID, part, total, msg.RoomID, matrixEventID, msg.Anchor); err != nil {
+			return err
+		}
+		sent[part] = matrixEventID
+	}
+
+	if msg.Pin {
+		pinLatest(ctx, c.repo, c.matrix, msg.RoomID, sent[1], c.logger)
+	}
+	if msg.Topic != "" {
+		c.syncTopic(ctx, msg.RoomID, msg.Topic)
+	}
+	return nil
 }
 
-func decodeEventPayload(eventType string, payloadBytes []byte) (MessagePayload, error) {
-	var messagePayload MessagePayload
-	if err := json.Unmarshal(payloadBytes, &messagePayload); err == nil {
-		if strings.TrimSpace(messagePayload.RoomID) != "" || strings.TrimSpace(messagePayload.Body) != "" {
-			return messagePayload, nil
+// planParts numbers the text parts 1..textParts and the attachments after
+// them, and sorts the deliveries of earlier attempts into the parts already
+// sent under this numbering and stale parts sent under a different one.
+func planParts(textParts, attachments int, delivered []repository.DeliveredEvent) (int, map[int]string, []repository.DeliveredEvent) {
+	total := textParts + attachments
+	sent := make(map[int]string, len(delivered))
+	var stale []repository.DeliveredEvent
+	for _, d := range delivered {
+		if d.PartCount == total {
+			sent[d.Part] = d.MatrixEventID
+		} else {
+			stale = append(stale, d)
 		}
 	}
+	return total, sent, stale
+}
+
+func matrixAttachment(a handler.Attachment) matrix.Attachment {
+	return matrix.Attachment{FileName: a.FileName, MimeType: a.MimeType, Data: a.Data}
+}
 
-	switch strings.TrimSpace(eventType) {
-	case "DailyTimetableAnnounced":
-		var payload timetableAnnouncedPayload
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			return MessagePayload{}, err
+// dropStaleParts redacts parts an earlier attempt sent under a different
+// part count and forgets them, so redactions, pins and receipts only see
+// the parts of the current split.
+func (c *OutboxConsumer) dropStaleParts(ctx context.Context, eventID string, total int, stale []repository.DeliveredEvent) error {
+	if len(stale) == 0 {
+		return nil
+	}
+	for _, d := range stale {
+		if d.RedactedAt != nil {
+			continue
 		}
-		if strings.TrimSpace(payload.MatrixRoomID) == "" {
-			return MessagePayload{}, errors.New("daily announcement missing matrix_room_id")
+		if _, err := c.matrix.Redact(ctx, d.RoomID, d.MatrixEventID, "superseded by a resend"); err != nil {
+			return fmt.Errorf("redact stale part %d of %s: %w", d.Part, eventID, err)
+		}
+	}
+	return c.repo.DeleteStaleDeliveries(ctx, eventID, total)
+}
+
+type pinStore interface {
+	PinnedEvent(ctx context.Context, roomID string) (string, bool, error)
+	SetPinnedEvent(ctx context.Context, roomID, matrixEventID string) error
+}
+
+type pinner interface {
+	PinEvent(ctx context.Context, roomID, eventID, unpinEventID string) error
+}
+
+// pinLatest pins matrixEventID in roomID in place of the adapter's previous
+// pin. The message is already delivered, so failures are logged rather than
+// retried.
+func pinLatest(ctx context.Context, repo pinStore, matrixClient pinner, roomID, matrixEventID string, logger *log.Logger) {
+	previous, _, err := repo.PinnedEvent(ctx, roomID)
+	if err != nil {
+		logger.Printf("pin: load previous pin for %s: %v", roomID, err)
+		return
+	}
+	if previous == matrixEventID {
+		return
+	}
+	if err := matrixClient.PinEvent(ctx, roomID, matrixEventID, previous); err != nil {
+		if errors.Is(err, matrix.ErrInsufficientPowerLevel) {
+			logger.Printf("pin: skipping room %s, bot lacks the m.room.pinned_events power level", roomID)
+		} else {
+			logger.Printf("pin: update pinned events in %s: %v", roomID, err)
 		}
-		return MessagePayload{
-			RoomID: payload.MatrixRoomID,
-			Body:   renderTimetableMessage(payload.Template, payload.Date, payload.Slots),
-			Format: "markdown",
-		}, nil
-	case "TimetableUpdated":
-		var payload timetableUpdatedPayload
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			return MessagePayload{}, err
+		return
+	}
+	if err := repo.SetPinnedEvent(ctx, roomID, matrixEventID); err != nil {
+		logger.Printf("pin: record pin for %s: %v", roomID, err)
+	}
+}
+
+// syncTopic sets roomID's topic when the room has topic sync enabled. A topic
+// arriving within topicEvery of the last change is kept pending and set by
+// flushTopics, so a burst of updates ends with the latest topic without a
+// state event for each. Failures are logged, not retried.
+func (c *OutboxConsumer) syncTopic(ctx context.Context, roomID, topic string) {
+	settings, err := c.roomSettings.Get(ctx, roomID)
+	if err != nil {
+		c.logger.Printf("topic: load settings for %s: %v", roomID, err)
+		return
+	}
+	if !settings.TopicSync {
+		return
+	}
+	state, err := c.topics.Get(ctx, roomID)
+	if err != nil {
+		c.logger.Printf("topic: load state for %s: %v", roomID, err)
+		return
+	}
+	switch planTopic(state, topic, time.Now(), c.topicEvery) {
+	case topicClearPending:
+		if err := c.topics.SetPending(ctx, roomID, ""); err != nil {
+			c.logger.Printf("topic: clear pending topic for %s: %v", roomID, err)
 		}
-		if strings.TrimSpace(payload.MatrixRoomID) == "" {
-			return MessagePayload{}, errors.New("timetable update missing matrix_room_id")
+	case topicDefer:
+		if err := c.topics.SetPending(ctx, roomID, topic); err != nil {
+			c.logger.Printf("topic: store pending topic for %s: %v", roomID, err)
 		}
-		return MessagePayload{
-			RoomID: payload.MatrixRoomID,
-			Body:   renderTimetableMessage(payload.UpdateTemplate, payload.Date, payload.Slots),
-			Format: "markdown",
-		}, nil
-	default:
-		return MessagePayload{}, errors.New("unsupported event payload")
+	case topicSet:
+		c.setTopic(ctx, roomID, topic)
 	}
 }
 
-func renderTimetableMessage(templateText, date string, slots []timetableSlotPayload) string {
-	title := strings.TrimSpace(templateText)
-	if title == "" {
-		title = "Timetable update"
+type topicStep int
+
+const (
+	topicUnchanged topicStep = iota
+	topicClearPending
+	topicDefer
+	topicSet
+)
+
+// planTopic decides what syncTopic does with topic given the room's topic
+// state. A topic equal to the current one cancels any pending change, since
+// the room already shows the latest summary.
+func planTopic(state repository.RoomTopic, topic string, now time.Time, every time.Duration) topicStep {
+	if topic == state.Topic {
+		if state.PendingTopic != "" {
+			return topicClearPending
+		}
+		return topicUnchanged
 	}
+	if !state.SetAt.IsZero() && now.Sub(state.SetAt) < every {
+		return topicDefer
+	}
+	return topicSet
+}
 
-	lines := make([]string, 0, len(slots)+2)
-	lines = append(lines, title)
-	if strings.TrimSpace(date) != "" {
-		lines = append(lines, "Date: "+date)
+// flushTopics sets pending topics whose rate limit has passed.
+func (c *OutboxConsumer) flushTopics(ctx context.Context) error {
+	due, err := c.topics.DuePending(ctx, time.Now().Add(-c.topicEvery))
+	if err != nil {
+		return err
+	}
+	for _, state := range due {
+		c.setTopic(ctx, state.RoomID, state.PendingTopic)
 	}
+	return nil
+}
 
-	for _, slot := range slots {
-		line := fmt.Sprintf("%d. %s (%s-%s) @ %s [%s]", slot.SlotIndex, safeText(slot.CourseCode), safeText(slot.StartTime), safeText(slot.EndTime), safeText(slot.Venue), safeText(slot.Status))
-		lines = append(lines, line)
+func (c *OutboxConsumer) setTopic(ctx context.Context, roomID, topic string) {
+	if err := c.matrix.SetTopic(ctx, roomID, topic); err != nil {
+		if errors.Is(err, matrix.ErrInsufficientPowerLevel) {
+			c.logger.Printf("topic: skipping room %s, bot lacks the m.room.topic power level", roomID)
+			// Drop the pending topic so it is not retried every poll.
+			if err := c.topics.SetPending(ctx, roomID, ""); err != nil {
+				c.logger.Printf("topic: clear pending topic for %s: %v", roomID, err)
+			}
+		} else {
+			c.logger.Printf("topic: set topic in %s: %v", roomID, err)
+		}
+		return
 	}
+	if err := c.topics.MarkSet(ctx, roomID, topic); err != nil {
+		c.logger.Printf("topic: record topic for %s: %v", roomID, err)
+	}
+}
 
-	return strings.Join(lines, "\n")
+type roomSettingsReader interface {
+	Get(ctx context.Context, roomID string) (repository.RoomSettings, error)
 }
 
-func safeText(value string) string {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return "-"
+type anchorFinder interface {
+	AnchorEvent(ctx context.Context, roomID, anchor string) (string, bool, error)
+}
+
+// resolveThread returns the event to thread msg under, or "" to send it as a
+// standalone message. Rooms without thread updates enabled, and messages whose
+// anchor was never delivered to the room, are sent standalone.
+func resolveThread(ctx context.Context, roomSettings roomSettingsReader, anchors anchorFinder, msg handler.Message, logger *log.Logger) (string, error) {
+	if msg.ThreadAnchor == "" {
+		return "", nil
 	}
-	return trimmed
+	settings, err := roomSettings.Get(ctx, msg.RoomID)
+	if err != nil {
+		return "", err
+	}
+	if !settings.ThreadUpdates {
+		return "", nil
+	}
+	rootID, found, err := anchors.AnchorEvent(ctx, msg.RoomID, msg.ThreadAnchor)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		logger.Printf("thread: no delivered %s in room %s, sending standalone", msg.ThreadAnchor, msg.RoomID)
+		return "", nil
+	}
+	return rootID, nil
 }
 
-func (c *OutboxConsumer) handleFailure(ctx context.Context, eventID string, err error) error {
-	attempts, claimed, claimErr := c.repo.ClaimEvent(ctx, eventID)
-	if claimErr != nil {
-		return claimErr
+// resolveMentions maps CR45 user IDs to Matrix IDs. Matrix IDs pass through;
+// unmapped CR45 IDs are logged and skipped so one stale mapping does not
+// block an urgent notice.
+func (c *OutboxConsumer) resolveMentions(ctx context.Context, requested handler.Mentions) (*matrix.Mentions, error) {
+	if len(requested.UserIDs) == 0 && !requested.Room {
+		return nil, nil
 	}
-	if !claimed {
-		return nil
+
+	mentions := &matrix.Mentions{Room: requested.Room}
+	var cr45IDs []string
+	for _, userID := range requested.UserIDs {
+		trimmed := strings.TrimSpace(userID)
+		if strings.HasPrefix(trimmed, "@") {
+			mentions.UserIDs = append(mentions.UserIDs, trimmed)
+		} else if trimmed != "" {
+			cr45IDs = append(cr45IDs, trimmed)
+		}
+	}
+	if len(cr45IDs) == 0 {
+		return mentions, nil
 	}
-	if attempts >= c.maxRetries {
+
+	mapped, err := c.users.MatrixUserIDs(ctx, cr45IDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, cr45ID := range cr45IDs {
+		matrixID, ok := mapped[cr45ID]
+		if !ok {
+			c.logger.Printf("mention: no Matrix ID for CR45 user %s", cr45ID)
+			continue
+		}
+		mentions.UserIDs = append(mentions.UserIDs, matrixID)
+	}
+	return mentions, nil
+}
+
+// resolveMsgType resolves msg's msgtype with c.msgTypes, loading the room
+// setting only when the payload does not name one.
+func (c *OutboxConsumer) resolveMsgType(ctx context.Context, msg handler.Message) (string, error) {
+	var roomMsgType string
+	if strings.TrimSpace(msg.MsgType) == "" {
+		settings, err := c.roomSettings.Get(ctx, msg.RoomID)
+		if err != nil {
+			return "", err
+		}
+		roomMsgType = settings.MsgType
+	}
+	return c.msgTypes.resolve(msg.MsgType, roomMsgType)
+}
+
+// resolve picks the payload's msgtype, then the room's, then the default,
+// and checks the result against the allow-list.
+func (p MsgTypePolicy) resolve(requested, roomMsgType string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		requested = strings.TrimSpace(roomMsgType)
+	}
+	if requested == "" {
+		requested = p.Default
+	}
+
+	msgType, ok := matrix.NormalizeMsgType(requested)
+	if !ok || !p.allows(msgType) {
+		return "", fmt.Errorf("msgtype %q is not allowed", requested)
+	}
+	return msgType, nil
+}
+
+// permanentError marks a failure that retrying cannot fix or would make
+// worse.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// handleFailure retries the claimed eventID, or fails it for good once the
+// retries run out or err is permanent.
+func (c *OutboxConsumer) handleFailure(ctx context.Context, eventID string, attempts int, err error) error {
+	var permanent permanentError
+	if attempts >= c.maxRetries || errors.As(err, &permanent) || errors.Is(err, matrix.ErrMediaTooLarge) {
 		return c.handlePermanentFailure(ctx, eventID, err)
 	}
 	return c.repo.MarkRetry(ctx, eventID, err.Error())
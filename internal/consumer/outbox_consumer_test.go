@@ -0,0 +1,283 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/handler"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+func TestPlanParts(t *testing.T) {
+	delivered := func(parts ...[2]int) []repository.DeliveredEvent {
+		out := make([]repository.DeliveredEvent, 0, len(parts))
+		for _, p := range parts {
+			out = append(out, repository.DeliveredEvent{Part: p[0], PartCount: p[1], MatrixEventID: fmt.Sprintf("$%d-of-%d", p[0], p[1])})
+		}
+		return out
+	}
+
+	cases := []struct {
+		name        string
+		textParts   int
+		attachments int
+		delivered   []repository.DeliveredEvent
+		wantTotal   int
+		wantSent    []int
+		wantStale   []int
+	}{
+		{name: "first attempt", textParts: 2, attachments: 1, wantTotal: 3},
+		{name: "attachments follow the text", textParts: 2, attachments: 2, delivered: delivered([2]int{1, 4}, [2]int{2, 4}), wantTotal: 4, wantSent: []int{1, 2}},
+		{name: "retry after the first attachment", textParts: 1, attachments: 2, delivered: delivered([2]int{1, 3}, [2]int{2, 3}), wantTotal: 3, wantSent: []int{1, 2}},
+		{name: "part count changed", textParts: 3, attachments: 0, delivered: delivered([2]int{1, 2}, [2]int{2, 2}), wantTotal: 3, wantStale: []int{1, 2}},
+		{name: "attachment dropped from the payload", textParts: 1, attachments: 0, delivered: delivered([2]int{1, 2}), wantTotal: 1, wantStale: []int{1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			total, sent, stale := planParts(tc.textParts, tc.attachments, tc.delivered)
+			if total != tc.wantTotal {
+				t.Errorf("total = %d, want %d", total, tc.wantTotal)
+			}
+			var gotSent []int
+			for part, matrixEventID := range sent {
+				if want := fmt.Sprintf("$%d-of-%d", part, total); matrixEventID != want {
+					t.Errorf("part %d = %s, want %s", part, matrixEventID, want)
+				}
+				gotSent = append(gotSent, part)
+			}
+			sort.Ints(gotSent)
+			var gotStale []int
+			for _, d := range stale {
+				gotStale = append(gotStale, d.Part)
+			}
+			if fmt.Sprint(gotSent) != fmt.Sprint(tc.wantSent) || fmt.Sprint(gotStale) != fmt.Sprint(tc.wantStale) {
+				t.Errorf("sent %v, stale %v; want %v, %v", gotSent, gotStale, tc.wantSent, tc.wantStale)
+			}
+		})
+	}
+}
+
+func TestPlanTopic(t *testing.T) {
+	now := time.Date(2024, 10, 14, 9, 0, 0, 0, time.UTC)
+	every := 10 * time.Minute
+	cases := []struct {
+		name  string
+		state repository.RoomTopic
+		topic string
+		want  topicStep
+	}{
+		{name: "never set", state: repository.RoomTopic{}, topic: "Today: CS301 09:00", want: topicSet},
+		{name: "pending before the first set", state: repository.RoomTopic{PendingTopic: "Today: old"}, topic: "Today: CS301 09:00", want: topicSet},
+		{name: "same topic", state: repository.RoomTopic{Topic: "Today: CS301 09:00", SetAt: now.Add(-time.Minute)}, topic: "Today: CS301 09:00", want: topicUnchanged},
+		{name: "back to the current topic", state: repository.RoomTopic{Topic: "Today: CS301 09:00", PendingTopic: "Today: MA201 10:00", SetAt: now.Add(-time.Minute)}, topic: "Today: CS301 09:00", want: topicClearPending},
+		{name: "changed within the interval", state: repository.RoomTopic{Topic: "Today: CS301 09:00", SetAt: now.Add(-time.Minute)}, topic: "Today: MA201 10:00", want: topicDefer},
+		{name: "changed after the interval", state: repository.RoomTopic{Topic: "Today: CS301 09:00", SetAt: now.Add(-every)}, topic: "Today: MA201 10:00", want: topicSet},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := planTopic(tc.state, tc.topic, now, every); got != tc.want {
+				t.Errorf("planTopic = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMsgTypePolicyResolve(t *testing.T) {
+	cases := []struct {
+		name      string
+		policy    MsgTypePolicy
+		requested string
+		room      string
+		want      string
+		wantErr   bool
+	}{
+		{name: "payload wins", policy: MsgTypePolicy{Default: "text"}, requested: "m.notice", room: "emote", want: "notice"},
+		{name: "room setting", policy: MsgTypePolicy{Default: "text"}, requested: " ", room: "Emote", want: "emote"},
+		{name: "adapter default", policy: MsgTypePolicy{Default: "m.text"}, want: "text"},
+		{name: "allowed with prefix", policy: MsgTypePolicy{Default: "text", Allowed: []string{"m.text", "M.Notice"}}, requested: "notice", want: "notice"},
+		{name: "not allowed", policy: MsgTypePolicy{Default: "text", Allowed: []string{"text"}}, requested: "notice", wantErr: true},
+		{name: "not sendable", policy: MsgTypePolicy{Default: "text"}, requested: "m.image", wantErr: true},
+		{name: "bad room setting", policy: MsgTypePolicy{Default: "text"}, room: "shout", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.policy.resolve(tc.requested, tc.room)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resolve error = %v, want error %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("resolve = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+type fakeRoomSettings map[string]repository.RoomSettings
+
+func (f fakeRoomSettings) Get(_ context.Context, roomID string) (repository.RoomSettings, error) {
+	if roomID == "!down:example.org" {
+		return repository.RoomSettings{}, errors.New("database unavailable")
+	}
+	return f[roomID], nil
+}
+
+type fakeAnchors map[string]string
+
+func (f fakeAnchors) AnchorEvent(_ context.Context, roomID, anchor string) (string, bool, error) {
+	matrixEventID, ok := f[roomID+"/"+anchor]
+	return matrixEventID, ok, nil
+}
+
+func TestResolveThread(t *testing.T) {
+	const anchor = "DailyTimetableAnnounced:cse-3a:2024-10-14"
+	settings := fakeRoomSettings{
+		"!threads:example.org": {ThreadUpdates: true},
+		"!flat:example.org":    {},
+	}
+	anchors := fakeAnchors{
+		"!threads:example.org/" + anchor: "$announcement",
+		"!flat:example.org/" + anchor:    "$announcement-flat",
+	}
+	cases := []struct {
+		name    string
+		msg     handler.Message
+		want    string
+		wantErr bool
+	}{
+		{name: "threaded update", msg: handler.Message{RoomID: "!threads:example.org", ThreadAnchor: anchor}, want: "$announcement"},
+		{name: "no thread anchor", msg: handler.Message{RoomID: "!threads:example.org", Anchor: anchor}},
+		{name: "thread updates off", msg: handler.Message{RoomID: "!flat:example.org", ThreadAnchor: anchor}},
+		{name: "announcement never delivered", msg: handler.Message{RoomID: "!threads:example.org", ThreadAnchor: "DailyTimetableAnnounced:cse-3a:2024-10-15"}},
+		{name: "settings unavailable", msg: handler.Message{RoomID: "!down:example.org", ThreadAnchor: anchor}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveThread(context.Background(), settings, anchors, tc.msg, log.New(io.Discard, "", 0))
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("resolveThread = %q, %v; want %q, error %t", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+type fakeRedactions struct {
+	delivered map[string][]repository.DeliveredEvent
+	failOn    string
+	redacted  []string
+}
+
+func (f *fakeRedactions) DeliveredEvents(_ context.Context, eventID string) ([]repository.DeliveredEvent, error) {
+	return f.delivered[eventID], nil
+}
+
+func (f *fakeRedactions) MarkRedacted(_ context.Context, matrixEventID, retractionEventID, redactionEventID, reason string) error {
+	f.redacted = append(f.redacted, fmt.Sprintf("%s by %s as %s (%s)", matrixEventID, retractionEventID, redactionEventID, reason))
+	for eventID, delivered := range f.delivered {
+		for i := range delivered {
+			if delivered[i].MatrixEventID == matrixEventID {
+				at := time.Now()
+				f.delivered[eventID][i].RedactedAt = &at
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeRedactions) Redact(_ context.Context, roomID, eventID, _ string) (string, error) {
+	if eventID == f.failOn {
+		return "", errors.New("rate limited")
+	}
+	return "$redacts-" + eventID[1:] + "-in-" + roomID[1:2], nil
+}
+
+func TestRedactDelivered(t *testing.T) {
+	earlier := time.Date(2024, 10, 14, 8, 0, 0, 0, time.UTC)
+	store := &fakeRedactions{
+		delivered: map[string][]repository.DeliveredEvent{
+			"original": {
+				{Part: 1, PartCount: 3, RoomID: "!a:example.org", MatrixEventID: "$part1"},
+				{Part: 2, PartCount: 3, RoomID: "!a:example.org", MatrixEventID: "$part2", RedactedAt: &earlier},
+				{Part: 3, PartCount: 3, RoomID: "!a:example.org", MatrixEventID: "$file"},
+			},
+		},
+		failOn: "$file",
+	}
+	redaction := handler.Redaction{OriginalEventID: "original", Reason: "posted by mistake"}
+
+	if err := redactDelivered(context.Background(), store, store, "retraction", redaction); err == nil {
+		t.Fatal("redactDelivered succeeded although the last part failed")
+	}
+	store.failOn = ""
+	if err := redactDelivered(context.Background(), store, store, "retraction", redaction); err != nil {
+		t.Fatalf("redactDelivered retry: %v", err)
+	}
+	want := []string{
+		"$part1 by retraction as $redacts-part1-in-a (posted by mistake)",
+		"$file by retraction as $redacts-file-in-a (posted by mistake)",
+	}
+	if fmt.Sprint(store.redacted) != fmt.Sprint(want) {
+		t.Errorf("redacted %v, want %v (each part once, earlier redactions skipped)", store.redacted, want)
+	}
+
+	if err := redactDelivered(context.Background(), store, store, "retraction", handler.Redaction{OriginalEventID: "not-sent-yet"}); err == nil {
+		t.Error("redactDelivered succeeded with nothing delivered, want an error so the retraction is retried")
+	}
+}
+
+type fakePins struct {
+	pinned  map[string]string
+	denied  bool
+	updates []string
+}
+
+func (f *fakePins) PinnedEvent(_ context.Context, roomID string) (string, bool, error) {
+	matrixEventID, ok := f.pinned[roomID]
+	return matrixEventID, ok, nil
+}
+
+func (f *fakePins) SetPinnedEvent(_ context.Context, roomID, matrixEventID string) error {
+	f.pinned[roomID] = matrixEventID
+	return nil
+}
+
+func (f *fakePins) PinEvent(_ context.Context, roomID, eventID, unpinEventID string) error {
+	if f.denied {
+		return matrix.ErrInsufficientPowerLevel
+	}
+	f.updates = append(f.updates, fmt.Sprintf("pin %s unpin %q in %s", eventID, unpinEventID, roomID))
+	return nil
+}
+
+func TestPinLatest(t *testing.T) {
+	cases := []struct {
+		name       string
+		pinned     map[string]string
+		denied     bool
+		want       []string
+		wantPinned string
+	}{
+		{name: "first pin", pinned: map[string]string{}, want: []string{`pin $today unpin "" in !a:example.org`}, wantPinned: "$today"},
+		{name: "replaces the previous pin", pinned: map[string]string{"!a:example.org": "$yesterday"}, want: []string{`pin $today unpin "$yesterday" in !a:example.org`}, wantPinned: "$today"},
+		{name: "already pinned by an earlier attempt", pinned: map[string]string{"!a:example.org": "$today"}, wantPinned: "$today"},
+		{name: "no power level", pinned: map[string]string{"!a:example.org": "$yesterday"}, denied: true, wantPinned: "$yesterday"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pins := &fakePins{pinned: tc.pinned, denied: tc.denied}
+			pinLatest(context.Background(), pins, pins, "!a:example.org", "$today", log.New(io.Discard, "", 0))
+			if fmt.Sprint(pins.updates) != fmt.Sprint(tc.want) {
+				t.Errorf("updates = %v, want %v", pins.updates, tc.want)
+			}
+			if got := pins.pinned["!a:example.org"]; got != tc.wantPinned {
+				t.Errorf("recorded pin = %q, want %q", got, tc.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package events
+
+import "time"
+
+type MessageAcknowledged struct {
+	OriginalEventID string    `json:"original_event_id"`
+	Adapter         string    `json:"adapter"`
+	MatrixRoomID    string    `json:"matrix_room_id"`
+	MatrixEventID   string    `json:"matrix_event_id"`
+	MatrixUserID    string    `json:"matrix_user_id"`
+	CR45UserID      string    `json:"cr45_user_id,omitempty"`
+	ReactionKey     string    `json:"reaction_key"`
+	ReactedAt       time.Time `json:"reacted_at"`
+}
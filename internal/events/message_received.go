@@ -0,0 +1,22 @@
+package events
+
+import "time"
+
+type MessageReceived struct {
+	Adapter       string           `json:"adapter"`
+	MatrixRoomID  string           `json:"matrix_room_id"`
+	MatrixEventID string           `json:"matrix_event_id"`
+	MatrixUserID  string           `json:"matrix_user_id"`
+	CR45UserID    string           `json:"cr45_user_id,omitempty"`
+	MsgType       string           `json:"msgtype"`
+	Body          string           `json:"body"`
+	Relation      *MessageRelation `json:"relation,omitempty"`
+	SentAt        time.Time        `json:"sent_at"`
+}
+
+// MessageRelation is how a received message relates to earlier events.
+type MessageRelation struct {
+	ThreadRootEventID string `json:"thread_root_event_id,omitempty"`
+	InReplyToEventID  string `json:"in_reply_to_event_id,omitempty"`
+	ReplacesEventID   string `json:"replaces_event_id,omitempty"`
+}
@@ -0,0 +1,33 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PollResultsCollected struct {
+	PollID          string              `json:"poll_id"`
+	OriginalEventID string              `json:"original_event_id"`
+	Adapter         string              `json:"adapter"`
+	MatrixRoomID    string              `json:"matrix_room_id"`
+	MatrixEventID   string              `json:"matrix_event_id"`
+	Question        string              `json:"question"`
+	Answers         []PollAnswerResult  `json:"answers"`
+	Responses       []PollVoterResponse `json:"responses"`
+	TotalVoters     int                 `json:"total_voters"`
+	ClosedAt        time.Time           `json:"closed_at"`
+	ClosedBy        string              `json:"closed_by"`
+	Context         json.RawMessage     `json:"context,omitempty"`
+}
+
+type PollAnswerResult struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+type PollVoterResponse struct {
+	MatrixUserID string   `json:"matrix_user_id"`
+	CR45UserID   string   `json:"cr45_user_id,omitempty"`
+	Answers      []string `json:"answers"`
+}
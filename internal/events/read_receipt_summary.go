@@ -0,0 +1,18 @@
+package events
+
+import "time"
+
+type ReadReceiptSummary struct {
+	OriginalEventID string       `json:"original_event_id"`
+	Adapter         string       `json:"adapter"`
+	MatrixRoomID    string       `json:"matrix_room_id"`
+	MatrixEventID   string       `json:"matrix_event_id"`
+	ReadCount       int          `json:"read_count"`
+	Readers         []ReadReport `json:"readers"`
+}
+
+type ReadReport struct {
+	MatrixUserID string    `json:"matrix_user_id"`
+	CR45UserID   string    `json:"cr45_user_id,omitempty"`
+	ReadAt       time.Time `json:"read_at"`
+}
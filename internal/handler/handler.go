@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"adapter-matrix/internal/schema"
+)
+
+// Message is the rendered, routed result of handling one outbox event. An
+// empty MsgType means the room's default.
+//
+// Anchor names the delivered message so later ones can refer to it;
+// ThreadAnchor names the message to reply to in a thread when the room has
+// thread updates enabled. Pin replaces the room's previously pinned message
+// with this one, and Topic is a one-line summary for rooms that sync their
+// topic. Attachments are uploaded and sent as files after the text. A
+// non-nil Poll is sent as a poll with Body as its text fallback, and a
+// non-nil Redaction replaces sending altogether.
+type Message struct {
+	RoomID        string
+	Body          string
+	FormattedBody string
+	Format        string
+	MsgType       string
+	Mentions      Mentions
+	Anchor        string
+	ThreadAnchor  string
+	Pin           bool
+	Topic         string
+	Attachments   []Attachment
+	Poll          *Poll
+	Redaction     *Redaction
+}
+
+// Attachment is a file sent alongside a message.
+type Attachment struct {
+	FileName string
+	MimeType string
+	Data     []byte
+}
+
+// Mentions is the payload's request to notify users or the whole room.
+// UserIDs may be Matrix IDs (@user:server) or CR45 user IDs, which the
+// consumer maps to Matrix IDs.
+type Mentions struct {
+	UserIDs []string `json:"user_ids"`
+	Room    bool     `json:"room"`
+}
+
+// Handler turns the raw payload of one outbox event type into a Message.
+type Handler interface {
+	EventType() string
+	Handle(ctx context.Context, payload []byte) (Message, error)
+}
+
+// Typed adapts per-type decode/validate/render/route functions into a
+// Handler. Payloads are checked against the embedded JSON Schema for Type
+// (when one exists) before they are decoded.
+type Typed[T any] struct {
+	Type     string
+	Decode   func(payload []byte) (T, error)
+	Validate func(ctx context.Context, payload T) error
+	Render   func(ctx context.Context, payload T) (Message, error)
+	Route    func(payload T) (string, error)
+}
+
+func (t Typed[T]) EventType() string {
+	return t.Type
+}
+
+func (t Typed[T]) Handle(ctx context.Context, raw []byte) (Message, error) {
+	if t.Render == nil || t.Route == nil {
+		return Message{}, fmt.Errorf("handler %s is missing render or route", t.Type)
+	}
+	payload, err := t.decode(ctx, raw)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg, err := t.Render(ctx, payload)
+	if err != nil {
+		return Message{}, err
+	}
+	roomID, err := t.Route(payload)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.RoomID = roomID
+	return msg, nil
+}
+
+// Check runs the schema, decode and Validate steps of Handle without
+// rendering.
+func (t Typed[T]) Check(ctx context.Context, raw []byte) error {
+	_, err := t.decode(ctx, raw)
+	return err
+}
+
+func (t Typed[T]) decode(ctx context.Context, raw []byte) (T, error) {
+	var payload T
+	if schema.Default().Has(t.Type) {
+		if err := schema.Validate(t.Type, raw); err != nil {
+			return payload, fmt.Errorf("schema %s: %w", t.Type, err)
+		}
+	}
+
+	var err error
+	if t.Decode != nil {
+		payload, err = t.Decode(raw)
+	} else {
+		err = json.Unmarshal(raw, &payload)
+	}
+	if err != nil {
+		return payload, err
+	}
+
+	if t.Validate != nil {
+		if err := t.Validate(ctx, payload); err != nil {
+			return payload, err
+		}
+	}
+	return payload, nil
+}
+
+// Checker is implemented by handlers that can validate a payload without
+// rendering it.
+type Checker interface {
+	Check(ctx context.Context, payload []byte) error
+}
+
+// Registry maps outbox event types to their handlers.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]Handler)}
+}
+
+func (r *Registry) Register(h Handler) error {
+	if h == nil {
+		return errors.New("handler is required")
+	}
+	eventType := strings.TrimSpace(h.EventType())
+	if eventType == "" {
+		return errors.New("handler event type is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.handlers[eventType]; exists {
+		return fmt.Errorf("handler for %s already registered", eventType)
+	}
+	r.handlers[eventType] = h
+	return nil
+}
+
+// Lookup picks the handler for an outbox row. Payloads shaped like a generic
+// message (room_id/body at the top level) go to the Message handler regardless
+// of event type, matching how producers have always been able to send ad-hoc
+// text.
+func (r *Registry) Lookup(eventType string, payload []byte) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if looksLikeMessage(payload) {
+		if h, ok := r.handlers[MessageEventType]; ok {
+			return h, true
+		}
+	}
+	h, ok := r.handlers[strings.TrimSpace(eventType)]
+	return h, ok
+}
+
+func (r *Registry) EventTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.handlers))
+	for eventType := range r.handlers {
+		out = append(out, eventType)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Check validates payload with the handler registered for eventType, when
+// that handler is a Checker. Unlike Lookup it does not route message-shaped
+// payloads to the Message handler.
+func (r *Registry) Check(ctx context.Context, eventType string, payload []byte) error {
+	r.mu.RLock()
+	h, ok := r.handlers[strings.TrimSpace(eventType)]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler for %s", eventType)
+	}
+	if c, ok := h.(Checker); ok {
+		return c.Check(ctx, payload)
+	}
+	return nil
+}
+
+// Handle looks up and runs the handler for an outbox row.
+func (r *Registry) Handle(ctx context.Context, eventType string, payload []byte) (Message, error) {
+	h, ok := r.Lookup(eventType, payload)
+	if !ok {
+		return Message{}, errors.New("unsupported event payload")
+	}
+	return h.Handle(ctx, payload)
+}
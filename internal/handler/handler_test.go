@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"adapter-matrix/internal/media"
+)
+
+func stubHandler(eventType string) Handler {
+	return Typed[map[string]any]{
+		Type: eventType,
+		Render: func(ctx context.Context, payload map[string]any) (Message, error) {
+			return Message{Body: eventType}, nil
+		},
+		Route: func(payload map[string]any) (string, error) {
+			return "!stub:example.org", nil
+		},
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry()
+	for _, h := range []Handler{NewMessageHandler(nil), stubHandler("Known")} {
+		if err := r.Register(h); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		eventType string
+		payload   string
+		wantType  string
+		wantOK    bool
+	}{
+		{"known type", "Known", `{"class_id":"c1"}`, "Known", true},
+		{"known type with padding", " Known ", `{}`, "Known", true},
+		{"unknown type", "Unknown", `{"class_id":"c1"}`, "", false},
+		{"message shaped with room_id", "Known", `{"room_id":"!a:example.org"}`, MessageEventType, true},
+		{"message shaped with body", "Unknown", `{"body":"hi"}`, MessageEventType, true},
+		{"blank room_id and body", "Known", `{"room_id":" ","body":""}`, "Known", true},
+		{"not JSON", "Known", `not json`, "Known", true},
+		{"room_id of the wrong type", "Unknown", `{"room_id":1}`, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, ok := r.Lookup(tc.eventType, []byte(tc.payload))
+			if ok != tc.wantOK {
+				t.Fatalf("Lookup ok = %t, want %t", ok, tc.wantOK)
+			}
+			if ok && h.EventType() != tc.wantType {
+				t.Errorf("Lookup handler = %s, want %s", h.EventType(), tc.wantType)
+			}
+		})
+	}
+
+	if _, err := r.Handle(context.Background(), "Unknown", []byte(`{}`)); err == nil {
+		t.Error("Handle accepted an unknown event type")
+	}
+	if got := strings.Join(r.EventTypes(), ","); got != "Known,"+MessageEventType {
+		t.Errorf("EventTypes = %s", got)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(stubHandler("Known")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	cases := []struct {
+		name    string
+		handler Handler
+		wantErr string
+	}{
+		{"nil handler", nil, "handler is required"},
+		{"blank event type", stubHandler("  "), "event type is required"},
+		{"duplicate", stubHandler("Known"), "already registered"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.Register(tc.handler)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Register error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMessageHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		loader  *media.Loader
+		payload string
+		want    Message
+		wantErr string
+	}{
+		{
+			name:    "plain",
+			payload: `{"room_id":"!a:example.org","body":"hi","format":"plain","msgtype":"m.notice"}`,
+			want:    Message{RoomID: "!a:example.org", Body: "hi", Format: "plain", MsgType: "m.notice"},
+		},
+		{
+			name:    "mentions",
+			payload: `{"room_id":"!a:example.org","body":"hi","format":"markdown","mentions":{"user_ids":["@ana:example.org"],"room":true}}`,
+			want:    Message{RoomID: "!a:example.org", Body: "hi", Format: "markdown", Mentions: Mentions{UserIDs: []string{"@ana:example.org"}, Room: true}},
+		},
+		{
+			name:    "inline media",
+			loader:  media.NewLoader("", nil),
+			payload: `{"room_id":"!a:example.org","body":"notes","format":"plain","media":{"file_name":"notes.txt","mimetype":"text/plain","data_base64":"aGk="}}`,
+			want: Message{RoomID: "!a:example.org", Body: "notes", Format: "plain", Attachments: []Attachment{
+				{FileName: "notes.txt", MimeType: "text/plain", Data: []byte("hi")},
+			}},
+		},
+		{
+			name:    "media without a loader",
+			payload: `{"room_id":"!a:example.org","body":"notes","format":"plain","media":{"data_base64":"aGk="}}`,
+			wantErr: "media payloads are not supported",
+		},
+		{
+			name:    "missing format",
+			payload: `{"room_id":"!a:example.org","body":"hi"}`,
+			wantErr: "/format: is required",
+		},
+		{
+			name:    "bad room id",
+			payload: `{"room_id":"a","body":"hi","format":"plain"}`,
+			wantErr: "/room_id: does not match pattern",
+		},
+		{
+			name:    "empty body",
+			payload: `{"room_id":"!a:example.org","body":"","format":"html"}`,
+			wantErr: "/body: must be at least 1 characters",
+		},
+		{
+			name:    "unknown mentions field",
+			payload: `{"room_id":"!a:example.org","body":"hi","format":"plain","mentions":{"users":[]}}`,
+			wantErr: "/mentions/users: is not allowed",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewMessageHandler(tc.loader).Handle(context.Background(), []byte(tc.payload))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Handle error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+			if got.RoomID != tc.want.RoomID || got.Body != tc.want.Body || got.Format != tc.want.Format || got.MsgType != tc.want.MsgType {
+				t.Errorf("Handle = %+v, want %+v", got, tc.want)
+			}
+			if strings.Join(got.Mentions.UserIDs, ",") != strings.Join(tc.want.Mentions.UserIDs, ",") || got.Mentions.Room != tc.want.Mentions.Room {
+				t.Errorf("Mentions = %+v, want %+v", got.Mentions, tc.want.Mentions)
+			}
+			if len(got.Attachments) != len(tc.want.Attachments) {
+				t.Fatalf("Attachments = %+v, want %+v", got.Attachments, tc.want.Attachments)
+			}
+			for i, att := range got.Attachments {
+				want := tc.want.Attachments[i]
+				if att.FileName != want.FileName || att.MimeType != want.MimeType || string(att.Data) != string(want.Data) {
+					t.Errorf("Attachment %d = %+v, want %+v", i, att, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTypedHandle(t *testing.T) {
+	type payload struct {
+		Room string `json:"room"`
+		N    int    `json:"n"`
+	}
+	errInvalid := errors.New("n must be positive")
+	h := Typed[payload]{
+		Type: "Typed",
+		Validate: func(ctx context.Context, p payload) error {
+			if p.N <= 0 {
+				return errInvalid
+			}
+			return nil
+		},
+		Render: func(ctx context.Context, p payload) (Message, error) {
+			return Message{Body: strings.Repeat("x", p.N), RoomID: "ignored"}, nil
+		},
+		Route: func(p payload) (string, error) {
+			if p.Room == "" {
+				return "", errors.New("no room")
+			}
+			return p.Room, nil
+		},
+	}
+
+	cases := []struct {
+		name     string
+		handler  Handler
+		payload  string
+		wantRoom string
+		wantBody string
+		wantErr  string
+	}{
+		{"routes after render", h, `{"room":"!a:example.org","n":2}`, "!a:example.org", "xx", ""},
+		{"decode error", h, `{"n":"2"}`, "", "", "cannot unmarshal"},
+		{"validate error", h, `{"room":"!a:example.org","n":0}`, "", "", errInvalid.Error()},
+		{"route error", h, `{"n":1}`, "", "", "no room"},
+		{"custom decode", Typed[payload]{
+			Type:   "Typed",
+			Decode: func([]byte) (payload, error) { return payload{Room: "!b:example.org", N: 1}, nil },
+			Render: h.Render,
+			Route:  h.Route,
+		}, `ignored`, "!b:example.org", "x", ""},
+		{"missing render", Typed[payload]{Type: "Typed", Route: h.Route}, `{}`, "", "", "missing render or route"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.handler.Handle(context.Background(), []byte(tc.payload))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Handle error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+			if got.RoomID != tc.wantRoom || got.Body != tc.wantBody {
+				t.Errorf("Handle = %+v, want room %q body %q", got, tc.wantRoom, tc.wantBody)
+			}
+		})
+	}
+}
+
+type plainHandler struct{}
+
+func (plainHandler) EventType() string { return "Plain" }
+
+func (plainHandler) Handle(context.Context, []byte) (Message, error) {
+	return Message{}, errors.New("Handle called")
+}
+
+func TestRegistryCheck(t *testing.T) {
+	errInvalid := errors.New("n must be positive")
+	r := NewRegistry()
+	for _, h := range []Handler{
+		Typed[struct{ N int }]{
+			Type: "Typed",
+			Validate: func(_ context.Context, p struct{ N int }) error {
+				if p.N <= 0 {
+					return errInvalid
+				}
+				return nil
+			},
+			Render: func(context.Context, struct{ N int }) (Message, error) {
+				return Message{}, errors.New("Render called")
+			},
+			Route: func(struct{ N int }) (string, error) { return "", errors.New("Route called") },
+		},
+		plainHandler{},
+		NewMessageHandler(nil),
+	} {
+		if err := r.Register(h); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		eventType string
+		payload   string
+		wantErr   string
+	}{
+		{"valid payload is not rendered", "Typed", `{"N":1}`, ""},
+		{"validate error", "Typed", `{"N":0}`, errInvalid.Error()},
+		{"message-shaped payload is not rerouted", "Typed", `{"room_id":"!a:example.org","N":0}`, errInvalid.Error()},
+		{"handler without Check", "Plain", `{}`, ""},
+		{"unknown type", "Nope", `{}`, "no handler for Nope"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.Check(context.Background(), tc.eventType, []byte(tc.payload))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Check error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
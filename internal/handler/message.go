@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"adapter-matrix/internal/media"
+	"adapter-matrix/internal/schema"
+)
+
+// MessageEventType is the pseudo event type for generic message payloads.
+const MessageEventType = schema.MessageEventType
+
+// MessagePayload is the generic outbox payload for ready-to-send text,
+// optionally followed by a file.
+type MessagePayload struct {
+	RoomID   string     `json:"room_id"`
+	Body     string     `json:"body"`
+	Format   string     `json:"format"`
+	MsgType  string     `json:"msgtype"`
+	Mentions Mentions   `json:"mentions"`
+	Media    *media.Ref `json:"media"`
+}
+
+// NewMessageHandler returns the handler for generic message payloads. A nil
+// loader rejects payloads that reference media.
+func NewMessageHandler(loader *media.Loader) Handler {
+	return Typed[MessagePayload]{
+		Type: MessageEventType,
+		Render: func(ctx context.Context, payload MessagePayload) (Message, error) {
+			msg := Message{
+				Body:     payload.Body,
+				Format:   payload.Format,
+				MsgType:  payload.MsgType,
+				Mentions: payload.Mentions,
+			}
+			if payload.Media != nil {
+				if loader == nil {
+					return Message{}, errors.New("media payloads are not supported")
+				}
+				file, err := loader.Load(ctx, *payload.Media)
+				if err != nil {
+					return Message{}, err
+				}
+				msg.Attachments = append(msg.Attachments, Attachment{
+					FileName: file.FileName,
+					MimeType: file.MimeType,
+					Data:     file.Data,
+				})
+			}
+			return msg, nil
+		},
+		Route: func(payload MessagePayload) (string, error) {
+			return payload.RoomID, nil
+		},
+	}
+}
+
+func looksLikeMessage(payload []byte) bool {
+	var probe MessagePayload
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return false
+	}
+	return strings.TrimSpace(probe.RoomID) != "" || strings.TrimSpace(probe.Body) != ""
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+)
+
+// PollEventType is the outbox event type that starts a Matrix poll.
+const PollEventType = "PollRequested"
+
+// PollRequestedPayload asks for a poll in a room. Context is opaque producer
+// data echoed back in PollResultsCollected.
+type PollRequestedPayload struct {
+	PollID        string              `json:"poll_id"`
+	MatrixRoomID  string              `json:"matrix_room_id"`
+	Question      string              `json:"question"`
+	Answers       []PollAnswerPayload `json:"answers"`
+	Kind          string              `json:"kind"`
+	MaxSelections int                 `json:"max_selections"`
+	ClosesAt      string              `json:"closes_at"`
+	Context       json.RawMessage     `json:"context"`
+}
+
+type PollAnswerPayload struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Poll asks the consumer to start a poll instead of sending a text message;
+// the Message body is the text fallback.
+type Poll struct {
+	PollID        string
+	Question      string
+	Answers       []PollAnswer
+	Disclosed     bool
+	MaxSelections int
+	ClosesAt      time.Time
+	Context       json.RawMessage
+}
+
+type PollAnswer struct {
+	ID   string
+	Text string
+}
+
+// NewPollHandler returns the handler for PollRequested events. The text
+// fallback is written in the room's locale; a nil resolver uses English.
+func NewPollHandler(locales *i18n.Resolver) Handler {
+	return Typed[PollRequestedPayload]{
+		Type: PollEventType,
+		Render: func(ctx context.Context, payload PollRequestedPayload) (Message, error) {
+			closesAt, err := time.Parse(time.RFC3339, strings.TrimSpace(payload.ClosesAt))
+			if err != nil {
+				return Message{}, fmt.Errorf("poll closes_at: %w", err)
+			}
+			poll := &Poll{
+				PollID:        strings.TrimSpace(payload.PollID),
+				Question:      strings.TrimSpace(payload.Question),
+				Disclosed:     payload.Kind != "undisclosed",
+				MaxSelections: payload.MaxSelections,
+				ClosesAt:      closesAt,
+				Context:       payload.Context,
+			}
+			if poll.MaxSelections < 1 {
+				poll.MaxSelections = 1
+			}
+
+			loc := i18n.Default().Localizer(i18n.DefaultLocale)
+			if locales != nil {
+				loc = locales.Localizer(ctx, payload.MatrixRoomID, "")
+			}
+			lines := []string{poll.Question}
+			seen := make(map[string]struct{}, len(payload.Answers))
+			for i, answer := range payload.Answers {
+				answerID := strings.TrimSpace(answer.ID)
+				if answerID == "" {
+					answerID = fmt.Sprintf("a%d", i+1)
+				}
+				if _, dup := seen[answerID]; dup {
+					return Message{}, fmt.Errorf("poll answer id %q is duplicated", answerID)
+				}
+				seen[answerID] = struct{}{}
+				poll.Answers = append(poll.Answers, PollAnswer{ID: answerID, Text: strings.TrimSpace(answer.Text)})
+				lines = append(lines, loc.T("poll.fallback.answer", i+1, strings.TrimSpace(answer.Text)))
+			}
+			if poll.MaxSelections > len(poll.Answers) {
+				return Message{}, errors.New("poll max_selections exceeds the number of answers")
+			}
+			lines = append(lines, loc.T("poll.fallback.hint"))
+
+			return Message{
+				Body:   strings.Join(lines, "\n"),
+				Format: "plain",
+				Poll:   poll,
+			}, nil
+		},
+		Route: func(payload PollRequestedPayload) (string, error) {
+			return payload.MatrixRoomID, nil
+		},
+	}
+}
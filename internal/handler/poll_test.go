@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func TestPollHandlerRender(t *testing.T) {
+	const payload = `{"poll_id":"p1","matrix_room_id":"!room:example.org","question":" Move the quiz? ","answers":[{"text":"Yes"},{"id":"later","text":"Next week"}],"closes_at":"2024-10-14T12:00:00+05:30"}`
+	cases := []struct {
+		name     string
+		locale   string
+		payload  string
+		wantBody string
+		wantErr  string
+	}{
+		{name: "english fallback", locale: "en", payload: payload, wantBody: "Move the quiz?\n1. Yes\n2. Next week\nOpen this poll in a client that supports polls to vote."},
+		{name: "room locale", locale: "hi", payload: payload, wantBody: "Move the quiz?\n1. Yes\n2. Next week\nवोट देने के लिए यह पोल ऐसे क्लाइंट में खोलें जो पोल का समर्थन करता हो।"},
+		{name: "duplicate answer id", locale: "en", payload: `{"poll_id":"p1","matrix_room_id":"!room:example.org","question":"Q","answers":[{"id":"a","text":"A"},{"id":"a","text":"B"}],"closes_at":"2024-10-14T12:00:00Z"}`, wantErr: "duplicated"},
+		{name: "too many selections", locale: "en", payload: `{"poll_id":"p1","matrix_room_id":"!room:example.org","question":"Q","answers":[{"text":"A"},{"text":"B"}],"max_selections":3,"closes_at":"2024-10-14T12:00:00Z"}`, wantErr: "max_selections"},
+		{name: "bad closes_at", locale: "en", payload: `{"poll_id":"p1","matrix_room_id":"!room:example.org","question":"Q","answers":[{"text":"A"},{"text":"B"}],"closes_at":"noon"}`, wantErr: "closes_at"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			locales, err := i18n.NewResolver(i18n.Default(), nil, tc.locale, nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := NewPollHandler(locales).Handle(context.Background(), []byte(tc.payload))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Render error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if msg.Body != tc.wantBody {
+				t.Errorf("body = %q, want %q", msg.Body, tc.wantBody)
+			}
+			if msg.Poll == nil || msg.Poll.Question != "Move the quiz?" || msg.Poll.MaxSelections != 1 || !msg.Poll.Disclosed {
+				t.Fatalf("poll = %+v", msg.Poll)
+			}
+			if ids := []string{msg.Poll.Answers[0].ID, msg.Poll.Answers[1].ID}; ids[0] != "a1" || ids[1] != "later" {
+				t.Errorf("answer ids = %v, want [a1 later]", ids)
+			}
+		})
+	}
+}
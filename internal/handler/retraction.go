@@ -0,0 +1,40 @@
+package handler
+
+import "context"
+
+// RetractionEventType is the outbox event type that withdraws an earlier
+// announcement.
+const RetractionEventType = "AnnouncementRetracted"
+
+// RetractionPayload names the outbox event whose Matrix messages should be
+// redacted.
+type RetractionPayload struct {
+	OriginalEventID string `json:"original_event_id"`
+	Reason          string `json:"reason"`
+}
+
+// Redaction asks the consumer to redact every Matrix event delivered for
+// OriginalEventID instead of sending a message.
+type Redaction struct {
+	OriginalEventID string
+	Reason          string
+}
+
+// NewRetractionHandler returns the handler for AnnouncementRetracted events.
+// The rooms come from the recorded deliveries, so the message is unrouted.
+func NewRetractionHandler() Handler {
+	return Typed[RetractionPayload]{
+		Type: RetractionEventType,
+		Render: func(_ context.Context, payload RetractionPayload) (Message, error) {
+			return Message{
+				Redaction: &Redaction{
+					OriginalEventID: payload.OriginalEventID,
+					Reason:          payload.Reason,
+				},
+			}, nil
+		},
+		Route: func(RetractionPayload) (string, error) {
+			return "", nil
+		},
+	}
+}
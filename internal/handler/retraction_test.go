@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRetractionHandler(t *testing.T) {
+	msg, err := NewRetractionHandler().Handle(context.Background(), []byte(`{"original_event_id":"0b7c6f9e-2a4e-4c1b-9f0e-1d2c3b4a5968","reason":"posted by mistake"}`))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if msg.Redaction == nil || msg.Redaction.OriginalEventID != "0b7c6f9e-2a4e-4c1b-9f0e-1d2c3b4a5968" || msg.Redaction.Reason != "posted by mistake" {
+		t.Errorf("Redaction = %+v", msg.Redaction)
+	}
+	if msg.RoomID != "" || msg.Body != "" {
+		t.Errorf("message = %+v, want only a redaction", msg)
+	}
+}
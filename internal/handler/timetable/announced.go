@@ -0,0 +1,67 @@
+package timetable
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/handler"
+)
+
+const EventTypeAnnounced = "DailyTimetableAnnounced"
+
+type AnnouncedPayload struct {
+	ClassID      string           `json:"class_id"`
+	Date         string           `json:"date"`
+	MatrixRoomID string           `json:"matrix_room_id"`
+	Locale       string           `json:"locale"`
+	Template     string           `json:"template"`
+	Slots        []Slot           `json:"slots"`
+	Mentions     handler.Mentions `json:"mentions"`
+}
+
+func newAnnouncedHandler(rd *renderer) handler.Handler {
+	return handler.Typed[AnnouncedPayload]{
+		Type: EventTypeAnnounced,
+		Validate: func(ctx context.Context, payload AnnouncedPayload) error {
+			return rd.validate(ctx, payload.MatrixRoomID, payload.Locale, payload.Date, payload.Slots)
+		},
+		Render: func(ctx context.Context, payload AnnouncedPayload) (handler.Message, error) {
+			msg := rd.render(ctx, payload.MatrixRoomID, payload.Locale, View{
+				EventType: EventTypeAnnounced,
+				ClassID:   payload.ClassID,
+				Date:      payload.Date,
+				Title:     payload.Template,
+				Slots:     payload.Slots,
+			})
+			msg.Mentions = payload.Mentions
+			msg.Anchor = announcementAnchor(payload.ClassID, payload.Date)
+			msg.Pin = true
+			return msg, nil
+		},
+		Route: func(payload AnnouncedPayload) (string, error) {
+			if strings.TrimSpace(payload.MatrixRoomID) == "" {
+				return "", errors.New("daily announcement missing matrix_room_id")
+			}
+			return payload.MatrixRoomID, nil
+		},
+	}
+}
+
+// announcementAnchor keys a class's announcement for one day, so that
+// updates for the same day can thread under it. Dates are normalized to
+// YYYY-MM-DD so producers may mix the accepted formats.
+func announcementAnchor(classID, date string) string {
+	dateKey := strings.TrimSpace(date)
+	if day, ok := parseDay(dateKey, time.UTC); ok {
+		dateKey = day.Format("2006-01-02")
+	}
+	return EventTypeAnnounced + ":" + strings.TrimSpace(classID) + ":" + dateKey
+}
+
+// AnnouncementAnchorPattern is a SQL LIKE pattern matching the anchors of
+// every class's announcement for day.
+func AnnouncementAnchorPattern(day time.Time) string {
+	return EventTypeAnnounced + ":%:" + day.Format("2006-01-02")
+}
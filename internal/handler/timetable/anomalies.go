@@ -0,0 +1,131 @@
+package timetable
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/schema"
+	"adapter-matrix/internal/slotstatus"
+)
+
+const (
+	anomalyEndBeforeStart = "end_before_start"
+	anomalyDuplicateIndex = "duplicate_index"
+	anomalyOverlap        = "overlap"
+)
+
+// anomaly is an inconsistency found in one slot. Hard anomalies make the
+// payload invalid when the handler is configured to reject them.
+type anomaly struct {
+	kind  string
+	other int
+	hard  bool
+}
+
+// findAnomalies checks every non-cancelled slot for an end at or before its
+// start, a slot_index shared with another slot, and a time range overlapping
+// another slot. The result is indexed like slots.
+func findAnomalies(slots []Slot, day time.Time, zone *time.Location) [][]anomaly {
+	type span struct {
+		start, end time.Time
+		ok         bool
+	}
+	spans := make([]span, len(slots))
+	for i, slot := range slots {
+		start, startOK := parseSlotTime(slot.StartTime, day, zone)
+		end, endOK := parseSlotTime(slot.EndTime, day, zone)
+		spans[i] = span{start: start.t, end: end.t, ok: startOK && endOK}
+	}
+
+	found := make([][]anomaly, len(slots))
+	for i, slot := range slots {
+		if slotstatus.IsCancelled(slot.Status) {
+			continue
+		}
+		if spans[i].ok && !spans[i].end.After(spans[i].start) {
+			found[i] = append(found[i], anomaly{kind: anomalyEndBeforeStart, hard: true})
+		}
+		for j, other := range slots {
+			if i == j || slotstatus.IsCancelled(other.Status) {
+				continue
+			}
+			if slot.SlotIndex == other.SlotIndex {
+				found[i] = append(found[i], anomaly{kind: anomalyDuplicateIndex, other: other.SlotIndex, hard: true})
+				continue
+			}
+			if spans[i].ok && spans[j].ok && spans[i].start.Before(spans[j].end) && spans[j].start.Before(spans[i].end) {
+				found[i] = append(found[i], anomaly{kind: anomalyOverlap, other: other.SlotIndex})
+			}
+		}
+	}
+	return found
+}
+
+// hardAnomalies returns the hard anomalies of slots on day in zone as
+// validation errors, or nil.
+func hardAnomalies(slots []Slot, day time.Time, zone *time.Location) error {
+	var errs schema.ValidationErrors
+	for i, list := range findAnomalies(slots, day, zone) {
+		for _, a := range list {
+			if !a.hard {
+				continue
+			}
+			switch a.kind {
+			case anomalyEndBeforeStart:
+				errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("/slots/%d/end_time", i), Message: "must be after start_time"})
+			case anomalyDuplicateIndex:
+				errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("/slots/%d/slot_index", i), Message: fmt.Sprintf("duplicates slot %d", a.other)})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// describeAnomalies renders anomalies as localized warnings.
+func describeAnomalies(loc *i18n.Localizer, list []anomaly) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, a := range list {
+		switch a.kind {
+		case anomalyEndBeforeStart:
+			out = append(out, loc.T("timetable.anomaly.end_before_start"))
+		case anomalyDuplicateIndex:
+			out = append(out, loc.T("timetable.anomaly.duplicate_index", a.other))
+		case anomalyOverlap:
+			out = append(out, loc.T("timetable.anomaly.overlap", a.other))
+		}
+	}
+	return out
+}
+
+// sortSlots orders slots by slot_index, then by start time, keeping the
+// producer's order for anything that compares equal.
+func sortSlots(slots []Slot, day time.Time, zone *time.Location) {
+	type keyed struct {
+		slot  Slot
+		start time.Time
+	}
+	items := make([]keyed, len(slots))
+	for i, slot := range slots {
+		items[i].slot = slot
+		if st, ok := parseSlotTime(slot.StartTime, day, zone); ok {
+			items[i].start = st.t
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		if items[a].slot.SlotIndex != items[b].slot.SlotIndex {
+			return items[a].slot.SlotIndex < items[b].slot.SlotIndex
+		}
+		return items[a].start.Before(items[b].start)
+	})
+	for i := range items {
+		slots[i] = items[i].slot
+	}
+}
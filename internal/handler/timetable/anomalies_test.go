@@ -0,0 +1,200 @@
+package timetable
+
+import (
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func newSlot(index int, start, end string) Slot {
+	return Slot{SlotIndex: index, CourseCode: "CS301", StartTime: start, EndTime: end}
+}
+
+func TestFindAnomalies(t *testing.T) {
+	day := time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+	cancelled := newSlot(2, "09:30", "10:20")
+	cancelled.Status = "Canceled"
+
+	cases := []struct {
+		name  string
+		slots []Slot
+		// want lists each slot's anomalies as "kind:other" entries.
+		want [][]string
+	}{
+		{
+			name:  "back to back slots",
+			slots: []Slot{newSlot(1, "09:00", "09:50"), newSlot(2, "09:50", "10:40")},
+			want:  [][]string{nil, nil},
+		},
+		{
+			name:  "gap between slots",
+			slots: []Slot{newSlot(1, "09:00", "09:50"), newSlot(2, "11:00", "11:50")},
+			want:  [][]string{nil, nil},
+		},
+		{
+			name:  "overlap",
+			slots: []Slot{newSlot(1, "09:00", "10:00"), newSlot(2, "09:50", "10:40"), newSlot(3, "10:40", "11:30")},
+			want:  [][]string{{"overlap:2"}, {"overlap:1"}, nil},
+		},
+		{
+			name:  "overlap across formats",
+			slots: []Slot{newSlot(1, "2024-10-14T09:00:00Z", "2024-10-14T10:00:00Z"), newSlot(2, "9:30 AM", "10:20 AM")},
+			want:  [][]string{{"overlap:2"}, {"overlap:1"}},
+		},
+		{
+			name:  "containment",
+			slots: []Slot{newSlot(1, "09:00", "12:00"), newSlot(2, "10:00", "10:50")},
+			want:  [][]string{{"overlap:2"}, {"overlap:1"}},
+		},
+		{
+			name:  "end before start",
+			slots: []Slot{newSlot(1, "10:00", "09:00")},
+			want:  [][]string{{"end_before_start:0"}},
+		},
+		{
+			name:  "zero length",
+			slots: []Slot{newSlot(1, "10:00", "10:00")},
+			want:  [][]string{{"end_before_start:0"}},
+		},
+		{
+			name:  "duplicate index is not also an overlap",
+			slots: []Slot{newSlot(1, "09:00", "09:50"), newSlot(1, "09:00", "09:50")},
+			want:  [][]string{{"duplicate_index:1"}, {"duplicate_index:1"}},
+		},
+		{
+			name:  "cancelled slots are ignored",
+			slots: []Slot{newSlot(1, "09:00", "10:00"), cancelled, newSlot(3, "10:00", "09:00")},
+			want:  [][]string{nil, nil, {"end_before_start:0"}},
+		},
+		{
+			name:  "unparseable times skip time checks",
+			slots: []Slot{newSlot(1, "soon", "later"), newSlot(2, "09:00", "09:50")},
+			want:  [][]string{nil, nil},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			found := findAnomalies(tc.slots, day, time.UTC)
+			if len(found) != len(tc.slots) {
+				t.Fatalf("findAnomalies returned %d entries for %d slots", len(found), len(tc.slots))
+			}
+			for i, list := range found {
+				got := make([]string, 0, len(list))
+				for _, a := range list {
+					got = append(got, a.kind+":"+strconv.Itoa(a.other))
+				}
+				if strings.Join(got, ",") != strings.Join(tc.want[i], ",") {
+					t.Errorf("slot %d anomalies = %v, want %v", i, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHardAnomalies(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	day := time.Date(2024, 10, 14, 0, 0, 0, 0, kolkata)
+	cases := []struct {
+		name  string
+		slots []Slot
+		want  string
+	}{
+		{"overlap is soft", []Slot{newSlot(1, "09:00", "10:00"), newSlot(2, "09:30", "10:30")}, ""},
+		{"end before start", []Slot{newSlot(1, "09:00", "09:50"), newSlot(2, "11:00", "10:00")}, "/slots/1/end_time: must be after start_time"},
+		{"duplicate index", []Slot{newSlot(4, "09:00", "09:50"), newSlot(4, "10:00", "10:50")}, "/slots/0/slot_index: duplicates slot 4; /slots/1/slot_index: duplicates slot 4"},
+		// 04:20Z is 09:50 IST, so the clock start is read on the same day in
+		// the room's zone rather than as today in UTC.
+		{"clock start with an ISO end", []Slot{newSlot(1, "09:00", "2024-10-14T04:20:00Z")}, ""},
+		{"clock start after an ISO end", []Slot{newSlot(1, "10:00", "2024-10-14T04:20:00Z")}, "/slots/0/end_time: must be after start_time"},
+		{"ISO start with a clock end", []Slot{newSlot(1, "2024-10-14T03:30:00Z", "09:50"), newSlot(2, "10:00", "10:50")}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := hardAnomalies(tc.slots, day, kolkata)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.want {
+				t.Errorf("hardAnomalies = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReadsTimesInTheRoomZone(t *testing.T) {
+	locales, err := i18n.NewResolver(i18n.Default(), nil, "", mustZone(t, "Asia/Kolkata"), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd := &renderer{locales: locales, rejectAnomalies: true}
+	slots := []Slot{newSlot(1, "09:00", "2024-10-14T04:20:00Z"), newSlot(2, "2024-10-14T04:30:00Z", "10:50")}
+	if err := rd.validate(t.Context(), "!a:example.org", "", "2024-10-14", slots); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	slots[1] = newSlot(2, "2024-10-14T04:30:00Z", "09:30")
+	if err := rd.validate(t.Context(), "!a:example.org", "", "2024-10-14", slots); err == nil || err.Error() != "/slots/1/end_time: must be after start_time" {
+		t.Errorf("validate = %v, want slot 2 rejected", err)
+	}
+}
+
+func TestRenderFlagsAnomalies(t *testing.T) {
+	rd := &renderer{}
+	msg := rd.render(t.Context(), "!a:example.org", "", View{
+		EventType: EventTypeAnnounced,
+		Date:      "2024-10-14",
+		Slots:     []Slot{newSlot(2, "09:30", "10:20"), newSlot(1, "09:00", "10:00")},
+	})
+	lines := strings.Split(msg.Body, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Body = %q, want title, date and two slots", msg.Body)
+	}
+	if !strings.HasPrefix(lines[2], "1. ") || !strings.HasSuffix(lines[2], "⚠️ overlaps slot 2") {
+		t.Errorf("line = %q, want slot 1 flagged as overlapping slot 2", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "2. ") || !strings.HasSuffix(lines[3], "⚠️ overlaps slot 1") {
+		t.Errorf("line = %q, want slot 2 flagged as overlapping slot 1", lines[3])
+	}
+	if !strings.Contains(msg.FormattedBody, "overlaps slot 2") {
+		t.Errorf("FormattedBody = %q, want the warning in the table", msg.FormattedBody)
+	}
+}
+
+func TestSortSlots(t *testing.T) {
+	slots := []Slot{
+		{SlotIndex: 2, CourseCode: "B", StartTime: "10:00"},
+		{SlotIndex: 1, CourseCode: "C", StartTime: "11:00"},
+		{SlotIndex: 1, CourseCode: "A", StartTime: "2024-10-14T09:00:00Z"},
+		{SlotIndex: 1, CourseCode: "D", StartTime: "??"},
+		{SlotIndex: 1, CourseCode: "E", StartTime: "??"},
+	}
+	sortSlots(slots, time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC), time.UTC)
+	var got []string
+	for _, s := range slots {
+		got = append(got, s.CourseCode)
+	}
+	if strings.Join(got, "") != "DEACB" {
+		t.Errorf("sortSlots order = %v, want unparseable first, then by start time within an index", got)
+	}
+}
+
+func TestDescribeAnomalies(t *testing.T) {
+	loc := i18n.Default().Localizer(i18n.DefaultLocale)
+	got := describeAnomalies(loc, []anomaly{
+		{kind: anomalyEndBeforeStart},
+		{kind: anomalyDuplicateIndex, other: 3},
+		{kind: anomalyOverlap, other: 4},
+	})
+	want := "ends before it starts; duplicates slot 3; overlaps slot 4"
+	if strings.Join(got, "; ") != want {
+		t.Errorf("describeAnomalies = %q, want %q", got, want)
+	}
+	if describeAnomalies(loc, nil) != nil {
+		t.Error("describeAnomalies(nil) != nil")
+	}
+}
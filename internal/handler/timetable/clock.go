@@ -0,0 +1,169 @@
+package timetable
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/schema"
+)
+
+// Accepted producer formats. Dates and wall-clock times without an offset are
+// read in the room's timezone; timestamps with an offset are converted to it.
+var (
+	dateLayouts = []string{
+		"2006-01-02",
+		"2006/01/02",
+		"02-01-2006",
+		"02/01/2006",
+		"2 Jan 2006",
+		"Mon 2 Jan 2006",
+	}
+	instantLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04Z07:00",
+	}
+	localDateTimeLayouts = []string{
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+	}
+	clockLayouts = []string{
+		"15:04",
+		"15:04:05",
+		"3:04PM",
+		"3:04 PM",
+		"3:04pm",
+		"3:04 pm",
+	}
+)
+
+// slotTime is a parsed start or end time. dated is false for wall-clock
+// values that borrowed their date from the announcement.
+type slotTime struct {
+	t     time.Time
+	dated bool
+}
+
+func parseDay(value string, zone *time.Location) (time.Time, bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, trimmed, zone); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range instantLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			local := t.In(zone)
+			return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseSlotTime reads a start/end value relative to day. When day is zero a
+// bare clock time is anchored to today so DST-aware zones still resolve.
+func parseSlotTime(value string, day time.Time, zone *time.Location) (slotTime, bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return slotTime{}, false
+	}
+	for _, layout := range instantLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			return slotTime{t: t.In(zone), dated: true}, true
+		}
+	}
+	for _, layout := range localDateTimeLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			return slotTime{t: wallTime(t, zone), dated: true}, true
+		}
+	}
+	if day.IsZero() {
+		now := time.Now().In(zone)
+		day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(trimmed)); err == nil {
+			wall := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+			return slotTime{t: wallTime(wall, zone)}, true
+		}
+	}
+	return slotTime{}, false
+}
+
+// wallTime reads the wall-clock fields of w (a UTC time) in zone. A time
+// skipped by a DST jump moves forward by the size of the jump, so 02:30 on a
+// spring-forward night reads as 03:30 rather than falling back to 01:30.
+func wallTime(w time.Time, zone *time.Location) time.Time {
+	t := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), zone)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if got.Before(w) {
+		t = t.Add(w.Sub(got))
+	}
+	return t
+}
+
+// formatSlotTime renders a slot's time range as "09:00–09:50 IST", prefixed
+// with the short date when a timestamp falls on a different day than the
+// announcement. Unparseable values are echoed as sent.
+func formatSlotTime(loc *i18n.Localizer, day time.Time, slot Slot) string {
+	zone := loc.Location()
+	start, startOK := parseSlotTime(slot.StartTime, day, zone)
+	end, endOK := parseSlotTime(slot.EndTime, day, zone)
+	if !startOK || !endOK {
+		return safeText(slot.StartTime) + "-" + safeText(slot.EndTime)
+	}
+
+	text := loc.FormatClock(start.t) + "–" + loc.FormatClock(end.t) + " " + loc.ZoneAbbrev(start.t)
+	if start.dated && (day.IsZero() || !sameDay(start.t, day)) {
+		text = loc.FormatShortDate(start.t) + ", " + text
+	}
+	return text
+}
+
+// announcementDay picks the announcement date, falling back to the first
+// timestamped slot when the payload has no usable date.
+func announcementDay(date string, slots []Slot, zone *time.Location) (time.Time, bool) {
+	if day, ok := parseDay(date, zone); ok {
+		return day, true
+	}
+	for _, slot := range slots {
+		if st, ok := parseSlotTime(slot.StartTime, time.Time{}, zone); ok && st.dated {
+			local := st.t.In(zone)
+			return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func sameDay(a, b time.Time) bool {
+	b = b.In(a.Location())
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// validateTimes reports dates and times that are in none of the accepted
+// formats, using the same JSON pointer paths as schema validation.
+func validateTimes(date string, slots []Slot, zone *time.Location) error {
+	var errs schema.ValidationErrors
+	day, hasDay := parseDay(date, zone)
+	if strings.TrimSpace(date) != "" && !hasDay {
+		errs = append(errs, schema.ValidationError{Path: "/date", Message: "is not a recognised date"})
+	}
+	for i, slot := range slots {
+		if _, ok := parseSlotTime(slot.StartTime, day, zone); !ok {
+			errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("/slots/%d/start_time", i), Message: "is not a recognised time"})
+		}
+		if _, ok := parseSlotTime(slot.EndTime, day, zone); !ok {
+			errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("/slots/%d/end_time", i), Message: "is not a recognised time"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package timetable
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return zone
+}
+
+func TestParseDay(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	cases := []struct {
+		name   string
+		value  string
+		zone   *time.Location
+		want   string
+		wantOK bool
+	}{
+		{"iso", "2024-10-14", kolkata, "2024-10-14", true},
+		{"slashes", " 2024/10/14 ", kolkata, "2024-10-14", true},
+		{"day first", "14-10-2024", kolkata, "2024-10-14", true},
+		{"day first slashes", "14/10/2024", kolkata, "2024-10-14", true},
+		{"month name", "14 Oct 2024", kolkata, "2024-10-14", true},
+		{"weekday", "Mon 14 Oct 2024", kolkata, "2024-10-14", true},
+		{"instant converted to the zone", "2024-10-13T20:00:00Z", kolkata, "2024-10-14", true},
+		{"instant kept in UTC", "2024-10-13T20:00:00Z", time.UTC, "2024-10-13", true},
+		{"instant with offset", "2024-10-14T01:00+05:30", time.UTC, "2024-10-13", true},
+		{"empty", "  ", kolkata, "", false},
+		{"garbage", "Monday", kolkata, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseDay(tc.value, tc.zone)
+			if ok != tc.wantOK {
+				t.Fatalf("parseDay(%q) ok = %t, want %t", tc.value, ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Format("2006-01-02") != tc.want || got.Location() != tc.zone || got.Hour() != 0 {
+				t.Errorf("parseDay(%q) = %v, want midnight %s in %s", tc.value, got, tc.want, tc.zone)
+			}
+		})
+	}
+}
+
+func TestFormatSlotTime(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	newYork := mustZone(t, "America/New_York")
+	day := func(zone *time.Location, y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, zone)
+	}
+
+	cases := []struct {
+		name       string
+		zone       *time.Location
+		day        time.Time
+		start, end string
+		want       string
+	}{
+		{"wall clock", kolkata, day(kolkata, 2024, 10, 14), "09:00", "09:50", "09:00–09:50 IST"},
+		{"twelve hour clock", kolkata, day(kolkata, 2024, 10, 14), "9:00 am", "1:30PM", "09:00–13:30 IST"},
+		{"utc instants converted", kolkata, day(kolkata, 2024, 10, 14), "2024-10-14T03:30:00Z", "2024-10-14T04:20:00Z", "09:00–09:50 IST"},
+		{"offset instants converted", kolkata, day(kolkata, 2024, 10, 14), "2024-10-14T05:30+02:00", "2024-10-14T06:20+02:00", "09:00–09:50 IST"},
+		{"local date-time", kolkata, day(kolkata, 2024, 10, 14), "2024-10-14 09:00", "2024-10-14T09:50:00", "09:00–09:50 IST"},
+		{"instant on another day", kolkata, day(kolkata, 2024, 10, 14), "2024-10-15T03:30:00Z", "2024-10-15T04:20:00Z", "Tue 15 Oct, 09:00–09:50 IST"},
+		{"daylight time", newYork, day(newYork, 2024, 11, 2), "09:00", "09:50", "09:00–09:50 EDT"},
+		{"standard time after fall back", newYork, day(newYork, 2024, 11, 3), "09:00", "09:50", "09:00–09:50 EST"},
+		{"instant across fall back", newYork, day(newYork, 2024, 11, 3), "2024-11-03T14:00:00Z", "2024-11-03T14:50:00Z", "09:00–09:50 EST"},
+		{"nonexistent time in spring forward", newYork, day(newYork, 2024, 3, 10), "02:30", "03:50", "03:30–03:50 EDT"},
+		{"nonexistent local date-time", newYork, day(newYork, 2024, 3, 10), "2024-03-10 02:15", "2024-03-10 03:50", "03:15–03:50 EDT"},
+		{"ambiguous time in fall back", newYork, day(newYork, 2024, 11, 3), "01:30", "03:00", "01:30–03:00 EDT"},
+		{"unparseable echoed", kolkata, day(kolkata, 2024, 10, 14), "nine", " ", "nine--"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc := i18n.Default().Localizer(i18n.DefaultLocale).In(tc.zone)
+			got := formatSlotTime(loc, tc.day, Slot{StartTime: tc.start, EndTime: tc.end})
+			if got != tc.want {
+				t.Errorf("formatSlotTime(%q, %q) = %q, want %q", tc.start, tc.end, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAnnouncementDay(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	cases := []struct {
+		name   string
+		date   string
+		slots  []Slot
+		want   string
+		wantOK bool
+	}{
+		{"from date", "2024-10-14", []Slot{{StartTime: "2024-10-20T03:30:00Z"}}, "2024-10-14", true},
+		{"from first timestamped slot", "", []Slot{{StartTime: "09:00"}, {StartTime: "2024-10-14T20:00:00Z"}}, "2024-10-15", true},
+		{"wall clocks only", "", []Slot{{StartTime: "09:00"}}, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := announcementDay(tc.date, tc.slots, kolkata)
+			if ok != tc.wantOK || (ok && got.Format("2006-01-02") != tc.want) {
+				t.Errorf("announcementDay = %v, %t; want %s, %t", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTimes(t *testing.T) {
+	err := validateTimes("14.10.2024", []Slot{
+		{StartTime: "09:00", EndTime: "2024-10-14T09:50:00Z"},
+		{StartTime: "25:00", EndTime: "soon"},
+	}, time.UTC)
+	want := "/date: is not a recognised date; /slots/1/start_time: is not a recognised time; /slots/1/end_time: is not a recognised time"
+	if err == nil || err.Error() != want {
+		t.Errorf("validateTimes = %v, want %q", err, want)
+	}
+	if err := validateTimes("2024-03-10", []Slot{{StartTime: "2:30 AM", EndTime: "21:50:00"}}, mustZone(t, "America/New_York")); err != nil {
+		t.Errorf("validateTimes: %v", err)
+	}
+}
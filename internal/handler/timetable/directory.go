@@ -0,0 +1,91 @@
+package timetable
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"adapter-matrix/internal/repository"
+)
+
+// Directory resolves course and venue codes to display details.
+type Directory interface {
+	Courses(ctx context.Context, codes []string) (map[string]repository.CourseEntry, error)
+	Venues(ctx context.Context, codes []string) (map[string]repository.VenueEntry, error)
+}
+
+// enrich fills CourseText, VenueText and VenueMapURL for every slot, falling
+// back to the raw codes when there is no directory or a lookup fails.
+func (rd *renderer) enrich(ctx context.Context, slots []Slot) {
+	for i := range slots {
+		slots[i].CourseText = safeText(slots[i].CourseCode)
+		slots[i].VenueText = safeText(slots[i].Venue)
+	}
+	if rd.directory == nil {
+		return
+	}
+
+	courseCodes := make([]string, 0, len(slots))
+	venueCodes := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		if code := strings.TrimSpace(slot.CourseCode); code != "" {
+			courseCodes = append(courseCodes, code)
+		}
+		if code := strings.TrimSpace(slot.Venue); code != "" {
+			venueCodes = append(venueCodes, code)
+		}
+	}
+
+	courses, err := rd.directory.Courses(ctx, courseCodes)
+	if err != nil {
+		rd.logf("timetable: course directory lookup failed: %v", err)
+	}
+	venues, err := rd.directory.Venues(ctx, venueCodes)
+	if err != nil {
+		rd.logf("timetable: venue directory lookup failed: %v", err)
+	}
+
+	for i, slot := range slots {
+		if course, ok := courses[strings.TrimSpace(slot.CourseCode)]; ok {
+			slots[i].CourseText = courseText(course)
+		}
+		if venue, ok := venues[strings.TrimSpace(slot.Venue)]; ok {
+			slots[i].VenueText = venueText(venue)
+			slots[i].VenueMapURL = safeURL(venue.MapURL)
+		}
+	}
+}
+
+// courseText renders "CS301 Operating Systems (Dr. X)".
+func courseText(course repository.CourseEntry) string {
+	text := course.CourseCode
+	if title := strings.TrimSpace(course.Title); title != "" {
+		text += " " + title
+	}
+	if faculty := strings.TrimSpace(course.Faculty); faculty != "" {
+		text += " (" + faculty + ")"
+	}
+	return text
+}
+
+// venueText renders "Lecture Hall Complex 2, 1st floor", or the code when
+// the entry has no building.
+func venueText(venue repository.VenueEntry) string {
+	building := strings.TrimSpace(venue.Building)
+	if building == "" {
+		return venue.VenueCode
+	}
+	if floor := strings.TrimSpace(venue.Floor); floor != "" {
+		return building + ", " + floor
+	}
+	return building
+}
+
+// safeURL returns raw only if it is an absolute http(s) URL.
+func safeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
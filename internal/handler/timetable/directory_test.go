@@ -0,0 +1,119 @@
+package timetable
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"adapter-matrix/internal/repository"
+)
+
+type fakeDirectory struct {
+	courses   map[string]repository.CourseEntry
+	venues    map[string]repository.VenueEntry
+	venuesErr error
+	asked     []string
+}
+
+func (f *fakeDirectory) Courses(_ context.Context, codes []string) (map[string]repository.CourseEntry, error) {
+	f.asked = append(f.asked, codes...)
+	out := make(map[string]repository.CourseEntry)
+	for _, code := range codes {
+		if entry, ok := f.courses[code]; ok {
+			out[code] = entry
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDirectory) Venues(_ context.Context, codes []string) (map[string]repository.VenueEntry, error) {
+	f.asked = append(f.asked, codes...)
+	if f.venuesErr != nil {
+		return nil, f.venuesErr
+	}
+	out := make(map[string]repository.VenueEntry)
+	for _, code := range codes {
+		if entry, ok := f.venues[code]; ok {
+			out[code] = entry
+		}
+	}
+	return out, nil
+}
+
+func TestEnrich(t *testing.T) {
+	dir := &fakeDirectory{
+		courses: map[string]repository.CourseEntry{
+			"CS301": {CourseCode: "CS301", Title: "Operating Systems", Faculty: "Dr. X"},
+			"MA201": {CourseCode: "MA201", Title: "Linear Algebra"},
+			"PH101": {CourseCode: "PH101"},
+		},
+		venues: map[string]repository.VenueEntry{
+			"LHC-2": {VenueCode: "LHC-2", Building: "Lecture Hall Complex 2", Floor: "1st floor", MapURL: "https://maps.example.org/lhc-2"},
+			"LHC-3": {VenueCode: "LHC-3", Building: "Lecture Hall Complex 3", MapURL: "javascript:alert(1)"},
+			"LAB-1": {VenueCode: "LAB-1", Floor: "Ground floor", MapURL: "//maps.example.org/lab-1"},
+		},
+	}
+	cases := []struct {
+		name       string
+		slot       Slot
+		wantCourse string
+		wantVenue  string
+		wantMap    string
+	}{
+		{name: "full entries", slot: Slot{CourseCode: " CS301 ", Venue: "LHC-2"}, wantCourse: "CS301 Operating Systems (Dr. X)", wantVenue: "Lecture Hall Complex 2, 1st floor", wantMap: "https://maps.example.org/lhc-2"},
+		{name: "no faculty, unsafe map link", slot: Slot{CourseCode: "MA201", Venue: "LHC-3"}, wantCourse: "MA201 Linear Algebra", wantVenue: "Lecture Hall Complex 3"},
+		{name: "bare entries", slot: Slot{CourseCode: "PH101", Venue: "LAB-1"}, wantCourse: "PH101", wantVenue: "LAB-1"},
+		{name: "not in the directory", slot: Slot{CourseCode: "EE101", Venue: "Annex"}, wantCourse: "EE101", wantVenue: "Annex"},
+		{name: "no codes", slot: Slot{}, wantCourse: "-", wantVenue: "-"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			slots := []Slot{tc.slot}
+			(&renderer{directory: dir}).enrich(context.Background(), slots)
+			got := slots[0]
+			if got.CourseText != tc.wantCourse || got.VenueText != tc.wantVenue || got.VenueMapURL != tc.wantMap {
+				t.Errorf("enrich = %q, %q, %q; want %q, %q, %q", got.CourseText, got.VenueText, got.VenueMapURL, tc.wantCourse, tc.wantVenue, tc.wantMap)
+			}
+		})
+	}
+}
+
+func TestEnrichFallsBack(t *testing.T) {
+	slots := []Slot{{CourseCode: "CS301", Venue: "LHC-2"}}
+	(&renderer{}).enrich(context.Background(), slots)
+	if slots[0].CourseText != "CS301" || slots[0].VenueText != "LHC-2" {
+		t.Errorf("without a directory = %+v, want the raw codes", slots[0])
+	}
+
+	dir := &fakeDirectory{
+		courses:   map[string]repository.CourseEntry{"CS301": {CourseCode: "CS301", Title: "Operating Systems"}},
+		venuesErr: errors.New("database unavailable"),
+	}
+	slots = []Slot{{CourseCode: "CS301", Venue: "LHC-2"}, {Venue: " "}}
+	(&renderer{directory: dir}).enrich(context.Background(), slots)
+	if slots[0].CourseText != "CS301 Operating Systems" || slots[0].VenueText != "LHC-2" {
+		t.Errorf("with a failed venue lookup = %+v, want courses enriched and the raw venue", slots[0])
+	}
+	if got := strings.Join(dir.asked, ","); got != "CS301,LHC-2" {
+		t.Errorf("looked up %q, want blank codes skipped", got)
+	}
+}
+
+func TestTimetableRenderEnriched(t *testing.T) {
+	dir := &fakeDirectory{
+		courses: map[string]repository.CourseEntry{"CS301": {CourseCode: "CS301", Title: "Operating Systems", Faculty: "Dr. X"}},
+		venues:  map[string]repository.VenueEntry{"LHC-2": {VenueCode: "LHC-2", Building: "Lecture Hall Complex 2", MapURL: "https://maps.example.org/lhc-2"}},
+	}
+	msg, err := testRegistry(t, Options{Directory: dir}).Handle(context.Background(), EventTypeAnnounced, []byte(`{"date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[
+		{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50","venue":"LHC-2"}]}`))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if want := "1. CS301 Operating Systems (Dr. X) (09:00–09:50 UTC) @ Lecture Hall Complex 2"; !strings.Contains(msg.Body, want) {
+		t.Errorf("Body = %q, want %q", msg.Body, want)
+	}
+	if want := `<a href="https://maps.example.org/lhc-2">Lecture Hall Complex 2</a>`; !strings.Contains(msg.FormattedBody, want) {
+		t.Errorf("FormattedBody = %q, want %q", msg.FormattedBody, want)
+	}
+}
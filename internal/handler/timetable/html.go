@@ -0,0 +1,116 @@
+package timetable
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/slotstatus"
+)
+
+// Table columns, also used as the values of Slot.Changed.
+const (
+	fieldCourse    = "course_code"
+	fieldStartTime = "start_time"
+	fieldEndTime   = "end_time"
+	fieldVenue     = "venue"
+	fieldStatus    = "status"
+)
+
+// renderHTMLTable renders the timetable as an HTML table for formatted_body.
+// Cancelled slots are struck through and changed cells are emphasised.
+func renderHTMLTable(loc *i18n.Localizer, title, dateText string, slots []Slot) string {
+	var b strings.Builder
+	b.WriteString("<p><strong>")
+	b.WriteString(html.EscapeString(title))
+	b.WriteString("</strong>")
+	if strings.TrimSpace(dateText) != "" {
+		b.WriteString("<br>")
+		b.WriteString(html.EscapeString(loc.T("timetable.date", dateText)))
+	}
+	b.WriteString("</p>\n")
+
+	b.WriteString("<table>\n<thead><tr>")
+	for _, key := range []string{"slot", "course", "time", "venue", "status"} {
+		b.WriteString("<th>")
+		b.WriteString(html.EscapeString(loc.T("timetable.column." + key)))
+		b.WriteString("</th>")
+	}
+	b.WriteString("</tr></thead>\n<tbody>\n")
+	for _, slot := range slots {
+		changed := changedFields(slot)
+		info, _ := slotstatus.Lookup(slot.Status)
+		struck := info.Struck
+
+		b.WriteString("<tr>")
+		writeCell(&b, strconv.Itoa(slot.SlotIndex), struck, false)
+		writeCell(&b, slot.CourseText, struck, changed[fieldCourse])
+		writeCell(&b, slot.TimeText, struck, changed[fieldStartTime] || changed[fieldEndTime])
+		writeLinkCell(&b, slot.VenueText, slot.VenueMapURL, struck, changed[fieldVenue])
+		writeCell(&b, safeText(slot.StatusText), false, changed[fieldStatus], slot.Warnings...)
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>")
+	return b.String()
+}
+
+func writeCell(b *strings.Builder, text string, struck, emphasised bool, warnings ...string) {
+	content := html.EscapeString(text)
+	if emphasised {
+		content = "<strong>" + content + "</strong>"
+	}
+	if struck {
+		content = "<del>" + content + "</del>"
+	}
+	for _, warning := range warnings {
+		content += "<br><em>⚠️ " + html.EscapeString(warning) + "</em>"
+	}
+	b.WriteString("<td>")
+	b.WriteString(content)
+	b.WriteString("</td>")
+}
+
+// writeLinkCell is writeCell with the text linked to href when set.
+func writeLinkCell(b *strings.Builder, text, href string, struck, emphasised bool) {
+	if href == "" {
+		writeCell(b, text, struck, emphasised)
+		return
+	}
+	content := `<a href="` + html.EscapeString(href) + `">` + html.EscapeString(text) + "</a>"
+	if emphasised {
+		content = "<strong>" + content + "</strong>"
+	}
+	if struck {
+		content = "<del>" + content + "</del>"
+	}
+	b.WriteString("<td>")
+	b.WriteString(content)
+	b.WriteString("</td>")
+}
+
+// changedFields merges the producer's explicit Changed list with the fields
+// the slot status emphasises.
+func changedFields(slot Slot) map[string]bool {
+	changed := make(map[string]bool, len(slot.Changed)+3)
+	for _, field := range slot.Changed {
+		changed[strings.TrimSpace(field)] = true
+	}
+	if info, ok := slotstatus.Lookup(slot.Status); ok {
+		for _, field := range info.Emphasis {
+			switch field {
+			case slotstatus.FieldCourse:
+				changed[fieldCourse] = true
+			case slotstatus.FieldTime:
+				changed[fieldStartTime] = true
+				changed[fieldEndTime] = true
+			case slotstatus.FieldVenue:
+				changed[fieldVenue] = true
+			}
+		}
+	}
+	if len(changed) > 0 {
+		changed[fieldStatus] = true
+	}
+	return changed
+}
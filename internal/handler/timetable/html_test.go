@@ -0,0 +1,86 @@
+package timetable
+
+import (
+	"strings"
+	"testing"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func TestRenderHTMLTable(t *testing.T) {
+	loc := i18n.Default().Localizer("en")
+	cases := []struct {
+		name    string
+		slot    Slot
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "scheduled",
+			slot: Slot{SlotIndex: 1, CourseText: "CS301", TimeText: "09:00–09:50 UTC", VenueText: "LHC-2", Status: "scheduled", StatusText: "✅ Scheduled"},
+			want: []string{"<tr><td>1</td><td>CS301</td><td>09:00–09:50 UTC</td><td>LHC-2</td><td>✅ Scheduled</td></tr>"},
+		},
+		{
+			name:    "cancelled is struck through except the status",
+			slot:    Slot{SlotIndex: 2, CourseText: "MA201", TimeText: "10:00–10:50 UTC", VenueText: "LHC-3", Status: "canceled", StatusText: "❌ Cancelled"},
+			want:    []string{"<td><del>2</del></td>", "<td><del>MA201</del></td>", "<td><del>LHC-3</del></td>", "<td>❌ Cancelled</td>"},
+			notWant: []string{"<td><strong>"},
+		},
+		{
+			name:    "explicitly changed cells",
+			slot:    Slot{SlotIndex: 3, CourseText: "PH101", TimeText: "11:00–11:50 UTC", VenueText: "LHC-4", Changed: []string{" venue "}},
+			want:    []string{"<td><strong>LHC-4</strong></td>", "<td><strong>-</strong></td>", "<td>PH101</td>"},
+			notWant: []string{"<del>"},
+		},
+		{
+			name: "status emphasis",
+			slot: Slot{SlotIndex: 4, CourseText: "CS302", TimeText: "12:00–12:50 UTC", VenueText: "Lab 1", Status: "rescheduled", StatusText: "🔁 Rescheduled"},
+			want: []string{"<td><strong>12:00–12:50 UTC</strong></td>", "<td>CS302</td>", "<td><strong>🔁 Rescheduled</strong></td>"},
+		},
+		{
+			name: "escaped text and venue link",
+			slot: Slot{SlotIndex: 5, CourseText: "R&D <lab>", TimeText: "13:00–13:50 UTC", VenueText: "Block \"A\"", VenueMapURL: "https://maps.example.org/?a=1&b=2", Status: "unknown"},
+			want: []string{"<td>R&amp;D &lt;lab&gt;</td>", `<td><a href="https://maps.example.org/?a=1&amp;b=2">Block &#34;A&#34;</a></td>`},
+		},
+		{
+			name: "warnings follow the status",
+			slot: Slot{SlotIndex: 6, CourseText: "CS303", TimeText: "14:00–13:50 UTC", VenueText: "LHC-2", StatusText: "✅ Scheduled", Warnings: []string{"ends before it starts"}},
+			want: []string{"<td>✅ Scheduled<br><em>⚠️ ends before it starts</em></td>"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderHTMLTable(loc, "Timetable update", "Mon 14 Oct 2024", []Slot{tc.slot})
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("table = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("table = %q, want no %q", got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderHTMLTableLayout(t *testing.T) {
+	slots := []Slot{
+		{SlotIndex: 1, CourseText: "CS301", TimeText: "09:00–09:50 UTC", VenueText: "LHC-2"},
+		{SlotIndex: 2, CourseText: "MA201", TimeText: "10:00–10:50 UTC", VenueText: "LHC-3"},
+	}
+	got := renderHTMLTable(i18n.Default().Localizer("en"), "Timetable <update>", "Mon 14 Oct 2024", slots)
+	want := "<p><strong>Timetable &lt;update&gt;</strong><br>Date: Mon 14 Oct 2024</p>\n" +
+		"<table>\n<thead><tr><th>Slot</th><th>Course</th><th>Time</th><th>Venue</th><th>Status</th></tr></thead>\n<tbody>\n" +
+		"<tr><td>1</td><td>CS301</td><td>09:00–09:50 UTC</td><td>LHC-2</td><td>-</td></tr>\n" +
+		"<tr><td>2</td><td>MA201</td><td>10:00–10:50 UTC</td><td>LHC-3</td><td>-</td></tr>\n" +
+		"</tbody>\n</table>"
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := renderHTMLTable(i18n.Default().Localizer("en"), "Timetable update", " ", nil); strings.Contains(got, "<br>") || !strings.Contains(got, "<tbody>\n</tbody>") {
+		t.Errorf("table without a date or slots = %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package timetable
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"adapter-matrix/internal/handler"
+	"adapter-matrix/internal/slotstatus"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\r", `\n`, "\n", `\n`)
+
+// calendarAttachment renders slots as an RFC 5545 calendar with one VEVENT per
+// slot whose times parse. ok is false when no slot could be placed.
+func calendarAttachment(classID string, day time.Time, slots []Slot) (handler.Attachment, bool) {
+	now := time.Now().UTC().Format(icsTimeLayout)
+	dayText := day.Format("2006-01-02")
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//adapter-matrix//timetable//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	events := 0
+	for _, slot := range slots {
+		start, startOK := parseSlotTime(slot.StartTime, day, day.Location())
+		end, endOK := parseSlotTime(slot.EndTime, day, day.Location())
+		if !startOK || !endOK {
+			continue
+		}
+		status := "CONFIRMED"
+		if slotstatus.IsCancelled(slot.Status) {
+			status = "CANCELLED"
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%s-%d@adapter-matrix", sanitizeFilePart(classID), dayText, slot.SlotIndex),
+			"DTSTAMP:"+now,
+			"DTSTART:"+start.t.UTC().Format(icsTimeLayout),
+			"DTEND:"+end.t.UTC().Format(icsTimeLayout),
+			"SUMMARY:"+icsEscaper.Replace(safeText(slot.CourseText)),
+		)
+		if strings.TrimSpace(slot.VenueText) != "" {
+			lines = append(lines, "LOCATION:"+icsEscaper.Replace(slot.VenueText))
+		}
+		if strings.TrimSpace(slot.StatusText) != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscaper.Replace(slot.StatusText))
+		}
+		lines = append(lines, "STATUS:"+status, "END:VEVENT")
+		events++
+	}
+	if events == 0 {
+		return handler.Attachment{}, false
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+
+	name := "timetable-" + dayText + ".ics"
+	if part := sanitizeFilePart(classID); part != "" {
+		name = "timetable-" + part + "-" + dayText + ".ics"
+	}
+	return handler.Attachment{
+		FileName: name,
+		MimeType: "text/calendar",
+		Data:     []byte(b.String()),
+	}, true
+}
+
+// foldICSLine splits lines longer than 75 octets as RFC 5545 requires,
+// without breaking UTF-8 sequences.
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, leaving 74 octets of text.
+		width = limit - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+func sanitizeFilePart(value string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(strings.TrimSpace(value), "-"), "-")
+}
@@ -0,0 +1,138 @@
+package timetable
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestICSEscaping(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"CS301", "CS301"},
+		{`Lab; Room 2, Block A`, `Lab\; Room 2\, Block A`},
+		{`C:\labs`, `C:\\labs`},
+		{"line one\nline two", `line one\nline two`},
+		{"line one\r\nline two", `line one\nline two`},
+		{"stray\rreturn", `stray\nreturn`},
+	}
+	for _, tc := range cases {
+		if got := icsEscaper.Replace(tc.in); got != tc.want {
+			t.Errorf("escape(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:CS301"},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"76 octets", "SUMMARY:" + strings.Repeat("a", 68)},
+		{"several folds", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"multi-byte runes", "LOCATION:" + strings.Repeat("व्याख्यान कक्ष ", 12)},
+		{"emoji at the boundary", "DESCRIPTION:" + strings.Repeat("x", 62) + strings.Repeat("❌", 10)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			folded := foldICSLine(tc.line)
+			parts := strings.Split(folded, "\r\n")
+			for i, part := range parts {
+				if len(part) > 75 {
+					t.Errorf("line %d is %d octets: %q", i, len(part), part)
+				}
+				if i > 0 && !strings.HasPrefix(part, " ") {
+					t.Errorf("continuation line %d does not start with a space: %q", i, part)
+				}
+				if !utf8.ValidString(part) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, part)
+				}
+			}
+			if len(tc.line) <= 75 && len(parts) != 1 {
+				t.Errorf("folded a %d-octet line", len(tc.line))
+			}
+			if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != tc.line {
+				t.Errorf("unfolded = %q, want %q", unfolded, tc.line)
+			}
+		})
+	}
+}
+
+func TestCalendarAttachment(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	day := time.Date(2024, 10, 14, 0, 0, 0, 0, kolkata)
+	slots := []Slot{
+		{SlotIndex: 1, StartTime: "09:00", EndTime: "09:50", CourseText: "CS301 Operating Systems, (Dr. X)", VenueText: "LHC-2; 1st floor", StatusText: "✅ Scheduled"},
+		{SlotIndex: 2, StartTime: "2024-10-14T04:30:00Z", EndTime: "2024-10-14T05:20:00Z", CourseText: "MA201", Status: "cancelled"},
+		{SlotIndex: 3, StartTime: "later", EndTime: "11:50", CourseText: "Skipped"},
+	}
+
+	att, ok := calendarAttachment("CSE 3/A", day, slots)
+	if !ok {
+		t.Fatal("calendarAttachment returned no calendar")
+	}
+	if att.FileName != "timetable-CSE-3-A-2024-10-14.ics" || att.MimeType != "text/calendar" {
+		t.Errorf("attachment = %q %q", att.FileName, att.MimeType)
+	}
+	data := string(att.Data)
+	if !strings.HasSuffix(data, "END:VCALENDAR\r\n") || strings.Contains(strings.ReplaceAll(data, "\r\n", ""), "\n") {
+		t.Errorf("calendar is not CRLF terminated: %q", data)
+	}
+	for _, want := range []string{
+		"UID:CSE-3-A-2024-10-14-1@adapter-matrix\r\n",
+		"DTSTART:20241014T033000Z\r\n",
+		"DTEND:20241014T042000Z\r\n",
+		`SUMMARY:CS301 Operating Systems\, (Dr. X)` + "\r\n",
+		`LOCATION:LHC-2\; 1st floor` + "\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"UID:CSE-3-A-2024-10-14-2@adapter-matrix\r\n",
+		"DTSTART:20241014T043000Z\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("calendar missing %q:\n%s", want, data)
+		}
+	}
+	if strings.Count(data, "BEGIN:VEVENT") != 2 || strings.Contains(data, "Skipped") {
+		t.Errorf("calendar should hold only the two parseable slots:\n%s", data)
+	}
+
+	// UIDs depend only on class, day and slot, so a resent or updated
+	// calendar replaces the imported events instead of duplicating them.
+	slots[0].CourseText = "CS302"
+	slots[0].StartTime = "10:00"
+	again, _ := calendarAttachment("CSE 3/A", day, slots)
+	if uids(t, again.Data) != uids(t, att.Data) {
+		t.Errorf("UIDs changed between renders: %q vs %q", uids(t, again.Data), uids(t, att.Data))
+	}
+	other, _ := calendarAttachment("CSE 3/B", day, slots)
+	if uids(t, other.Data) == uids(t, att.Data) {
+		t.Error("UIDs are shared between classes")
+	}
+}
+
+func TestCalendarAttachmentWithoutEvents(t *testing.T) {
+	day := time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+	if _, ok := calendarAttachment("c1", day, []Slot{{StartTime: "soon", EndTime: "later"}}); ok {
+		t.Error("calendarAttachment produced a calendar without events")
+	}
+	att, ok := calendarAttachment("  ", day, []Slot{{StartTime: "09:00", EndTime: "09:50"}})
+	if !ok || att.FileName != "timetable-2024-10-14.ics" {
+		t.Errorf("attachment = %q, %t; want a dated file name without a class", att.FileName, ok)
+	}
+}
+
+func uids(t *testing.T, data []byte) string {
+	t.Helper()
+	var out []string
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if strings.HasPrefix(line, "UID:") {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,233 @@
+package timetable
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"adapter-matrix/internal/handler"
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/slotstatus"
+	"adapter-matrix/internal/templates"
+)
+
+// Slot is one period in a class timetable as sent by CR45.
+type Slot struct {
+	SlotIndex  int      `json:"slot_index"`
+	CourseCode string   `json:"course_code"`
+	StartTime  string   `json:"start_time"`
+	EndTime    string   `json:"end_time"`
+	Venue      string   `json:"venue"`
+	Status     string   `json:"status"`
+	Changed    []string `json:"changed"`
+
+	// Display fields filled in before templates run: TimeText is the
+	// rendered time range, CourseText/VenueText/VenueMapURL come from the
+	// directories, StatusText is the emoji and localized status label and
+	// Warnings the localized anomalies for this slot.
+	TimeText    string   `json:"-"`
+	CourseText  string   `json:"-"`
+	VenueText   string   `json:"-"`
+	VenueMapURL string   `json:"-"`
+	StatusText  string   `json:"-"`
+	Warnings    []string `json:"-"`
+}
+
+// View is the data passed to configured timetable templates. DateText is
+// Date formatted for Locale.
+type View struct {
+	EventType string
+	ClassID   string
+	Date      string
+	DateText  string
+	Locale    string
+	Title     string
+	UpdatedBy string
+	Slots     []Slot
+}
+
+// Options configures the timetable handlers. Templates may be nil, in which
+// case the built-in layout is always used; Locales may be nil, in which case
+// only the payload locale is honoured. RejectAnomalies fails payloads with
+// hard inconsistencies (end before start, duplicate slot_index) instead of
+// flagging them in the message. Directory may be nil to skip enrichment.
+// CalendarAttachment sends daily announcements with an .ics file.
+type Options struct {
+	Templates          templates.Renderer
+	Locales            *i18n.Resolver
+	Directory          Directory
+	RejectAnomalies    bool
+	CalendarAttachment bool
+	Logger             *log.Logger
+}
+
+type renderer struct {
+	templates       templates.Renderer
+	locales         *i18n.Resolver
+	directory       Directory
+	rejectAnomalies bool
+	calendar        bool
+	logger          *log.Logger
+}
+
+// Register adds the timetable event handlers to r.
+func Register(r *handler.Registry, opts Options) error {
+	rd := &renderer{
+		templates:       opts.Templates,
+		locales:         opts.Locales,
+		directory:       opts.Directory,
+		rejectAnomalies: opts.RejectAnomalies,
+		calendar:        opts.CalendarAttachment,
+		logger:          opts.Logger,
+	}
+	for _, h := range []handler.Handler{newAnnouncedHandler(rd), newUpdatedHandler(rd)} {
+		if err := r.Register(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SampleViews returns representative template data per event type, used to
+// validate configured templates at startup.
+func SampleViews() map[string]any {
+	slots := []Slot{
+		{SlotIndex: 1, CourseCode: "CS301", StartTime: "09:00", EndTime: "09:50", Venue: "LHC-2", Status: "scheduled", TimeText: "09:00–09:50 IST", CourseText: "CS301 Operating Systems (Dr. X)", VenueText: "Lecture Hall Complex 2, 1st floor", VenueMapURL: "https://maps.example.org/lhc-2", StatusText: "✅ Scheduled"},
+		{SlotIndex: 2, CourseCode: "MA201", StartTime: "10:00", EndTime: "10:50", Venue: "LHC-3", Status: "cancelled", TimeText: "10:00–10:50 IST", CourseText: "MA201", VenueText: "LHC-3", StatusText: "❌ Cancelled"},
+	}
+	return map[string]any{
+		EventTypeAnnounced: View{EventType: EventTypeAnnounced, ClassID: "sample", Date: "2024-10-14", DateText: "Mon 14 Oct 2024", Locale: i18n.DefaultLocale, Title: "Timetable update", Slots: slots},
+		EventTypeUpdated:   View{EventType: EventTypeUpdated, ClassID: "sample", Date: "2024-10-14", DateText: "Mon 14 Oct 2024", Locale: i18n.DefaultLocale, Title: "Timetable update", UpdatedBy: "sample", Slots: slots},
+	}
+}
+
+func (rd *renderer) localizer(ctx context.Context, roomID, requested string) *i18n.Localizer {
+	if rd.locales == nil {
+		return i18n.Default().Localizer(requested)
+	}
+	return rd.locales.Localizer(ctx, roomID, requested)
+}
+
+func (rd *renderer) render(ctx context.Context, roomID, requestedLocale string, view View) handler.Message {
+	loc := rd.localizer(ctx, roomID, requestedLocale)
+	view.Locale = loc.Locale()
+	if strings.TrimSpace(view.Title) == "" {
+		view.Title = loc.T("timetable.title")
+	}
+	day, hasDay := announcementDay(view.Date, view.Slots, loc.Location())
+	if hasDay {
+		view.DateText = loc.FormatDate(day)
+	} else {
+		view.DateText = strings.TrimSpace(view.Date)
+	}
+	anomalies := findAnomalies(view.Slots, day, loc.Location())
+	slots := make([]Slot, len(view.Slots))
+	for i, slot := range view.Slots {
+		slot.TimeText = formatSlotTime(loc, day, slot)
+		slot.StatusText = formatStatus(loc, slot.Status)
+		slot.Warnings = describeAnomalies(loc, anomalies[i])
+		slots[i] = slot
+	}
+	rd.enrich(ctx, slots)
+	sortSlots(slots, day, loc.Location())
+	view.Slots = slots
+
+	msg := handler.Message{
+		Body:          renderMessage(loc, view.Title, view.DateText, view.Slots),
+		FormattedBody: renderHTMLTable(loc, view.Title, view.DateText, view.Slots),
+		Format:        "html",
+	}
+	// Updates may carry only the changed slots, so only a full-day
+	// announcement can describe the whole day in the topic.
+	if view.EventType == EventTypeAnnounced {
+		msg.Topic = summarizeTopic(loc, day, hasDay, view.Slots)
+	}
+	if rd.calendar && hasDay && view.EventType == EventTypeAnnounced {
+		if attachment, ok := calendarAttachment(view.ClassID, day, view.Slots); ok {
+			msg.Attachments = append(msg.Attachments, attachment)
+		}
+	}
+	if rd.templates == nil {
+		return msg
+	}
+
+	out, ok, err := rd.templates.Render(templates.Key{EventType: view.EventType, RoomID: roomID, Locale: view.Locale}, view)
+	if err != nil {
+		rd.logf("templates: %s render failed, using built-in layout: %v", view.EventType, err)
+		return msg
+	}
+	if !ok {
+		return msg
+	}
+	if out.Text != "" {
+		msg.Body = out.Text
+		if out.HTML == "" {
+			// A custom text layout should not be paired with the built-in table.
+			msg.FormattedBody = ""
+			msg.Format = "markdown"
+		}
+	}
+	if out.HTML != "" {
+		msg.FormattedBody = out.HTML
+		msg.Format = "html"
+	}
+	return msg
+}
+
+func renderMessage(loc *i18n.Localizer, title, dateText string, slots []Slot) string {
+	lines := make([]string, 0, len(slots)+2)
+	lines = append(lines, title)
+	if strings.TrimSpace(dateText) != "" {
+		lines = append(lines, loc.T("timetable.date", dateText))
+	}
+
+	for _, slot := range slots {
+		line := fmt.Sprintf("%d. %s (%s) @ %s [%s]", slot.SlotIndex, slot.CourseText, slot.TimeText, slot.VenueText, safeText(slot.StatusText))
+		if len(slot.Warnings) > 0 {
+			line += " ⚠️ " + strings.Join(slot.Warnings, "; ")
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatStatus renders a known status as emoji plus localized label and
+// passes unknown statuses through unchanged.
+func formatStatus(loc *i18n.Localizer, status string) string {
+	info, ok := slotstatus.Lookup(status)
+	if !ok {
+		return strings.TrimSpace(status)
+	}
+	return info.Emoji + " " + loc.T(info.LabelKey)
+}
+
+// validate checks what the schema cannot: that dates and times parse and,
+// when configured, that slots are free of hard anomalies. Times are read on
+// the announcement day in the room's timezone, as render reads them.
+func (rd *renderer) validate(ctx context.Context, roomID, requestedLocale, date string, slots []Slot) error {
+	zone := rd.localizer(ctx, roomID, requestedLocale).Location()
+	if err := validateTimes(date, slots, zone); err != nil {
+		return err
+	}
+	if rd.rejectAnomalies {
+		day, _ := announcementDay(date, slots, zone)
+		return hardAnomalies(slots, day, zone)
+	}
+	return nil
+}
+
+func (rd *renderer) logf(format string, args ...any) {
+	if rd.logger != nil {
+		rd.logger.Printf(format, args...)
+	}
+}
+
+func safeText(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "-"
+	}
+	return trimmed
+}
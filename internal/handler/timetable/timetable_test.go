@@ -0,0 +1,153 @@
+package timetable
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"adapter-matrix/internal/handler"
+)
+
+func testRegistry(t *testing.T, opts Options) *handler.Registry {
+	t.Helper()
+	r := handler.NewRegistry()
+	if err := Register(r, opts); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return r
+}
+
+func TestTimetableRender(t *testing.T) {
+	r := testRegistry(t, Options{})
+	cases := []struct {
+		name             string
+		eventType        string
+		payload          string
+		wantAnchor       string
+		wantThreadAnchor string
+		wantPin          bool
+		wantBody         []string
+		wantHTML         []string
+	}{
+		{
+			name:      "announcement",
+			eventType: EventTypeAnnounced,
+			payload: `{"class_id":"cse-3a","date":"2024-10-14","matrix_room_id":"!a:example.org","mentions":{"room":true},"slots":[
+				{"slot_index":2,"course_code":"MA201","start_time":"10:00","end_time":"10:50","venue":"LHC-3","status":"cancelled"},
+				{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50","venue":"LHC-2","status":"scheduled"}]}`,
+			wantAnchor: "DailyTimetableAnnounced:cse-3a:2024-10-14",
+			wantPin:    true,
+			wantBody:   []string{"1. CS301 (09:00–09:50 UTC) @ LHC-2", "2. MA201 (10:00–10:50 UTC) @ LHC-3"},
+			wantHTML:   []string{"<table>", "<del>MA201</del>"},
+		},
+		{
+			name:       "announcement anchor normalizes the date",
+			eventType:  EventTypeAnnounced,
+			payload:    `{"class_id":" cse-3a ","date":"14/10/2024","matrix_room_id":"!a:example.org","template":"Monday <b>plan</b>","slots":[{"slot_index":1,"course_code":"CS301","start_time":"9:00 am","end_time":"9:50 am"}]}`,
+			wantAnchor: "DailyTimetableAnnounced:cse-3a:2024-10-14",
+			wantPin:    true,
+			wantBody:   []string{"Monday <b>plan</b>", "1. CS301 (09:00–09:50 UTC)"},
+			wantHTML:   []string{"<strong>Monday &lt;b&gt;plan&lt;/b&gt;</strong>"},
+		},
+		{
+			name:             "update threads under the announcement",
+			eventType:        EventTypeUpdated,
+			payload:          `{"class_id":"cse-3a","date":"2024-10-14","matrix_room_id":"!a:example.org","updated_by":"hod","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50","venue":"LHC-4","changed":["venue"]}]}`,
+			wantThreadAnchor: "DailyTimetableAnnounced:cse-3a:2024-10-14",
+			wantBody:         []string{"1. CS301 (09:00–09:50 UTC) @ LHC-4"},
+			wantHTML:         []string{"<strong>LHC-4</strong>"},
+		},
+		{
+			name:             "update with an instant date",
+			eventType:        EventTypeUpdated,
+			payload:          `{"class_id":"cse-3a","date":"2024-10-14T08:00:00Z","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"}]}`,
+			wantThreadAnchor: "DailyTimetableAnnounced:cse-3a:2024-10-14",
+			wantBody:         []string{"1. CS301 (09:00–09:50 UTC)"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := r.Handle(context.Background(), tc.eventType, []byte(tc.payload))
+			if err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+			if msg.RoomID != "!a:example.org" {
+				t.Errorf("RoomID = %q", msg.RoomID)
+			}
+			if msg.Anchor != tc.wantAnchor || msg.ThreadAnchor != tc.wantThreadAnchor || msg.Pin != tc.wantPin {
+				t.Errorf("Anchor = %q, ThreadAnchor = %q, Pin = %t; want %q, %q, %t",
+					msg.Anchor, msg.ThreadAnchor, msg.Pin, tc.wantAnchor, tc.wantThreadAnchor, tc.wantPin)
+			}
+			if msg.Format != "html" {
+				t.Errorf("Format = %q, want html", msg.Format)
+			}
+			for _, want := range tc.wantBody {
+				if !strings.Contains(msg.Body, want) {
+					t.Errorf("Body = %q, want it to contain %q", msg.Body, want)
+				}
+			}
+			for _, want := range tc.wantHTML {
+				if !strings.Contains(msg.FormattedBody, want) {
+					t.Errorf("FormattedBody = %q, want it to contain %q", msg.FormattedBody, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimetableRenderSortsSlots(t *testing.T) {
+	r := testRegistry(t, Options{})
+	msg, err := r.Handle(context.Background(), EventTypeAnnounced, []byte(`{"date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[
+		{"slot_index":2,"course_code":"MA201","start_time":"10:00","end_time":"10:50"},
+		{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"}]}`))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if strings.Index(msg.Body, "CS301") > strings.Index(msg.Body, "MA201") {
+		t.Errorf("Body = %q, want slots in time order", msg.Body)
+	}
+}
+
+func TestTimetableRejects(t *testing.T) {
+	cases := []struct {
+		name      string
+		opts      Options
+		eventType string
+		payload   string
+		wantErr   string
+	}{
+		{
+			name:      "missing room",
+			eventType: EventTypeAnnounced,
+			payload:   `{"date":"2024-10-14","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"}]}`,
+			wantErr:   "/matrix_room_id: is required",
+		},
+		{
+			name:      "unparseable date",
+			eventType: EventTypeUpdated,
+			payload:   `{"date":"Monday","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"}]}`,
+			wantErr:   "/date: is not a recognised date",
+		},
+		{
+			name:      "unparseable time",
+			eventType: EventTypeAnnounced,
+			payload:   `{"date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"nine","end_time":"09:50"}]}`,
+			wantErr:   "/slots/0/start_time: is not a recognised time",
+		},
+		{
+			name:      "hard anomaly when rejecting",
+			opts:      Options{RejectAnomalies: true},
+			eventType: EventTypeAnnounced,
+			payload:   `{"date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"10:00","end_time":"09:50"}]}`,
+			wantErr:   "/slots/0/end_time",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testRegistry(t, tc.opts).Handle(context.Background(), tc.eventType, []byte(tc.payload))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Handle error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
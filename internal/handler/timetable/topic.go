@@ -0,0 +1,34 @@
+package timetable
+
+import (
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/slotstatus"
+)
+
+// summarizeTopic renders sorted slots as a one-line room topic such as
+// "Today: CS301 09:00, MA201 10:00". Cancelled slots are left out.
+func summarizeTopic(loc *i18n.Localizer, day time.Time, hasDay bool, slots []Slot) string {
+	entries := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		if slotstatus.IsCancelled(slot.Status) {
+			continue
+		}
+		entry := safeText(slot.CourseCode)
+		if start, ok := parseSlotTime(slot.StartTime, day, loc.Location()); ok {
+			entry += " " + loc.FormatClock(start.t)
+		}
+		entries = append(entries, entry)
+	}
+	summary := strings.Join(entries, ", ")
+	if summary == "" {
+		summary = loc.T("timetable.topic.empty")
+	}
+
+	if !hasDay || sameDay(time.Now().In(loc.Location()), day) {
+		return loc.T("timetable.topic.today", summary)
+	}
+	return loc.T("timetable.topic.date", loc.FormatShortDate(day), summary)
+}
@@ -0,0 +1,67 @@
+package timetable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func TestSummarizeTopic(t *testing.T) {
+	kolkata := mustZone(t, "Asia/Kolkata")
+	loc := i18n.Default().Localizer("en").In(kolkata)
+	now := time.Now().In(kolkata)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, kolkata)
+	other := time.Date(2024, 10, 14, 0, 0, 0, 0, kolkata)
+	cancelled := newSlot(2, "10:00", "10:50")
+	cancelled.CourseCode = "MA201"
+	cancelled.Status = "cancelled"
+	cs301 := newSlot(1, "09:00", "09:50")
+	cs301.CourseCode = "CS301"
+	lab := newSlot(3, "2024-10-14T08:30:00Z", "2024-10-14T10:30:00Z")
+	lab.CourseCode = "Lab"
+
+	cases := []struct {
+		name   string
+		day    time.Time
+		hasDay bool
+		slots  []Slot
+		want   string
+	}{
+		{name: "today", day: today, hasDay: true, slots: []Slot{cs301, cancelled}, want: "Today: CS301 09:00"},
+		{name: "another day", day: other, hasDay: true, slots: []Slot{cs301, cancelled, lab}, want: "Mon 14 Oct: CS301 09:00, Lab 14:00"},
+		{name: "no date", slots: []Slot{cs301}, want: "Today: CS301 09:00"},
+		{name: "everything cancelled", day: other, hasDay: true, slots: []Slot{cancelled}, want: "Mon 14 Oct: no classes"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := summarizeTopic(loc, tc.day, tc.hasDay, tc.slots); got != tc.want {
+				t.Errorf("summarizeTopic = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTopicOnlyFromAnnouncements(t *testing.T) {
+	r := testRegistry(t, Options{})
+	cases := []struct {
+		eventType string
+		want      string
+	}{
+		{EventTypeAnnounced, "Mon 14 Oct: CS301 09:00, MA201 10:00"},
+		{EventTypeUpdated, ""},
+	}
+	const payload = `{"class_id":"cse-3a","date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[
+		{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"},
+		{"slot_index":2,"course_code":"MA201","start_time":"10:00","end_time":"10:50"}]}`
+	for _, tc := range cases {
+		msg, err := r.Handle(context.Background(), tc.eventType, []byte(payload))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.eventType, err)
+		}
+		if msg.Topic != tc.want {
+			t.Errorf("%s topic = %q, want %q", tc.eventType, msg.Topic, tc.want)
+		}
+	}
+}
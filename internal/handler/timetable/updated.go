@@ -0,0 +1,50 @@
+package timetable
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"adapter-matrix/internal/handler"
+)
+
+const EventTypeUpdated = "TimetableUpdated"
+
+type UpdatedPayload struct {
+	ClassID        string           `json:"class_id"`
+	Date           string           `json:"date"`
+	MatrixRoomID   string           `json:"matrix_room_id"`
+	Locale         string           `json:"locale"`
+	UpdateTemplate string           `json:"update_template"`
+	Slots          []Slot           `json:"slots"`
+	Mentions       handler.Mentions `json:"mentions"`
+	UpdatedBy      string           `json:"updated_by"`
+}
+
+func newUpdatedHandler(rd *renderer) handler.Handler {
+	return handler.Typed[UpdatedPayload]{
+		Type: EventTypeUpdated,
+		Validate: func(ctx context.Context, payload UpdatedPayload) error {
+			return rd.validate(ctx, payload.MatrixRoomID, payload.Locale, payload.Date, payload.Slots)
+		},
+		Render: func(ctx context.Context, payload UpdatedPayload) (handler.Message, error) {
+			msg := rd.render(ctx, payload.MatrixRoomID, payload.Locale, View{
+				EventType: EventTypeUpdated,
+				ClassID:   payload.ClassID,
+				Date:      payload.Date,
+				Title:     payload.UpdateTemplate,
+				UpdatedBy: payload.UpdatedBy,
+				Slots:     payload.Slots,
+			})
+			msg.Mentions = payload.Mentions
+			msg.ThreadAnchor = announcementAnchor(payload.ClassID, payload.Date)
+			return msg, nil
+		},
+		Route: func(payload UpdatedPayload) (string, error) {
+			if strings.TrimSpace(payload.MatrixRoomID) == "" {
+				return "", errors.New("timetable update missing matrix_room_id")
+			}
+			return payload.MatrixRoomID, nil
+		},
+	}
+}
@@ -0,0 +1,265 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed locales/*.json
+var files embed.FS
+
+// DefaultLocale is used when neither the payload nor the room picks one.
+const DefaultLocale = "en"
+
+type catalog struct {
+	Locale     string            `json:"locale"`
+	Name       string            `json:"name"`
+	DateLayout string            `json:"date_layout"`
+	Weekdays   []string          `json:"weekdays"`
+	Months     []string          `json:"months"`
+	Messages   map[string]string `json:"messages"`
+}
+
+// Bundle holds every embedded message catalog.
+type Bundle struct {
+	catalogs map[string]*catalog
+}
+
+var defaultBundle = mustLoad()
+
+func Default() *Bundle {
+	return defaultBundle
+}
+
+func mustLoad() *Bundle {
+	b, err := load(files)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func load(fsys fs.FS) (*Bundle, error) {
+	names, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("list embedded catalogs: %w", err)
+	}
+	b := &Bundle{catalogs: make(map[string]*catalog, len(names))}
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read catalog %s: %w", name, err)
+		}
+		var c catalog
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("parse catalog %s: %w", name, err)
+		}
+		if c.Locale == "" || len(c.Weekdays) != 7 || len(c.Months) != 12 {
+			return nil, fmt.Errorf("catalog %s must set locale, 7 weekdays and 12 months", name)
+		}
+		b.catalogs[c.Locale] = &c
+	}
+	if _, ok := b.catalogs[DefaultLocale]; !ok {
+		return nil, errors.New("default catalog is missing")
+	}
+	return b, nil
+}
+
+// Locales lists the locales that have a catalog.
+func (b *Bundle) Locales() []string {
+	out := make([]string, 0, len(b.catalogs))
+	for locale := range b.catalogs {
+		out = append(out, locale)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Supported reports whether locale (or its base language) has a catalog.
+func (b *Bundle) Supported(locale string) bool {
+	_, ok := b.match(locale)
+	return ok
+}
+
+// Localizer returns a localizer for locale, falling back to the base
+// language ("ta" for "ta-IN") and then to English.
+func (b *Bundle) Localizer(locale string) *Localizer {
+	c, ok := b.match(locale)
+	if !ok {
+		c = b.catalogs[DefaultLocale]
+	}
+	return &Localizer{catalog: c, fallback: b.catalogs[DefaultLocale], location: time.UTC}
+}
+
+func (b *Bundle) match(locale string) (*catalog, bool) {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if normalized == "" {
+		return nil, false
+	}
+	if c, ok := b.catalogs[normalized]; ok {
+		return c, true
+	}
+	if base, _, found := strings.Cut(normalized, "-"); found {
+		if c, ok := b.catalogs[base]; ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Localizer renders strings, dates and times for one locale and timezone.
+type Localizer struct {
+	catalog  *catalog
+	fallback *catalog
+	location *time.Location
+}
+
+// Locale returns the catalog locale actually in use.
+func (l *Localizer) Locale() string {
+	return l.catalog.Locale
+}
+
+// Location returns the timezone times are rendered in.
+func (l *Localizer) Location() *time.Location {
+	return l.location
+}
+
+// In returns a copy of l that renders times in loc.
+func (l *Localizer) In(loc *time.Location) *Localizer {
+	if loc == nil {
+		return l
+	}
+	out := *l
+	out.location = loc
+	return &out
+}
+
+// T looks up key and formats it with args, falling back to English and then
+// to the key itself.
+func (l *Localizer) T(key string, args ...any) string {
+	msg, ok := l.catalog.Messages[key]
+	if !ok {
+		msg, ok = l.fallback.Messages[key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// FormatDate renders t's calendar date in l's timezone using the catalog's
+// date layout.
+func (l *Localizer) FormatDate(t time.Time) string {
+	t = t.In(l.location)
+	r := strings.NewReplacer(
+		"{weekday}", l.catalog.Weekdays[int(t.Weekday())],
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.catalog.Months[int(t.Month())-1],
+		"{year}", strconv.Itoa(t.Year()),
+	)
+	return r.Replace(l.catalog.DateLayout)
+}
+
+// FormatShortDate renders t as weekday, day and month without the year.
+func (l *Localizer) FormatShortDate(t time.Time) string {
+	t = t.In(l.location)
+	return l.catalog.Weekdays[int(t.Weekday())] + " " + strconv.Itoa(t.Day()) + " " + l.catalog.Months[int(t.Month())-1]
+}
+
+// FormatClock renders t as a 24-hour HH:MM time in l's timezone.
+func (l *Localizer) FormatClock(t time.Time) string {
+	return t.In(l.location).Format("15:04")
+}
+
+// ZoneAbbrev returns the timezone abbreviation in effect at t (e.g. IST).
+func (l *Localizer) ZoneAbbrev(t time.Time) string {
+	return t.In(l.location).Format("MST")
+}
+
+// RoomPreferences are a room's rendering overrides; empty fields defer to
+// the adapter defaults.
+type RoomPreferences struct {
+	Locale   string
+	Timezone string
+}
+
+// RoomPreferencesFunc returns the configured preferences for a room.
+type RoomPreferencesFunc func(ctx context.Context, roomID string) (RoomPreferences, error)
+
+// Resolver picks the locale and timezone for a message. The locale is the
+// payload's requested locale, then the room setting, then the configured
+// default; the timezone is the room setting, then the configured default.
+type Resolver struct {
+	bundle          *Bundle
+	roomPreferences RoomPreferencesFunc
+	defaultLocale   string
+	defaultZone     *time.Location
+	logger          *log.Logger
+}
+
+func NewResolver(bundle *Bundle, roomPreferences RoomPreferencesFunc, defaultLocale string, defaultZone *time.Location, logger *log.Logger) (*Resolver, error) {
+	if bundle == nil {
+		return nil, errors.New("bundle is required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	if !bundle.Supported(defaultLocale) {
+		return nil, fmt.Errorf("default locale %q has no catalog", defaultLocale)
+	}
+	if defaultZone == nil {
+		defaultZone = time.UTC
+	}
+	return &Resolver{
+		bundle:          bundle,
+		roomPreferences: roomPreferences,
+		defaultLocale:   defaultLocale,
+		defaultZone:     defaultZone,
+		logger:          logger,
+	}, nil
+}
+
+func (r *Resolver) Localizer(ctx context.Context, roomID, requested string) *Localizer {
+	var prefs RoomPreferences
+	if r.roomPreferences != nil && roomID != "" {
+		var err error
+		prefs, err = r.roomPreferences(ctx, roomID)
+		if err != nil {
+			r.logger.Printf("i18n: room preferences lookup failed for %s: %v", roomID, err)
+		}
+	}
+
+	locale := r.defaultLocale
+	if r.bundle.Supported(requested) {
+		locale = requested
+	} else if r.bundle.Supported(prefs.Locale) {
+		locale = prefs.Locale
+	}
+
+	zone := r.defaultZone
+	if name := strings.TrimSpace(prefs.Timezone); name != "" {
+		loaded, err := time.LoadLocation(name)
+		if err != nil {
+			r.logger.Printf("i18n: room %s has invalid timezone %q: %v", roomID, name, err)
+		} else {
+			zone = loaded
+		}
+	}
+
+	return r.bundle.Localizer(locale).In(zone)
+}
@@ -0,0 +1,161 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBundleLocalizerFallback(t *testing.T) {
+	cases := []struct {
+		requested string
+		want      string
+	}{
+		{"ta", "ta"},
+		{"ta-IN", "ta"},
+		{" TA_in ", "ta"},
+		{"hi-IN", "hi"},
+		{"en-GB", "en"},
+		{"fr-FR", "en"},
+		{"", "en"},
+	}
+	for _, tc := range cases {
+		if got := Default().Localizer(tc.requested).Locale(); got != tc.want {
+			t.Errorf("Localizer(%q).Locale() = %q, want %q", tc.requested, got, tc.want)
+		}
+	}
+}
+
+func TestTFallsBackToEnglish(t *testing.T) {
+	catalog := func(locale, messages string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`{"locale":"` + locale + `","date_layout":"{day}",
+			"weekdays":["1","2","3","4","5","6","7"],
+			"months":["1","2","3","4","5","6","7","8","9","10","11","12"],
+			"messages":` + messages + `}`)}
+	}
+	b, err := load(fstest.MapFS{
+		"locales/en.json": catalog("en", `{"greeting":"Hello %s","only.en":"English only"}`),
+		"locales/ta.json": catalog("ta", `{"greeting":"வணக்கம் %s"}`),
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	loc := b.Localizer("ta-IN")
+	if got := loc.T("greeting", "Ana"); got != "வணக்கம் Ana" {
+		t.Errorf("T(greeting) = %q, want the Tamil message", got)
+	}
+	if got := loc.T("only.en"); got != "English only" {
+		t.Errorf("T(only.en) = %q, want the English message", got)
+	}
+	if got := loc.T("not.defined"); got != "not.defined" {
+		t.Errorf("T(not.defined) = %q, want the key", got)
+	}
+
+	if _, err := load(fstest.MapFS{"locales/ta.json": catalog("ta", `{}`)}); err == nil {
+		t.Error("load accepted catalogs without the English default")
+	}
+}
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	en := Default().catalogs[DefaultLocale]
+	for _, locale := range Default().Locales() {
+		c := Default().catalogs[locale]
+		for key, msg := range en.Messages {
+			translated, ok := c.Messages[key]
+			if !ok {
+				t.Errorf("%s is missing %q", locale, key)
+				continue
+			}
+			if strings.Count(translated, "%") != strings.Count(msg, "%") {
+				t.Errorf("%s %q = %q, want the same verbs as %q", locale, key, translated, msg)
+			}
+		}
+		for key := range c.Messages {
+			if _, ok := en.Messages[key]; !ok {
+				t.Errorf("%s has %q, which English lacks", locale, key)
+			}
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 20:00 UTC on Sunday is already Monday in India.
+	at := time.Date(2024, 10, 13, 20, 0, 0, 0, time.UTC)
+	cases := []struct {
+		locale string
+		zone   *time.Location
+		want   string
+		short  string
+	}{
+		{"en", time.UTC, "Sun 13 Oct 2024", "Sun 13 Oct"},
+		{"en", kolkata, "Mon 14 Oct 2024", "Mon 14 Oct"},
+		{"ta-IN", kolkata, "திங்கள், 14 அக்டோபர் 2024", "திங்கள் 14 அக்டோபர்"},
+		{"hi", kolkata, "सोमवार, 14 अक्टूबर 2024", "सोमवार 14 अक्टूबर"},
+	}
+	for _, tc := range cases {
+		loc := Default().Localizer(tc.locale).In(tc.zone)
+		if got := loc.FormatDate(at); got != tc.want {
+			t.Errorf("%s in %s: FormatDate = %q, want %q", tc.locale, tc.zone, got, tc.want)
+		}
+		if got := loc.FormatShortDate(at); got != tc.short {
+			t.Errorf("%s in %s: FormatShortDate = %q, want %q", tc.locale, tc.zone, got, tc.short)
+		}
+	}
+	loc := Default().Localizer("ta").In(kolkata)
+	if got := loc.FormatClock(at) + " " + loc.ZoneAbbrev(at); got != "01:30 IST" {
+		t.Errorf("clock = %q, want 01:30 IST", got)
+	}
+}
+
+func TestResolverLocalizer(t *testing.T) {
+	prefs := map[string]RoomPreferences{
+		"!ta:example.org":  {Locale: "ta", Timezone: "Asia/Kolkata"},
+		"!bad:example.org": {Locale: "xx", Timezone: "Mars/Olympus"},
+	}
+	lookup := func(_ context.Context, roomID string) (RoomPreferences, error) {
+		if roomID == "!down:example.org" {
+			return RoomPreferences{}, errors.New("database unavailable")
+		}
+		return prefs[roomID], nil
+	}
+	r, err := NewResolver(Default(), lookup, "hi", time.UTC, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		roomID    string
+		requested string
+		want      string
+		wantZone  string
+	}{
+		{name: "room setting", roomID: "!ta:example.org", want: "ta", wantZone: "Asia/Kolkata"},
+		{name: "payload wins over the room", roomID: "!ta:example.org", requested: "en-IN", want: "en", wantZone: "Asia/Kolkata"},
+		{name: "unsupported payload locale", roomID: "!ta:example.org", requested: "fr", want: "ta", wantZone: "Asia/Kolkata"},
+		{name: "invalid room settings", roomID: "!bad:example.org", want: "hi", wantZone: "UTC"},
+		{name: "lookup failure", roomID: "!down:example.org", want: "hi", wantZone: "UTC"},
+		{name: "no room", want: "hi", wantZone: "UTC"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc := r.Localizer(context.Background(), tc.roomID, tc.requested)
+			if loc.Locale() != tc.want || loc.Location().String() != tc.wantZone {
+				t.Errorf("Localizer = %s in %s, want %s in %s", loc.Locale(), loc.Location(), tc.want, tc.wantZone)
+			}
+		})
+	}
+
+	if _, err := NewResolver(Default(), nil, "fr", nil, log.New(io.Discard, "", 0)); err == nil {
+		t.Error("NewResolver accepted a default locale without a catalog")
+	}
+}
@@ -0,0 +1,72 @@
+// Package inbound turns events seen in Matrix sync into adapter state and
+// outbox events for CR45.
+package inbound
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+type acknowledgementStore interface {
+	DeliveredEventByMatrixID(ctx context.Context, matrixEventID string) (repository.DeliveredEvent, bool, error)
+	EmitMessageAcknowledged(ctx context.Context, reactionEventID string, ack events.MessageAcknowledged) (bool, error)
+}
+
+type userDirectory interface {
+	CR45UserIDs(ctx context.Context, matrixUserIDs []string) (map[string]string, error)
+}
+
+// Acknowledgements reports reactions to adapter-sent messages as
+// MessageAcknowledged events.
+type Acknowledgements struct {
+	repo   acknowledgementStore
+	users  userDirectory
+	logger *log.Logger
+}
+
+func NewAcknowledgements(repo *repository.AdapterStateRepository, users *repository.UserDirectoryRepository, logger *log.Logger) (*Acknowledgements, error) {
+	if repo == nil || users == nil {
+		return nil, errors.New("state repository and user directory are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	return &Acknowledgements{repo: repo, users: users, logger: logger}, nil
+}
+
+// HandleReaction emits MessageAcknowledged when reaction targets a message
+// the adapter delivered. Reactions to anything else are ignored.
+func (a *Acknowledgements) HandleReaction(ctx context.Context, reaction matrix.Reaction) {
+	delivered, found, err := a.repo.DeliveredEventByMatrixID(ctx, reaction.TargetEventID)
+	if err != nil {
+		a.logger.Printf("inbound: look up reacted event %s: %v", reaction.TargetEventID, err)
+		return
+	}
+	if !found || delivered.RoomID != reaction.RoomID {
+		return
+	}
+
+	cr45IDs, err := a.users.CR45UserIDs(ctx, []string{reaction.Sender})
+	if err != nil {
+		a.logger.Printf("inbound: map %s to a CR45 user: %v", reaction.Sender, err)
+		return
+	}
+	_, err = a.repo.EmitMessageAcknowledged(ctx, reaction.EventID, events.MessageAcknowledged{
+		OriginalEventID: delivered.EventID,
+		Adapter:         "adapter-matrix",
+		MatrixRoomID:    reaction.RoomID,
+		MatrixEventID:   reaction.TargetEventID,
+		MatrixUserID:    reaction.Sender,
+		CR45UserID:      cr45IDs[reaction.Sender],
+		ReactionKey:     reaction.Key,
+		ReactedAt:       reaction.At.UTC(),
+	})
+	if err != nil {
+		a.logger.Printf("inbound: emit acknowledgement for %s: %v", reaction.EventID, err)
+	}
+}
@@ -0,0 +1,108 @@
+package inbound
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+type fakeDirectory map[string]string
+
+func (f fakeDirectory) CR45UserIDs(_ context.Context, matrixUserIDs []string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, userID := range matrixUserIDs {
+		if id, ok := f[userID]; ok {
+			out[userID] = id
+		}
+	}
+	return out, nil
+}
+
+type fakeAcknowledgementStore struct {
+	delivered map[string]repository.DeliveredEvent
+	seen      map[string]bool
+	emitted   []events.MessageAcknowledged
+}
+
+func (f *fakeAcknowledgementStore) DeliveredEventByMatrixID(_ context.Context, matrixEventID string) (repository.DeliveredEvent, bool, error) {
+	d, ok := f.delivered[matrixEventID]
+	return d, ok, nil
+}
+
+func (f *fakeAcknowledgementStore) EmitMessageAcknowledged(_ context.Context, reactionEventID string, ack events.MessageAcknowledged) (bool, error) {
+	if f.seen[reactionEventID] {
+		return false, nil
+	}
+	f.seen[reactionEventID] = true
+	f.emitted = append(f.emitted, ack)
+	return true, nil
+}
+
+func TestHandleReaction(t *testing.T) {
+	at := time.Date(2024, 10, 14, 9, 5, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	cases := []struct {
+		name     string
+		reaction matrix.Reaction
+		want     *events.MessageAcknowledged
+	}{
+		{
+			name:     "reaction to a delivered part",
+			reaction: matrix.Reaction{RoomID: "!a:example.org", EventID: "$r1", TargetEventID: "$part2", Sender: "@ana:example.org", Key: "👍", At: at},
+			want: &events.MessageAcknowledged{
+				OriginalEventID: "original",
+				Adapter:         "adapter-matrix",
+				MatrixRoomID:    "!a:example.org",
+				MatrixEventID:   "$part2",
+				MatrixUserID:    "@ana:example.org",
+				CR45UserID:      "cr45-ana",
+				ReactionKey:     "👍",
+				ReactedAt:       at.UTC(),
+			},
+		},
+		{
+			name:     "unmapped user",
+			reaction: matrix.Reaction{RoomID: "!a:example.org", EventID: "$r2", TargetEventID: "$part1", Sender: "@guest:example.org", Key: "✅", At: at},
+			want: &events.MessageAcknowledged{
+				OriginalEventID: "original",
+				Adapter:         "adapter-matrix",
+				MatrixRoomID:    "!a:example.org",
+				MatrixEventID:   "$part1",
+				MatrixUserID:    "@guest:example.org",
+				ReactionKey:     "✅",
+				ReactedAt:       at.UTC(),
+			},
+		},
+		{name: "message the adapter did not send", reaction: matrix.Reaction{RoomID: "!a:example.org", EventID: "$r3", TargetEventID: "$someone-else", Sender: "@ana:example.org", Key: "👍", At: at}},
+		{name: "event ID from another room", reaction: matrix.Reaction{RoomID: "!b:example.org", EventID: "$r4", TargetEventID: "$part1", Sender: "@ana:example.org", Key: "👍", At: at}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeAcknowledgementStore{
+				delivered: map[string]repository.DeliveredEvent{
+					"$part1": {EventID: "original", Part: 1, RoomID: "!a:example.org", MatrixEventID: "$part1"},
+					"$part2": {EventID: "original", Part: 2, RoomID: "!a:example.org", MatrixEventID: "$part2"},
+				},
+				seen: make(map[string]bool),
+			}
+			a := &Acknowledgements{repo: store, users: fakeDirectory{"@ana:example.org": "cr45-ana"}, logger: log.New(io.Discard, "", 0)}
+			a.HandleReaction(context.Background(), tc.reaction)
+			a.HandleReaction(context.Background(), tc.reaction)
+
+			if tc.want == nil {
+				if len(store.emitted) != 0 {
+					t.Errorf("emitted %+v, want nothing", store.emitted)
+				}
+				return
+			}
+			if len(store.emitted) != 1 || store.emitted[0] != *tc.want {
+				t.Errorf("emitted %+v, want %+v once", store.emitted, *tc.want)
+			}
+		})
+	}
+}
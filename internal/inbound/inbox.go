@@ -0,0 +1,102 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+type inboxStore interface {
+	Insert(ctx context.Context, msg events.MessageReceived) (bool, error)
+}
+
+// Inbox bridges room messages from allow-listed rooms into the inbox table
+// as MessageReceived events.
+type Inbox struct {
+	repo      inboxStore
+	users     userDirectory
+	rooms     map[string]struct{}
+	msgTypes  map[string]struct{}
+	isCommand func(matrix.IncomingMessage) bool
+	logger    *log.Logger
+}
+
+// NewInbox returns an inbox for messages of msgTypes ("text", "m.emote",
+// ...) in roomIDs. Messages for which isCommand reports true are bot
+// commands and are left out; isCommand may be nil.
+func NewInbox(repo *repository.InboxRepository, users *repository.UserDirectoryRepository, roomIDs, msgTypes []string, isCommand func(matrix.IncomingMessage) bool, logger *log.Logger) (*Inbox, error) {
+	if repo == nil || users == nil {
+		return nil, errors.New("inbox repository and user directory are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	if len(roomIDs) == 0 || len(msgTypes) == 0 {
+		return nil, errors.New("inbox rooms and message types are required")
+	}
+	in := &Inbox{
+		repo:      repo,
+		users:     users,
+		rooms:     make(map[string]struct{}, len(roomIDs)),
+		msgTypes:  make(map[string]struct{}, len(msgTypes)),
+		isCommand: isCommand,
+		logger:    logger,
+	}
+	for _, roomID := range roomIDs {
+		in.rooms[strings.TrimSpace(roomID)] = struct{}{}
+	}
+	for _, msgType := range msgTypes {
+		in.msgTypes[normalizeMsgType(msgType)] = struct{}{}
+	}
+	return in, nil
+}
+
+// HandleMessage writes msg to the inbox if its room and type are selected.
+// Bot commands and messages already in the inbox, e.g. replayed by sync, are
+// skipped.
+func (in *Inbox) HandleMessage(ctx context.Context, msg matrix.IncomingMessage) {
+	if _, ok := in.rooms[msg.RoomID]; !ok {
+		return
+	}
+	if _, ok := in.msgTypes[normalizeMsgType(msg.MsgType)]; !ok {
+		return
+	}
+	if in.isCommand != nil && in.isCommand(msg) {
+		return
+	}
+
+	cr45IDs, err := in.users.CR45UserIDs(ctx, []string{msg.Sender})
+	if err != nil {
+		in.logger.Printf("inbound: map %s to a CR45 user: %v", msg.Sender, err)
+		return
+	}
+	received := events.MessageReceived{
+		Adapter:       "adapter-matrix",
+		MatrixRoomID:  msg.RoomID,
+		MatrixEventID: msg.EventID,
+		MatrixUserID:  msg.Sender,
+		CR45UserID:    cr45IDs[msg.Sender],
+		MsgType:       msg.MsgType,
+		Body:          msg.Body,
+		SentAt:        msg.At.UTC(),
+	}
+	if msg.ThreadRootID != "" || msg.ReplyToID != "" || msg.ReplacesID != "" {
+		received.Relation = &events.MessageRelation{
+			ThreadRootEventID: msg.ThreadRootID,
+			InReplyToEventID:  msg.ReplyToID,
+			ReplacesEventID:   msg.ReplacesID,
+		}
+	}
+	if _, err := in.repo.Insert(ctx, received); err != nil {
+		in.logger.Printf("inbound: write message %s to inbox: %v", msg.EventID, err)
+	}
+}
+
+func normalizeMsgType(value string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "m.")
+}
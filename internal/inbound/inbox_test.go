@@ -0,0 +1,88 @@
+package inbound
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+)
+
+type fakeInboxStore struct {
+	rows map[string]events.MessageReceived
+}
+
+func (f *fakeInboxStore) Insert(_ context.Context, msg events.MessageReceived) (bool, error) {
+	if _, ok := f.rows[msg.MatrixEventID]; ok {
+		return false, nil
+	}
+	f.rows[msg.MatrixEventID] = msg
+	return true, nil
+}
+
+func TestInboxHandleMessage(t *testing.T) {
+	const room = "!class:example.org"
+	at := time.Date(2024, 10, 14, 9, 30, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	isCommand := func(msg matrix.IncomingMessage) bool { return strings.HasPrefix(msg.Body, "!help") }
+
+	cases := []struct {
+		name string
+		msg  matrix.IncomingMessage
+		want bool
+	}{
+		{name: "text", msg: matrix.IncomingMessage{RoomID: room, MsgType: "m.text", Body: "Is CS301 on?"}, want: true},
+		{name: "other room", msg: matrix.IncomingMessage{RoomID: "!other:example.org", MsgType: "m.text", Body: "hi"}},
+		{name: "type not selected", msg: matrix.IncomingMessage{RoomID: room, MsgType: "m.notice", Body: "hi"}},
+		{name: "emote", msg: matrix.IncomingMessage{RoomID: room, MsgType: "m.emote", Body: "waves"}, want: true},
+		{name: "command", msg: matrix.IncomingMessage{RoomID: room, MsgType: "m.text", Body: "!help"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeInboxStore{rows: make(map[string]events.MessageReceived)}
+			in := &Inbox{
+				repo:      store,
+				users:     fakeDirectory{"@ana:example.org": "cr45-ana"},
+				rooms:     map[string]struct{}{room: {}},
+				msgTypes:  map[string]struct{}{"text": {}, "emote": {}},
+				isCommand: isCommand,
+				logger:    log.New(io.Discard, "", 0),
+			}
+			tc.msg.EventID, tc.msg.Sender, tc.msg.At = "$1", "@ana:example.org", at
+			in.HandleMessage(context.Background(), tc.msg)
+
+			got, ok := store.rows["$1"]
+			if ok != tc.want {
+				t.Fatalf("bridged = %t, want %t", ok, tc.want)
+			}
+			if ok && (got.CR45UserID != "cr45-ana" || got.Body != tc.msg.Body || !got.SentAt.Equal(at) || got.SentAt.Location() != time.UTC || got.Relation != nil) {
+				t.Errorf("row = %+v", got)
+			}
+		})
+	}
+}
+
+func TestInboxRelation(t *testing.T) {
+	store := &fakeInboxStore{rows: make(map[string]events.MessageReceived)}
+	in := &Inbox{
+		repo:     store,
+		users:    fakeDirectory{},
+		rooms:    map[string]struct{}{"!class:example.org": {}},
+		msgTypes: map[string]struct{}{"text": {}},
+		logger:   log.New(io.Discard, "", 0),
+	}
+	msg := matrix.IncomingMessage{RoomID: "!class:example.org", EventID: "$2", Sender: "@ben:example.org", MsgType: "m.text", Body: "!help", ThreadRootID: "$root", ReplacesID: "$1"}
+	in.HandleMessage(context.Background(), msg)
+	in.HandleMessage(context.Background(), msg)
+
+	got, ok := store.rows["$2"]
+	if !ok || len(store.rows) != 1 {
+		t.Fatalf("rows = %+v, want the message once (no command filter set)", store.rows)
+	}
+	if got.CR45UserID != "" || got.Relation == nil || got.Relation.ThreadRootEventID != "$root" || got.Relation.ReplacesEventID != "$1" || got.Relation.InReplyToEventID != "" {
+		t.Errorf("row = %+v, relation %+v", got, got.Relation)
+	}
+}
@@ -0,0 +1,155 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+// receiptStore is the part of repository.ReceiptRepository Receipts uses.
+type receiptStore interface {
+	RecordOriginTime(ctx context.Context, matrixEventID string, at time.Time) error
+	DeliveredAt(ctx context.Context, roomID, matrixEventID string) (time.Time, bool, error)
+	HasUnreadDeliveries(ctx context.Context, roomID, userID string) (bool, error)
+	RecordReadUpTo(ctx context.Context, roomID, userID string, upTo, readAt time.Time) error
+	RecordRead(ctx context.Context, matrixEventID, userID string, readAt time.Time) error
+	PendingSummaries(ctx context.Context) ([]repository.ReadSummary, error)
+	EmitSummary(ctx context.Context, summary events.ReadReceiptSummary) error
+}
+
+type eventTimer interface {
+	EventTime(ctx context.Context, roomID, eventID string) (time.Time, error)
+}
+
+// Receipts tracks read receipts on adapter-sent messages and reports them as
+// ReadReceiptSummary events.
+type Receipts struct {
+	repo   receiptStore
+	users  userDirectory
+	matrix eventTimer
+	logger *log.Logger
+}
+
+func NewReceipts(repo *repository.ReceiptRepository, users *repository.UserDirectoryRepository, matrixClient *matrix.Client, logger *log.Logger) (*Receipts, error) {
+	if repo == nil || users == nil || matrixClient == nil {
+		return nil, errors.New("receipt repository, user directory and matrix client are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	return &Receipts{repo: repo, users: users, matrix: matrixClient, logger: logger}, nil
+}
+
+// HandleSentEvent records the homeserver time of one of the adapter's own
+// events, which read positions are compared against.
+func (r *Receipts) HandleSentEvent(ctx context.Context, sent matrix.SentEvent) {
+	if err := r.repo.RecordOriginTime(ctx, sent.EventID, sent.At); err != nil {
+		r.logger.Printf("inbound: record origin time of %s: %v", sent.EventID, err)
+	}
+}
+
+// HandleReadReceipt marks the adapter's messages covered by receipt as read.
+// A main-timeline receipt covers every message up to the receipted event; a
+// thread receipt only the receipted event itself.
+func (r *Receipts) HandleReadReceipt(ctx context.Context, receipt matrix.ReadReceipt) {
+	if receipt.Threaded {
+		if err := r.repo.RecordRead(ctx, receipt.EventID, receipt.UserID, receipt.At); err != nil {
+			r.logger.Printf("inbound: record read of %s: %v", receipt.EventID, err)
+		}
+		return
+	}
+
+	upTo, ok, err := r.eventTime(ctx, receipt)
+	if err != nil {
+		r.logger.Printf("inbound: resolve receipt event %s: %v", receipt.EventID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := r.repo.RecordReadUpTo(ctx, receipt.RoomID, receipt.UserID, upTo, receipt.At); err != nil {
+		r.logger.Printf("inbound: record reads in %s: %v", receipt.RoomID, err)
+	}
+}
+
+// eventTime returns the receipted event's origin_server_ts. The adapter's
+// own events are looked up in the delivery records. Other events are only
+// fetched from the homeserver when the room has deliveries newer than the
+// user's read position; otherwise ok is false, as the receipt marks nothing
+// new as read.
+func (r *Receipts) eventTime(ctx context.Context, receipt matrix.ReadReceipt) (time.Time, bool, error) {
+	at, found, err := r.repo.DeliveredAt(ctx, receipt.RoomID, receipt.EventID)
+	if err != nil || found {
+		return at, found, err
+	}
+
+	unread, err := r.repo.HasUnreadDeliveries(ctx, receipt.RoomID, receipt.UserID)
+	if err != nil || !unread {
+		return time.Time{}, false, err
+	}
+	at, err = r.matrix.EventTime(ctx, receipt.RoomID, receipt.EventID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return at, true, nil
+}
+
+// Run emits summaries every interval until ctx is done.
+func (r *Receipts) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.EmitSummaries(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			r.logger.Printf("inbound: emit read receipt summaries failed: %v", err)
+		}
+	}
+}
+
+// EmitSummaries writes a ReadReceiptSummary for every message with reads
+// not yet reported. Each summary lists all readers so far.
+func (r *Receipts) EmitSummaries(ctx context.Context) error {
+	pending, err := r.repo.PendingSummaries(ctx)
+	if err != nil {
+		return err
+	}
+	for _, summary := range pending {
+		userIDs := make([]string, 0, len(summary.Readers))
+		for _, reader := range summary.Readers {
+			userIDs = append(userIDs, reader.MatrixUserID)
+		}
+		cr45IDs, err := r.users.CR45UserIDs(ctx, userIDs)
+		if err != nil {
+			return err
+		}
+
+		out := events.ReadReceiptSummary{
+			OriginalEventID: summary.EventID,
+			Adapter:         "adapter-matrix",
+			MatrixRoomID:    summary.RoomID,
+			MatrixEventID:   summary.MatrixEventID,
+			ReadCount:       len(summary.Readers),
+			Readers:         make([]events.ReadReport, 0, len(summary.Readers)),
+		}
+		for _, reader := range summary.Readers {
+			out.Readers = append(out.Readers, events.ReadReport{
+				MatrixUserID: reader.MatrixUserID,
+				CR45UserID:   cr45IDs[reader.MatrixUserID],
+				ReadAt:       reader.ReadAt.UTC(),
+			})
+		}
+		if err := r.repo.EmitSummary(ctx, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package inbound
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+type fakeReceiptStore struct {
+	delivered map[string]time.Time
+	unread    bool
+	origin    map[string]time.Time
+	calls     []string
+	pending   []repository.ReadSummary
+	emitted   []events.ReadReceiptSummary
+}
+
+func (f *fakeReceiptStore) RecordOriginTime(_ context.Context, matrixEventID string, at time.Time) error {
+	f.origin[matrixEventID] = at
+	return nil
+}
+
+func (f *fakeReceiptStore) DeliveredAt(_ context.Context, roomID, matrixEventID string) (time.Time, bool, error) {
+	at, ok := f.delivered[roomID+"/"+matrixEventID]
+	return at, ok, nil
+}
+
+func (f *fakeReceiptStore) HasUnreadDeliveries(context.Context, string, string) (bool, error) {
+	return f.unread, nil
+}
+
+func (f *fakeReceiptStore) RecordReadUpTo(_ context.Context, roomID, userID string, upTo, _ time.Time) error {
+	f.calls = append(f.calls, fmt.Sprintf("up to %s by %s in %s", upTo.Format(time.TimeOnly), userID, roomID))
+	return nil
+}
+
+func (f *fakeReceiptStore) RecordRead(_ context.Context, matrixEventID, userID string, _ time.Time) error {
+	f.calls = append(f.calls, fmt.Sprintf("read %s by %s", matrixEventID, userID))
+	return nil
+}
+
+func (f *fakeReceiptStore) PendingSummaries(context.Context) ([]repository.ReadSummary, error) {
+	return f.pending, nil
+}
+
+func (f *fakeReceiptStore) EmitSummary(_ context.Context, summary events.ReadReceiptSummary) error {
+	f.emitted = append(f.emitted, summary)
+	return nil
+}
+
+type fakeEventTimer struct {
+	at      time.Time
+	fetched int
+}
+
+func (f *fakeEventTimer) EventTime(context.Context, string, string) (time.Time, error) {
+	f.fetched++
+	return f.at, nil
+}
+
+func TestHandleReadReceipt(t *testing.T) {
+	sent := time.Date(2024, 10, 14, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		receipt     matrix.ReadReceipt
+		unread      bool
+		wantCalls   []string
+		wantFetched int
+	}{
+		{
+			name:      "thread receipt",
+			receipt:   matrix.ReadReceipt{RoomID: "!a:example.org", EventID: "$thread-reply", UserID: "@ana:example.org", Threaded: true},
+			wantCalls: []string{"read $thread-reply by @ana:example.org"},
+		},
+		{
+			name:      "receipt on an adapter message",
+			receipt:   matrix.ReadReceipt{RoomID: "!a:example.org", EventID: "$ours", UserID: "@ana:example.org"},
+			wantCalls: []string{"up to 09:00:00 by @ana:example.org in !a:example.org"},
+		},
+		{
+			name:    "adapter message seen from another room",
+			receipt: matrix.ReadReceipt{RoomID: "!b:example.org", EventID: "$ours", UserID: "@ana:example.org"},
+		},
+		{
+			name:    "other message with nothing unread",
+			receipt: matrix.ReadReceipt{RoomID: "!a:example.org", EventID: "$theirs", UserID: "@ana:example.org"},
+		},
+		{
+			name:        "other message with unread deliveries",
+			receipt:     matrix.ReadReceipt{RoomID: "!a:example.org", EventID: "$theirs", UserID: "@ana:example.org"},
+			unread:      true,
+			wantCalls:   []string{"up to 09:30:00 by @ana:example.org in !a:example.org"},
+			wantFetched: 1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeReceiptStore{delivered: map[string]time.Time{"!a:example.org/$ours": sent}, unread: tc.unread}
+			timer := &fakeEventTimer{at: sent.Add(30 * time.Minute)}
+			r := &Receipts{repo: store, users: fakeDirectory{}, matrix: timer, logger: log.New(io.Discard, "", 0)}
+			r.HandleReadReceipt(context.Background(), tc.receipt)
+			if fmt.Sprint(store.calls) != fmt.Sprint(tc.wantCalls) {
+				t.Errorf("calls = %v, want %v", store.calls, tc.wantCalls)
+			}
+			if timer.fetched != tc.wantFetched {
+				t.Errorf("fetched %d event times, want %d", timer.fetched, tc.wantFetched)
+			}
+		})
+	}
+}
+
+func TestHandleSentEvent(t *testing.T) {
+	store := &fakeReceiptStore{origin: map[string]time.Time{}}
+	r := &Receipts{repo: store, users: fakeDirectory{}, matrix: &fakeEventTimer{}, logger: log.New(io.Discard, "", 0)}
+	at := time.Date(2024, 10, 14, 9, 0, 0, 0, time.UTC)
+	r.HandleSentEvent(context.Background(), matrix.SentEvent{RoomID: "!a:example.org", EventID: "$ours", At: at})
+	if got := store.origin["$ours"]; !got.Equal(at) {
+		t.Errorf("origin time = %s, want %s", got, at)
+	}
+}
+
+func TestEmitSummaries(t *testing.T) {
+	readAt := time.Date(2024, 10, 14, 9, 0, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	store := &fakeReceiptStore{pending: []repository.ReadSummary{{
+		EventID:       "2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11",
+		RoomID:        "!a:example.org",
+		MatrixEventID: "$ours",
+		Readers:       []repository.Reader{{MatrixUserID: "@ana:example.org", ReadAt: readAt}, {MatrixUserID: "@ben:example.org", ReadAt: readAt}},
+	}}}
+	r := &Receipts{repo: store, users: fakeDirectory{"@ana:example.org": "cr45-ana"}, matrix: &fakeEventTimer{}, logger: log.New(io.Discard, "", 0)}
+	if err := r.EmitSummaries(context.Background()); err != nil {
+		t.Fatalf("EmitSummaries: %v", err)
+	}
+	if len(store.emitted) != 1 {
+		t.Fatalf("emitted %d summaries, want 1", len(store.emitted))
+	}
+	got := store.emitted[0]
+	if got.OriginalEventID != "2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11" || got.MatrixEventID != "$ours" || got.ReadCount != 2 || got.Adapter != "adapter-matrix" {
+		t.Errorf("summary = %+v", got)
+	}
+	if got.Readers[0].CR45UserID != "cr45-ana" || got.Readers[1].CR45UserID != "" {
+		t.Errorf("readers = %+v, want only @ana mapped", got.Readers)
+	}
+	if got.Readers[0].ReadAt.Location() != time.UTC {
+		t.Errorf("read_at = %s, want UTC", got.Readers[0].ReadAt)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
@@ -14,9 +15,12 @@ import (
 
 type Client struct {
 	client       *mautrix.Client
+	sync         *mautrix.DefaultSyncer
 	allowedRooms map[string]struct{}
 	joinedRooms  map[string]struct{}
 	mu           sync.RWMutex
+	uploadSize   int64
+	uploadSizeAt time.Time
 	logger       *log.Logger
 }
 
@@ -53,17 +57,54 @@ func NewClient(homeserverURL, userID, accessToken string, allowedRooms []string,
 
 	c := &Client{
 		client:       cli,
+		sync:         cli.Syncer.(*mautrix.DefaultSyncer),
 		allowedRooms: allowed,
 		joinedRooms:  make(map[string]struct{}),
 		logger:       logger,
 	}
 
-	syncer := cli.Syncer.(*mautrix.DefaultSyncer)
-	syncer.OnEventType(event.StateMember, c.handleMemberEvent)
+	c.syncer().OnEventType(event.StateMember, c.handleMemberEvent)
 
 	return c, nil
 }
 
+// BatchSyncStore is a SyncStore that holds each next_batch token until
+// CommitNextBatch, which the client calls once the batch has been handled.
+type BatchSyncStore interface {
+	mautrix.SyncStore
+	CommitNextBatch(ctx context.Context, userID id.UserID) error
+}
+
+// UseStore makes the client keep its sync filter and next_batch token in
+// syncStore and cache room state in stateStore, so StartSync resumes where
+// the last run stopped. Call it before StartSync.
+func (c *Client) UseStore(syncStore BatchSyncStore, stateStore mautrix.StateStore) {
+	c.client.Store = syncStore
+	c.client.StateStore = stateStore
+	c.syncer().OnEvent(c.client.StateStoreSyncHandler)
+	c.client.Syncer = &committingSyncer{DefaultSyncer: c.sync, store: syncStore, userID: c.client.UserID, logger: c.logger}
+}
+
+// committingSyncer commits the next_batch token after every handler has
+// seen the batch. A failed commit is logged and only means the batch is
+// handled again after a restart.
+type committingSyncer struct {
+	*mautrix.DefaultSyncer
+	store  BatchSyncStore
+	userID id.UserID
+	logger *log.Logger
+}
+
+func (s *committingSyncer) ProcessResponse(ctx context.Context, resp *mautrix.RespSync, since string) error {
+	if err := s.DefaultSyncer.ProcessResponse(ctx, resp, since); err != nil {
+		return err
+	}
+	if err := s.store.CommitNextBatch(ctx, s.userID); err != nil {
+		s.logger.Printf("matrix: commit sync token: %v", err)
+	}
+	return nil
+}
+
 func (c *Client) StartSync(ctx context.Context) error {
 	if err := c.loadJoinedRooms(ctx); err != nil {
 		return err
@@ -71,28 +112,100 @@ func (c *Client) StartSync(ctx context.Context) error {
 	return c.client.SyncWithContext(ctx)
 }
 
-func (c *Client) SendMessage(ctx context.Context, roomID, body, format string) error {
+// Message is an outgoing text message. FormattedBody defaults to Body for
+// markdown and html formats when empty; MsgType defaults to MsgTypeText.
+// A non-empty ThreadRootID sends the message in that thread, replying to
+// ReplyToID (the root when empty).
+type Message struct {
+	Body          string
+	FormattedBody string
+	Format        string
+	MsgType       string
+	Mentions      *Mentions
+	ThreadRootID  string
+	ReplyToID     string
+}
+
+// Message types the adapter can send, as accepted in payloads.
+const (
+	MsgTypeText   = "text"
+	MsgTypeNotice = "notice"
+	MsgTypeEmote  = "emote"
+)
+
+var msgTypes = map[string]event.MessageType{
+	MsgTypeText:   event.MsgText,
+	MsgTypeNotice: event.MsgNotice,
+	MsgTypeEmote:  event.MsgEmote,
+}
+
+// NormalizeMsgType maps "notice", "m.notice" or "Notice" to MsgTypeNotice
+// (and likewise for text and emote). ok is false for anything else.
+func NormalizeMsgType(value string) (string, bool) {
+	key := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "m.")
+	_, ok := msgTypes[key]
+	return key, ok
+}
+
+// SendMessage sends msg as a single event and returns its event ID. Callers
+// with potentially large bodies should split them with SplitMessage first.
+func (c *Client) SendMessage(ctx context.Context, roomID string, msg Message) (string, error) {
 	if roomID == "" {
-		return errors.New("room ID is required")
+		return "", errors.New("room ID is required")
 	}
 	if err := c.ensureJoined(ctx, roomID); err != nil {
-		return err
+		return "", err
+	}
+
+	resp, err := c.client.SendMessageEvent(ctx, id.RoomID(roomID), event.EventMessage, buildContent(msg))
+	if err != nil {
+		return "", err
+	}
+	return resp.EventID.String(), nil
+}
+
+// Redact redacts eventID in roomID with an optional reason and returns the
+// redaction's event ID.
+func (c *Client) Redact(ctx context.Context, roomID, eventID, reason string) (string, error) {
+	if roomID == "" || eventID == "" {
+		return "", errors.New("room ID and event ID are required")
 	}
+	resp, err := c.client.RedactEvent(ctx, id.RoomID(roomID), id.EventID(eventID), mautrix.ReqRedact{Reason: reason})
+	if err != nil {
+		return "", err
+	}
+	return resp.EventID.String(), nil
+}
 
-	content := event.MessageEventContent{
-		MsgType: event.MsgText,
-		Body:    body,
+func buildContent(msg Message) *event.MessageEventContent {
+	msgType, ok := msgTypes[msg.MsgType]
+	if !ok {
+		msgType = event.MsgText
+	}
+	content := &event.MessageEventContent{
+		MsgType:  msgType,
+		Body:     msg.Body,
+		Mentions: buildMentions(msg.Mentions),
 	}
-	if format == "html" {
+	formatted := msg.FormattedBody
+	if formatted == "" {
+		formatted = msg.Body
+	}
+	if msg.Format == "html" {
 		content.Format = event.FormatHTML
-		content.FormattedBody = body
-	} else if format == "markdown" {
+		content.FormattedBody = formatted
+	} else if msg.Format == "markdown" {
 		content.Format = "org.matrix.custom.markdown"
-		content.FormattedBody = body
+		content.FormattedBody = formatted
 	}
-
-	_, err := c.client.SendMessageEvent(ctx, id.RoomID(roomID), event.EventMessage, content)
-	return err
+	if msg.ThreadRootID != "" {
+		replyTo := id.EventID(msg.ReplyToID)
+		if replyTo == "" {
+			replyTo = id.EventID(msg.ThreadRootID)
+		}
+		content.RelatesTo = (&event.RelatesTo{}).SetThread(id.EventID(msg.ThreadRootID), replyTo)
+	}
+	return content
 }
 
 func (c *Client) handleMemberEvent(ctx context.Context, evt *event.Event) {
@@ -0,0 +1,62 @@
+package matrix
+
+import (
+	"testing"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+func TestNormalizeMsgType(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    string
+		wantOK  bool
+		content event.MessageType
+	}{
+		{value: "text", want: MsgTypeText, wantOK: true, content: event.MsgText},
+		{value: "m.notice", want: MsgTypeNotice, wantOK: true, content: event.MsgNotice},
+		{value: " M.Emote ", want: MsgTypeEmote, wantOK: true, content: event.MsgEmote},
+		{value: "m.image", want: "image"},
+		{value: "", want: ""},
+	}
+	for _, tc := range cases {
+		got, ok := NormalizeMsgType(tc.value)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("NormalizeMsgType(%q) = %q, %t; want %q, %t", tc.value, got, ok, tc.want, tc.wantOK)
+		}
+		if !ok {
+			continue
+		}
+		if content := buildContent(Message{Body: "hi", MsgType: got}); content.MsgType != tc.content {
+			t.Errorf("buildContent msgtype for %q = %s, want %s", tc.value, content.MsgType, tc.content)
+		}
+	}
+}
+
+func TestBuildContentThread(t *testing.T) {
+	cases := []struct {
+		name        string
+		msg         Message
+		wantThread  id.EventID
+		wantReplyTo id.EventID
+	}{
+		{name: "standalone", msg: Message{Body: "hi"}},
+		{name: "thread reply falls back to the root", msg: Message{Body: "hi", ThreadRootID: "$root"}, wantThread: "$root", wantReplyTo: "$root"},
+		{name: "thread reply to a later message", msg: Message{Body: "hi", ThreadRootID: "$root", ReplyToID: "$update"}, wantThread: "$root", wantReplyTo: "$update"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rel := buildContent(tc.msg).RelatesTo
+			if tc.wantThread == "" {
+				if rel != nil {
+					t.Fatalf("RelatesTo = %+v, want none", rel)
+				}
+				return
+			}
+			if rel == nil || rel.Type != event.RelThread || rel.EventID != tc.wantThread || !rel.IsFallingBack || rel.InReplyTo == nil || rel.InReplyTo.EventID != tc.wantReplyTo {
+				t.Errorf("RelatesTo = %+v, want thread %s replying to %s", rel, tc.wantThread, tc.wantReplyTo)
+			}
+		})
+	}
+}
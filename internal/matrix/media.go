@@ -0,0 +1,203 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	mediaConfigTTL  = time.Hour
+	thumbnailWidth  = 800
+	thumbnailHeight = 600
+)
+
+// ErrMediaTooLarge is returned when an attachment exceeds the homeserver's
+// m.upload.size. Retrying cannot help.
+var ErrMediaTooLarge = errors.New("attachment exceeds the homeserver upload limit")
+
+// Attachment is a file to upload to the media repository. Images that decode
+// are sent as m.image with dimensions and a thumbnail; anything else as
+// m.file.
+type Attachment struct {
+	FileName string
+	MimeType string
+	Data     []byte
+}
+
+// SendAttachment uploads a and sends it, in the thread rooted at threadRootID
+// when set, and returns the event ID.
+func (c *Client) SendAttachment(ctx context.Context, roomID string, a Attachment, threadRootID string) (string, error) {
+	if roomID == "" {
+		return "", errors.New("room ID is required")
+	}
+	limit, err := c.uploadLimit(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := checkUploadSize(a, limit); err != nil {
+		return "", err
+	}
+	if err := c.ensureJoined(ctx, roomID); err != nil {
+		return "", err
+	}
+
+	upload, err := c.client.UploadBytesWithName(ctx, a.Data, a.MimeType, a.FileName)
+	if err != nil {
+		return "", err
+	}
+	content := &event.MessageEventContent{
+		MsgType:  event.MsgFile,
+		Body:     a.FileName,
+		FileName: a.FileName,
+		URL:      upload.ContentURI.CUString(),
+		Info: &event.FileInfo{
+			MimeType: a.MimeType,
+			Size:     len(a.Data),
+		},
+	}
+	if strings.HasPrefix(a.MimeType, "image/") {
+		if img, _, err := image.Decode(bytes.NewReader(a.Data)); err == nil {
+			content.MsgType = event.MsgImage
+			content.Info.Width = img.Bounds().Dx()
+			content.Info.Height = img.Bounds().Dy()
+			c.attachThumbnail(ctx, content, img, a, limit)
+		}
+	}
+	if threadRootID != "" {
+		root := id.EventID(threadRootID)
+		content.RelatesTo = (&event.RelatesTo{}).SetThread(root, root)
+	}
+
+	resp, err := c.client.SendMessageEvent(ctx, id.RoomID(roomID), event.EventMessage, content)
+	if err != nil {
+		return "", err
+	}
+	return resp.EventID.String(), nil
+}
+
+// CheckUploadSize returns ErrMediaTooLarge when a exceeds the homeserver's
+// m.upload.size, so callers can reject a message before sending any of it.
+func (c *Client) CheckUploadSize(ctx context.Context, a Attachment) error {
+	limit, err := c.uploadLimit(ctx)
+	if err != nil {
+		return err
+	}
+	return checkUploadSize(a, limit)
+}
+
+func checkUploadSize(a Attachment, limit int64) error {
+	if limit > 0 && int64(len(a.Data)) > limit {
+		return fmt.Errorf("%w: %s is %d bytes, limit %d", ErrMediaTooLarge, a.FileName, len(a.Data), limit)
+	}
+	return nil
+}
+
+// attachThumbnail uploads a downscaled copy of images larger than the
+// thumbnail bounds. A failed thumbnail only costs the preview, so it is
+// logged and the image is sent without one.
+func (c *Client) attachThumbnail(ctx context.Context, content *event.MessageEventContent, img image.Image, a Attachment, limit int64) {
+	bounds := img.Bounds()
+	if bounds.Dx() <= thumbnailWidth && bounds.Dy() <= thumbnailHeight {
+		return
+	}
+	thumb := downscale(img, thumbnailWidth, thumbnailHeight)
+
+	var buf bytes.Buffer
+	mimeType := "image/jpeg"
+	var err error
+	if a.MimeType == "image/png" || a.MimeType == "image/gif" {
+		mimeType = "image/png"
+		err = png.Encode(&buf, thumb)
+	} else {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		c.logger.Printf("matrix: thumbnail for %s failed: %v", a.FileName, err)
+		return
+	}
+	if limit > 0 && int64(buf.Len()) > limit {
+		return
+	}
+	upload, err := c.client.UploadBytes(ctx, buf.Bytes(), mimeType)
+	if err != nil {
+		c.logger.Printf("matrix: thumbnail upload for %s failed: %v", a.FileName, err)
+		return
+	}
+	content.Info.ThumbnailURL = upload.ContentURI.CUString()
+	content.Info.ThumbnailInfo = &event.FileInfo{
+		MimeType: mimeType,
+		Width:    thumb.Bounds().Dx(),
+		Height:   thumb.Bounds().Dy(),
+		Size:     buf.Len(),
+	}
+}
+
+// uploadLimit returns the homeserver's m.upload.size (0 when it sets none),
+// cached for mediaConfigTTL.
+func (c *Client) uploadLimit(ctx context.Context) (int64, error) {
+	c.mu.RLock()
+	limit, fetched := c.uploadSize, c.uploadSizeAt
+	c.mu.RUnlock()
+	if !fetched.IsZero() && time.Since(fetched) < mediaConfigTTL {
+		return limit, nil
+	}
+
+	cfg, err := c.client.GetMediaConfig(ctx)
+	if err != nil {
+		if !fetched.IsZero() {
+			c.logger.Printf("matrix: media config refresh failed, keeping previous limit: %v", err)
+			return limit, nil
+		}
+		return 0, err
+	}
+	c.mu.Lock()
+	c.uploadSize, c.uploadSizeAt = cfg.UploadSize, time.Now()
+	c.mu.Unlock()
+	return cfg.UploadSize, nil
+}
+
+// downscale fits img within maxW×maxH, averaging the source pixels that
+// fall into each destination pixel.
+func downscale(img image.Image, maxW, maxH int) image.Image {
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	if w*maxH > h*maxW {
+		h = max(1, h*maxW/w)
+		w = maxW
+	} else {
+		w = max(1, w*maxH/h)
+		h = maxH
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/w)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
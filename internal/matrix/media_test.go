@@ -0,0 +1,64 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckUploadSize(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		status   int
+		size     int
+		wantErr  bool
+		tooLarge bool
+	}{
+		{name: "under the limit", config: `{"m.upload.size":10}`, size: 10},
+		{name: "over the limit", config: `{"m.upload.size":10}`, size: 11, wantErr: true, tooLarge: true},
+		{name: "no limit", config: `{}`, size: 1 << 20},
+		{name: "config unavailable", status: http.StatusInternalServerError, size: 1, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/_matrix/client/v1/media/config" {
+					http.NotFound(w, r)
+					return
+				}
+				requests++
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					_, _ = io.WriteString(w, `{"errcode":"M_UNKNOWN"}`)
+					return
+				}
+				_, _ = io.WriteString(w, tc.config)
+			}))
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "@bot:example.org", "token", nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			a := Attachment{FileName: "notes.pdf", MimeType: "application/pdf", Data: make([]byte, tc.size)}
+			for range 2 {
+				err = c.CheckUploadSize(context.Background(), a)
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("CheckUploadSize error = %v, want error %t", err, tc.wantErr)
+				}
+				if errors.Is(err, ErrMediaTooLarge) != tc.tooLarge {
+					t.Fatalf("CheckUploadSize error = %v, want ErrMediaTooLarge %t", err, tc.tooLarge)
+				}
+			}
+			if tc.status == 0 && requests != 1 {
+				t.Errorf("media config fetched %d times, want it cached after the first", requests)
+			}
+		})
+	}
+}
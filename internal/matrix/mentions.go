@@ -0,0 +1,140 @@
+package matrix
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"strings"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/format"
+	"maunium.net/go/mautrix/id"
+)
+
+// Mentions are the users (Matrix IDs) and @room ping a message intends to
+// notify. They are sent as m.mentions and rendered as pills.
+type Mentions struct {
+	UserIDs []string
+	Room    bool
+}
+
+func (m *Mentions) empty() bool {
+	return m == nil || (len(m.UserIDs) == 0 && !m.Room)
+}
+
+// ApplyMentions prepares msg.Mentions for sending to roomID: invalid user
+// IDs are dropped, @room is dropped when the bot lacks the notifications.room
+// power level, and the remaining mentions are prepended to the body as text
+// and to formatted_body as pills. Plain and markdown messages with mentions
+// are converted to HTML so the pills render.
+func (c *Client) ApplyMentions(ctx context.Context, roomID string, msg Message) Message {
+	if msg.Mentions.empty() {
+		return msg
+	}
+
+	mentions := &Mentions{}
+	seen := make(map[string]struct{}, len(msg.Mentions.UserIDs))
+	for _, userID := range msg.Mentions.UserIDs {
+		trimmed := strings.TrimSpace(userID)
+		if _, _, err := id.UserID(trimmed).Parse(); err != nil {
+			c.logger.Printf("matrix: dropping invalid mention %q: %v", userID, err)
+			continue
+		}
+		if _, dup := seen[trimmed]; dup {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		mentions.UserIDs = append(mentions.UserIDs, trimmed)
+	}
+	if msg.Mentions.Room {
+		ok, err := c.hasPowerLevel(ctx, roomID, func(pl *event.PowerLevelsEventContent) int {
+			return pl.Notifications.Room()
+		})
+		switch {
+		case err != nil:
+			c.logger.Printf("matrix: dropping @room for %s, power levels unavailable: %v", roomID, err)
+		case !ok:
+			c.logger.Printf("matrix: dropping @room for %s, bot lacks notifications.room power level", roomID)
+		default:
+			mentions.Room = true
+		}
+	}
+	if mentions.empty() {
+		msg.Mentions = nil
+		return msg
+	}
+
+	var plain, pills []string
+	if mentions.Room {
+		plain = append(plain, "@room")
+		pills = append(pills, "@room")
+	}
+	for _, userID := range mentions.UserIDs {
+		plain = append(plain, userID)
+		pills = append(pills, `<a href="https://matrix.to/#/`+url.PathEscape(userID)+`">`+html.EscapeString(userID)+`</a>`)
+	}
+
+	msg.FormattedBody = "<p>" + strings.Join(pills, " ") + "</p>\n" + formattedHTML(msg)
+	msg.Format = "html"
+	msg.Body = strings.Join(plain, " ") + "\n" + msg.Body
+	msg.Mentions = mentions
+	return msg
+}
+
+// formattedHTML returns msg's formatted body as HTML: plain text is escaped
+// and markdown rendered.
+func formattedHTML(msg Message) string {
+	switch msg.Format {
+	case "html":
+		if msg.FormattedBody != "" {
+			return msg.FormattedBody
+		}
+		return escapeText(msg.Body)
+	case "markdown":
+		source := msg.FormattedBody
+		if source == "" {
+			source = msg.Body
+		}
+		// RenderMarkdown leaves FormattedBody empty when the source has no
+		// markup.
+		if rendered := format.RenderMarkdown(source, true, false).FormattedBody; rendered != "" {
+			return rendered
+		}
+		return escapeText(source)
+	default:
+		return escapeText(msg.Body)
+	}
+}
+
+func escapeText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// hasPowerLevel reports whether the bot's power level in roomID is at least
+// the level required returns for the room's power levels.
+func (c *Client) hasPowerLevel(ctx context.Context, roomID string, required func(*event.PowerLevelsEventContent) int) (bool, error) {
+	pl, err := c.powerLevels(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	return pl.GetUserLevel(c.client.UserID) >= required(pl), nil
+}
+
+func (c *Client) powerLevels(ctx context.Context, roomID string) (*event.PowerLevelsEventContent, error) {
+	var pl event.PowerLevelsEventContent
+	if err := c.client.StateEvent(ctx, id.RoomID(roomID), event.StatePowerLevels, "", &pl); err != nil {
+		return nil, err
+	}
+	return &pl, nil
+}
+
+func buildMentions(m *Mentions) *event.Mentions {
+	if m.empty() {
+		return nil
+	}
+	out := &event.Mentions{Room: m.Room}
+	for _, userID := range m.UserIDs {
+		out.Add(id.UserID(userID))
+	}
+	return out
+}
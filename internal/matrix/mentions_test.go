@@ -0,0 +1,79 @@
+package matrix
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestApplyMentionsPills(t *testing.T) {
+	c := &Client{logger: log.New(io.Discard, "", 0)}
+	pill := `<a href="https://matrix.to/#/@ana:example.org">@ana:example.org</a>`
+
+	cases := []struct {
+		name          string
+		msg           Message
+		wantBody      string
+		wantFormatted string
+	}{
+		{
+			name:          "plain text is escaped",
+			msg:           Message{Body: "Lab moved <today>\nSee you", Format: "plain"},
+			wantBody:      "@ana:example.org\nLab moved <today>\nSee you",
+			wantFormatted: "<p>" + pill + "</p>\nLab moved &lt;today&gt;<br>See you",
+		},
+		{
+			name:          "markdown is rendered",
+			msg:           Message{Body: "**Exam** moved", Format: "markdown"},
+			wantBody:      "@ana:example.org\n**Exam** moved",
+			wantFormatted: "<p>" + pill + "</p>\n<strong>Exam</strong> moved",
+		},
+		{
+			name:          "markdown without markup is escaped",
+			msg:           Message{Body: "Exam moved", Format: "markdown"},
+			wantBody:      "@ana:example.org\nExam moved",
+			wantFormatted: "<p>" + pill + "</p>\nExam moved",
+		},
+		{
+			name:          "html keeps its formatted body",
+			msg:           Message{Body: "Exam moved", FormattedBody: "<em>Exam</em> moved", Format: "html"},
+			wantBody:      "@ana:example.org\nExam moved",
+			wantFormatted: "<p>" + pill + "</p>\n<em>Exam</em> moved",
+		},
+		{
+			name:          "html without formatted body escapes the body",
+			msg:           Message{Body: "a < b", Format: "html"},
+			wantBody:      "@ana:example.org\na < b",
+			wantFormatted: "<p>" + pill + "</p>\na &lt; b",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.msg.Mentions = &Mentions{UserIDs: []string{"@ana:example.org", "not-a-user", "@ana:example.org"}}
+			got := c.ApplyMentions(context.Background(), "!room:example.org", tc.msg)
+			if got.Format != "html" {
+				t.Errorf("Format = %q, want html", got.Format)
+			}
+			if got.Body != tc.wantBody {
+				t.Errorf("Body = %q, want %q", got.Body, tc.wantBody)
+			}
+			if strings.TrimSpace(got.FormattedBody) != tc.wantFormatted {
+				t.Errorf("FormattedBody = %q, want %q", got.FormattedBody, tc.wantFormatted)
+			}
+			if got.Mentions == nil || len(got.Mentions.UserIDs) != 1 {
+				t.Errorf("Mentions = %+v, want only @ana:example.org", got.Mentions)
+			}
+		})
+	}
+}
+
+func TestApplyMentionsWithoutMentions(t *testing.T) {
+	c := &Client{logger: log.New(io.Discard, "", 0)}
+	msg := Message{Body: "Exam moved", Format: "plain", Mentions: &Mentions{UserIDs: []string{"bogus"}}}
+	got := c.ApplyMentions(context.Background(), "!room:example.org", msg)
+	if got.Format != "plain" || got.FormattedBody != "" || got.Mentions != nil {
+		t.Errorf("got %+v, want the message unchanged without mentions", got)
+	}
+}
@@ -0,0 +1,87 @@
+package matrix
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// IncomingMessage is an m.room.message seen in sync. ThreadRootID is set
+// when the message is part of a thread, ReplyToID when it replies to an
+// event and ReplacesID when it edits one.
+type IncomingMessage struct {
+	RoomID       string
+	EventID      string
+	Sender       string
+	MsgType      string
+	Body         string
+	ThreadRootID string
+	ReplyToID    string
+	ReplacesID   string
+	At           time.Time
+}
+
+// OnMessage calls fn for every room message seen in sync, except the bot's
+// own.
+func (c *Client) OnMessage(fn func(ctx context.Context, msg IncomingMessage)) {
+	c.syncer().OnEventType(event.EventMessage, func(ctx context.Context, evt *event.Event) {
+		if evt.Sender == c.client.UserID {
+			return
+		}
+		content, ok := evt.Content.Parsed.(*event.MessageEventContent)
+		if !ok {
+			return
+		}
+		msg := IncomingMessage{
+			RoomID:  evt.RoomID.String(),
+			EventID: evt.ID.String(),
+			Sender:  evt.Sender.String(),
+			MsgType: string(content.MsgType),
+			Body:    content.Body,
+			At:      time.UnixMilli(evt.Timestamp),
+		}
+		if rel := content.RelatesTo; rel != nil {
+			switch rel.Type {
+			case event.RelThread:
+				msg.ThreadRootID = rel.EventID.String()
+			case event.RelReplace:
+				msg.ReplacesID = rel.EventID.String()
+				if content.NewContent != nil {
+					msg.Body = content.NewContent.Body
+				}
+			}
+			if rel.InReplyTo != nil && !rel.IsFallingBack {
+				msg.ReplyToID = rel.InReplyTo.EventID.String()
+			}
+		}
+		fn(ctx, msg)
+	})
+}
+
+// Reply sends msg as a reply to to, in to's thread or in a new thread rooted
+// at to.
+func (c *Client) Reply(ctx context.Context, to IncomingMessage, msg Message) (string, error) {
+	msg.ThreadRootID = to.ThreadRootID
+	if msg.ThreadRootID == "" {
+		msg.ThreadRootID = to.EventID
+	}
+	msg.ReplyToID = to.EventID
+	return c.SendMessage(ctx, to.RoomID, msg)
+}
+
+// UserPowerLevel returns userID's power level in roomID.
+func (c *Client) UserPowerLevel(ctx context.Context, roomID, userID string) (int, error) {
+	pl, err := c.powerLevels(ctx, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return pl.GetUserLevel(id.UserID(userID)), nil
+}
+
+// Permalink returns a matrix.to link to eventID in roomID.
+func Permalink(roomID, eventID string) string {
+	return "https://matrix.to/#/" + url.PathEscape(roomID) + "/" + url.PathEscape(eventID)
+}
@@ -0,0 +1,190 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// Unstable MSC3381 content keys, which is what current clients send and
+// render.
+const (
+	pollStartKey = "org.matrix.msc3381.poll.start"
+	pollEndKey   = "org.matrix.msc3381.poll.end"
+	textKey      = "org.matrix.msc1767.text"
+
+	pollKindDisclosed   = "org.matrix.msc3381.poll.disclosed"
+	pollKindUndisclosed = "org.matrix.msc3381.poll.undisclosed"
+)
+
+// Poll is an outgoing MSC3381 poll. Fallback is shown by clients that do
+// not support polls.
+type Poll struct {
+	Question      string
+	Answers       []PollAnswer
+	Disclosed     bool
+	MaxSelections int
+	Fallback      string
+}
+
+type PollAnswer struct {
+	ID   string
+	Text string
+}
+
+// PollResponse is a vote seen in sync. Answers are the selected answer IDs
+// as sent, unvalidated.
+type PollResponse struct {
+	RoomID      string
+	EventID     string
+	PollEventID string
+	Sender      string
+	Answers     []string
+	At          time.Time
+}
+
+// PollEnd is an m.poll.end seen in sync.
+type PollEnd struct {
+	RoomID      string
+	EventID     string
+	PollEventID string
+	Sender      string
+	At          time.Time
+}
+
+type pollText struct {
+	Text string `json:"org.matrix.msc1767.text"`
+}
+
+type pollAnswerContent struct {
+	ID   string `json:"id"`
+	Text string `json:"org.matrix.msc1767.text"`
+}
+
+type pollStartContent struct {
+	Kind          string              `json:"kind"`
+	MaxSelections int                 `json:"max_selections"`
+	Question      pollText            `json:"question"`
+	Answers       []pollAnswerContent `json:"answers"`
+}
+
+// SendPoll starts p in roomID and returns the poll's event ID.
+func (c *Client) SendPoll(ctx context.Context, roomID string, p Poll) (string, error) {
+	if roomID == "" {
+		return "", errors.New("room ID is required")
+	}
+	if err := c.ensureJoined(ctx, roomID); err != nil {
+		return "", err
+	}
+
+	start := pollStartContent{
+		Kind:          pollKindUndisclosed,
+		MaxSelections: p.MaxSelections,
+		Question:      pollText{Text: p.Question},
+	}
+	if p.Disclosed {
+		start.Kind = pollKindDisclosed
+	}
+	for _, answer := range p.Answers {
+		start.Answers = append(start.Answers, pollAnswerContent{ID: answer.ID, Text: answer.Text})
+	}
+	content := map[string]any{
+		pollStartKey: start,
+		textKey:      p.Fallback,
+	}
+
+	resp, err := c.client.SendMessageEvent(ctx, id.RoomID(roomID), event.EventUnstablePollStart, content)
+	if err != nil {
+		return "", err
+	}
+	return resp.EventID.String(), nil
+}
+
+// SendPollEnd ends the poll pollEventID with a text summary of the results
+// and returns the end event's ID.
+func (c *Client) SendPollEnd(ctx context.Context, roomID, pollEventID, summary string) (string, error) {
+	content := map[string]any{
+		"m.relates_to": &event.RelatesTo{Type: event.RelReference, EventID: id.EventID(pollEventID)},
+		pollEndKey:     map[string]any{},
+		textKey:        summary,
+	}
+	resp, err := c.client.SendMessageEvent(ctx, id.RoomID(roomID), event.EventUnstablePollEnd, content)
+	if err != nil {
+		return "", err
+	}
+	return resp.EventID.String(), nil
+}
+
+// CanEndPoll reports whether userID may end polls it did not start in
+// roomID, which MSC3381 ties to the redaction power level.
+func (c *Client) CanEndPoll(ctx context.Context, roomID, userID string) (bool, error) {
+	pl, err := c.powerLevels(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	return pl.GetUserLevel(id.UserID(userID)) >= pl.Redact(), nil
+}
+
+// UserID returns the bot's Matrix user ID.
+func (c *Client) UserID() string {
+	return c.client.UserID.String()
+}
+
+// OnPollResponse calls fn for every poll response seen in sync.
+func (c *Client) OnPollResponse(fn func(ctx context.Context, resp PollResponse)) {
+	c.syncer().OnEventType(event.EventUnstablePollResponse, func(ctx context.Context, evt *event.Event) {
+		var content struct {
+			RelatesTo event.RelatesTo `json:"m.relates_to"`
+			Response  struct {
+				Answers []string `json:"answers"`
+			} `json:"org.matrix.msc3381.poll.response"`
+		}
+		if err := json.Unmarshal(evt.Content.VeryRaw, &content); err != nil {
+			c.logger.Printf("matrix: failed to parse poll response %s: %v", evt.ID, err)
+			return
+		}
+		if content.RelatesTo.Type != event.RelReference || content.RelatesTo.EventID == "" {
+			return
+		}
+		fn(ctx, PollResponse{
+			RoomID:      evt.RoomID.String(),
+			EventID:     evt.ID.String(),
+			PollEventID: content.RelatesTo.EventID.String(),
+			Sender:      evt.Sender.String(),
+			Answers:     content.Response.Answers,
+			At:          time.UnixMilli(evt.Timestamp),
+		})
+	})
+}
+
+// OnPollEnd calls fn for every poll end seen in sync.
+func (c *Client) OnPollEnd(fn func(ctx context.Context, end PollEnd)) {
+	c.syncer().OnEventType(event.EventUnstablePollEnd, func(ctx context.Context, evt *event.Event) {
+		var content struct {
+			RelatesTo event.RelatesTo `json:"m.relates_to"`
+		}
+		if err := json.Unmarshal(evt.Content.VeryRaw, &content); err != nil {
+			c.logger.Printf("matrix: failed to parse poll end %s: %v", evt.ID, err)
+			return
+		}
+		if content.RelatesTo.Type != event.RelReference || content.RelatesTo.EventID == "" {
+			return
+		}
+		fn(ctx, PollEnd{
+			RoomID:      evt.RoomID.String(),
+			EventID:     evt.ID.String(),
+			PollEventID: content.RelatesTo.EventID.String(),
+			Sender:      evt.Sender.String(),
+			At:          time.UnixMilli(evt.Timestamp),
+		})
+	})
+}
+
+func (c *Client) syncer() *mautrix.DefaultSyncer {
+	return c.sync
+}
@@ -0,0 +1,39 @@
+package matrix
+
+import (
+	"context"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+)
+
+// Reaction is an m.reaction annotation seen in sync.
+type Reaction struct {
+	RoomID        string
+	EventID       string
+	TargetEventID string
+	Sender        string
+	Key           string
+	At            time.Time
+}
+
+// OnReaction calls fn for every reaction seen in sync, except the bot's own.
+func (c *Client) OnReaction(fn func(ctx context.Context, reaction Reaction)) {
+	c.syncer().OnEventType(event.EventReaction, func(ctx context.Context, evt *event.Event) {
+		if evt.Sender == c.client.UserID {
+			return
+		}
+		content, ok := evt.Content.Parsed.(*event.ReactionEventContent)
+		if !ok || content.RelatesTo.Type != event.RelAnnotation || content.RelatesTo.EventID == "" {
+			return
+		}
+		fn(ctx, Reaction{
+			RoomID:        evt.RoomID.String(),
+			EventID:       evt.ID.String(),
+			TargetEventID: content.RelatesTo.EventID.String(),
+			Sender:        evt.Sender.String(),
+			Key:           content.RelatesTo.Key,
+			At:            time.UnixMilli(evt.Timestamp),
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package matrix
+
+import (
+	"context"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// ReadReceipt is a public m.read receipt seen in sync: UserID has read
+// EventID and, unless Threaded, everything before it in the room.
+type ReadReceipt struct {
+	RoomID   string
+	EventID  string
+	UserID   string
+	Threaded bool
+	At       time.Time
+}
+
+// OnReadReceipt calls fn for every public read receipt seen in sync, except
+// the bot's own.
+func (c *Client) OnReadReceipt(fn func(ctx context.Context, receipt ReadReceipt)) {
+	c.syncer().OnEventType(event.EphemeralEventReceipt, func(ctx context.Context, evt *event.Event) {
+		content, ok := evt.Content.Parsed.(*event.ReceiptEventContent)
+		if !ok {
+			return
+		}
+		for eventID, receipts := range *content {
+			for userID, receipt := range receipts[event.ReceiptTypeRead] {
+				if userID == c.client.UserID {
+					continue
+				}
+				fn(ctx, ReadReceipt{
+					RoomID:   evt.RoomID.String(),
+					EventID:  eventID.String(),
+					UserID:   userID.String(),
+					Threaded: receipt.ThreadID != "" && receipt.ThreadID != event.ReadReceiptThreadMain,
+					At:       receipt.Timestamp,
+				})
+			}
+		}
+	})
+}
+
+// SentEvent is one of the bot's own timeline events seen in sync.
+type SentEvent struct {
+	RoomID  string
+	EventID string
+	At      time.Time
+}
+
+// OnSentEvent calls fn for every timeline event the bot sent, with the
+// homeserver's origin_server_ts. Sync hands a room's timeline to listeners
+// before its receipts, so fn has run for an event before any receipt that
+// names it.
+func (c *Client) OnSentEvent(fn func(ctx context.Context, sent SentEvent)) {
+	c.syncer().OnEvent(func(ctx context.Context, evt *event.Event) {
+		if evt.Sender != c.client.UserID || evt.Mautrix.EventSource&event.SourceTimeline == 0 {
+			return
+		}
+		fn(ctx, SentEvent{
+			RoomID:  evt.RoomID.String(),
+			EventID: evt.ID.String(),
+			At:      time.UnixMilli(evt.Timestamp),
+		})
+	})
+}
+
+// EventTime returns the origin_server_ts of eventID.
+func (c *Client) EventTime(ctx context.Context, roomID, eventID string) (time.Time, error) {
+	evt, err := c.client.GetEvent(ctx, id.RoomID(roomID), id.EventID(eventID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(evt.Timestamp), nil
+}
@@ -0,0 +1,235 @@
+package matrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxContentBytes is the largest serialized message content the adapter
+// sends in one event. Matrix caps whole events at 65536 bytes; the rest is
+// headroom for the envelope, signatures and relation metadata.
+const MaxContentBytes = 60000
+
+var errLineTooLarge = errors.New("message contains a line larger than the event size limit")
+
+// SplitMessage splits msg into numbered parts whose serialized content fits
+// within maxBytes. Plain bodies are split at line boundaries. HTML is split
+// at line boundaries outside tables and at row boundaries inside a table
+// body, with each part re-opening the table and its header so it renders on
+// its own. Messages that already fit are returned unchanged.
+func SplitMessage(msg Message, maxBytes int) ([]Message, error) {
+	if contentSize(msg) <= maxBytes {
+		return []Message{msg}, nil
+	}
+
+	bodyLines := strings.Split(msg.Body, "\n")
+	var htmlUnits []htmlUnit
+	if msg.FormattedBody != "" {
+		htmlUnits = splitHTML(msg.FormattedBody)
+	}
+	maxParts := len(bodyLines)
+	if len(htmlUnits) > maxParts {
+		maxParts = len(htmlUnits)
+	}
+
+	start := contentSize(msg)/maxBytes + 1
+	for n := start; n <= maxParts; n++ {
+		parts := buildParts(msg, bodyLines, htmlUnits, n)
+		if parts == nil {
+			continue
+		}
+		fits := true
+		for _, part := range parts {
+			if contentSize(part) > maxBytes {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return parts, nil
+		}
+	}
+	return nil, errLineTooLarge
+}
+
+func buildParts(msg Message, bodyLines []string, htmlUnits []htmlUnit, n int) []Message {
+	bodyGroups := groupItems(bodyLines, func(line string) int { return len(line) + 1 }, n)
+	if bodyGroups == nil {
+		return nil
+	}
+	var htmlGroups [][]htmlUnit
+	if htmlUnits != nil {
+		htmlGroups = groupItems(htmlUnits, func(u htmlUnit) int { return len(u.text) + 1 }, n)
+		if htmlGroups == nil {
+			return nil
+		}
+	}
+
+	parts := make([]Message, n)
+	for i := range parts {
+		label := fmt.Sprintf("(%d/%d)", i+1, n)
+		part := msg
+		if i > 0 {
+			// Only the first part carries the pills, so only it pings.
+			part.Mentions = nil
+		}
+		part.Body = label + " " + strings.Join(bodyGroups[i], "\n")
+		if htmlGroups != nil {
+			part.FormattedBody = "<p>" + label + "</p>\n" + renderHTML(htmlGroups[i])
+		}
+		parts[i] = part
+	}
+	return parts
+}
+
+// groupItems splits items into n non-empty groups of roughly equal size.
+func groupItems[T any](items []T, size func(T) int, n int) [][]T {
+	if n > len(items) {
+		return nil
+	}
+	total := 0
+	for _, item := range items {
+		total += size(item)
+	}
+
+	groups := make([][]T, 0, n)
+	current := []T{}
+	currentSize := 0
+	for i, item := range items {
+		current = append(current, item)
+		currentSize += size(item)
+		remainingItems := len(items) - i - 1
+		remainingGroups := n - len(groups) - 1
+		target := total * (len(groups) + 1) / n
+		if remainingGroups > 0 && (currentSize >= target || remainingItems == remainingGroups) {
+			groups = append(groups, current)
+			current = []T{}
+		}
+	}
+	groups = append(groups, current)
+	if len(groups) != n {
+		return nil
+	}
+	return groups
+}
+
+// htmlUnit is the smallest piece of formatted_body a split keeps together: a
+// line outside any table, a row of a table body, or a whole table that has
+// no splittable body. Rows carry their table's number (from 1) and head,
+// the table opening through <tbody> that a row needs around it.
+type htmlUnit struct {
+	text  string
+	table int
+	head  string
+}
+
+// splitHTML breaks formatted_body into units for splitting.
+func splitHTML(body string) []htmlUnit {
+	var units []htmlUnit
+	addLines := func(text string) {
+		text = strings.Trim(text, "\n")
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(text, "\n") {
+			units = append(units, htmlUnit{text: line})
+		}
+	}
+
+	tables := 0
+	rest := body
+	for rest != "" {
+		start := strings.Index(strings.ToLower(rest), "<table")
+		if start < 0 {
+			addLines(rest)
+			break
+		}
+		end := strings.Index(strings.ToLower(rest[start:]), "</table>")
+		if end < 0 {
+			addLines(rest)
+			break
+		}
+		end += start + len("</table>")
+		addLines(rest[:start])
+		tables++
+		units = append(units, tableUnits(rest[start:end], tables)...)
+		rest = rest[end:]
+	}
+	return units
+}
+
+// tableUnits returns one unit per row of table's body, or the whole table as
+// one unit when it has no body rows or content after the body.
+func tableUnits(table string, number int) []htmlUnit {
+	whole := []htmlUnit{{text: table}}
+	lower := strings.ToLower(table)
+	open := strings.Index(lower, "<tbody")
+	if open < 0 {
+		return whole
+	}
+	bodyStart := open + strings.Index(lower[open:], ">") + 1
+	bodyEnd := strings.LastIndex(lower, "</tbody>")
+	if bodyEnd < bodyStart || strings.TrimSpace(table[bodyEnd+len("</tbody>"):len(table)-len("</table>")]) != "" {
+		return whole
+	}
+
+	head := table[:bodyStart]
+	var rows []htmlUnit
+	body := table[bodyStart:bodyEnd]
+	lowerBody := lower[bodyStart:bodyEnd]
+	for {
+		rowStart := strings.Index(lowerBody, "<tr")
+		if rowStart < 0 {
+			break
+		}
+		rowEnd := strings.Index(lowerBody[rowStart:], "</tr>")
+		if rowEnd < 0 {
+			return whole
+		}
+		rowEnd += rowStart + len("</tr>")
+		if strings.TrimSpace(body[:rowStart]) != "" {
+			return whole
+		}
+		rows = append(rows, htmlUnit{text: body[rowStart:rowEnd], table: number, head: head})
+		body, lowerBody = body[rowEnd:], lowerBody[rowEnd:]
+	}
+	if len(rows) == 0 || strings.TrimSpace(body) != "" {
+		return whole
+	}
+	return rows
+}
+
+// renderHTML joins units back into HTML, opening a table before the first
+// row of each run of rows and closing it after the last.
+func renderHTML(units []htmlUnit) string {
+	var lines []string
+	open := 0
+	closeTable := func() {
+		if open != 0 {
+			lines = append(lines, "</tbody>", "</table>")
+			open = 0
+		}
+	}
+	for _, u := range units {
+		if u.table != open {
+			closeTable()
+			if u.table != 0 {
+				lines = append(lines, u.head)
+				open = u.table
+			}
+		}
+		lines = append(lines, u.text)
+	}
+	closeTable()
+	return strings.Join(lines, "\n")
+}
+
+func contentSize(msg Message) int {
+	raw, err := json.Marshal(buildContent(msg))
+	if err != nil {
+		return len(msg.Body) + len(msg.FormattedBody)
+	}
+	return len(raw)
+}
@@ -0,0 +1,186 @@
+package matrix
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var rowPattern = regexp.MustCompile(`<tr>.*?</tr>`)
+
+// timetableMessage builds a message shaped like a rendered timetable with
+// rows table rows, optionally surrounded by paragraphs.
+func timetableMessage(rows int, before, after bool) Message {
+	var body, html []string
+	if before {
+		body = append(body, "Timetable update")
+		html = append(html, "<p><strong>Timetable update</strong></p>")
+	}
+	html = append(html, "<table>", "<thead><tr><th>Slot</th><th>Course</th><th>Venue</th></tr></thead>", "<tbody>")
+	for i := 1; i <= rows; i++ {
+		body = append(body, fmt.Sprintf("%d. CS%03d in room %d", i, i, 100+i))
+		html = append(html, fmt.Sprintf("<tr><td>%d</td><td>CS%03d</td><td>Room %d</td></tr>", i, i, 100+i))
+	}
+	html = append(html, "</tbody>", "</table>")
+	if after {
+		body = append(body, "Reply in thread with questions.")
+		html = append(html, "<p>Reply in thread with questions.</p>")
+	}
+	return Message{
+		Body:          strings.Join(body, "\n"),
+		FormattedBody: strings.Join(html, "\n"),
+		Format:        "html",
+	}
+}
+
+// rowsToOverflow returns the smallest row count whose message no longer
+// fits in MaxContentBytes.
+func rowsToOverflow(before, after bool) int {
+	n := 1
+	for contentSize(timetableMessage(n, before, after)) <= MaxContentBytes {
+		n++
+	}
+	return n
+}
+
+func checkBalancedTables(t *testing.T, html string) {
+	t.Helper()
+	depth := 0
+	for _, tag := range regexp.MustCompile(`<table|</table>|<tbody>|</tbody>`).FindAllString(html, -1) {
+		switch tag {
+		case "<table":
+			if depth != 0 {
+				t.Fatalf("nested or unclosed table in %q", html)
+			}
+			depth = 1
+		case "<tbody>":
+			if depth != 1 {
+				t.Fatalf("tbody outside table in %q", html)
+			}
+			depth = 2
+		case "</tbody>":
+			if depth != 2 {
+				t.Fatalf("stray </tbody> in %q", html)
+			}
+			depth = 1
+		case "</table>":
+			if depth != 1 {
+				t.Fatalf("stray </table> in %q", html)
+			}
+			depth = 0
+		}
+	}
+	if depth != 0 {
+		t.Fatalf("unclosed table in %q", html)
+	}
+	if strings.Contains(html, "<tbody>\n</tbody>") {
+		t.Fatalf("empty table body in %q", html)
+	}
+}
+
+func TestSplitMessageAtSizeLimit(t *testing.T) {
+	for _, layout := range []struct {
+		name          string
+		before, after bool
+	}{
+		{"table only", false, false},
+		{"paragraph before", true, false},
+		{"paragraph after", false, true},
+		{"paragraphs around", true, true},
+	} {
+		limit := rowsToOverflow(layout.before, layout.after)
+		for _, tc := range []struct {
+			rows      int
+			wantSplit bool
+		}{
+			{limit - 1, false},
+			{limit, true},
+			{limit + 1, true},
+			{2*limit + 3, true},
+		} {
+			t.Run(fmt.Sprintf("%s/%d rows", layout.name, tc.rows), func(t *testing.T) {
+				msg := timetableMessage(tc.rows, layout.before, layout.after)
+				parts, err := SplitMessage(msg, MaxContentBytes)
+				if err != nil {
+					t.Fatalf("SplitMessage: %v", err)
+				}
+				if !tc.wantSplit {
+					if len(parts) != 1 || parts[0].FormattedBody != msg.FormattedBody {
+						t.Fatalf("message under the limit was split into %d parts", len(parts))
+					}
+					return
+				}
+				if len(parts) < 2 {
+					t.Fatalf("got %d parts, want a split", len(parts))
+				}
+
+				var rows []string
+				for i, part := range parts {
+					if size := contentSize(part); size > MaxContentBytes {
+						t.Errorf("part %d is %d bytes", i+1, size)
+					}
+					label := fmt.Sprintf("(%d/%d)", i+1, len(parts))
+					if !strings.HasPrefix(part.Body, label+" ") || !strings.HasPrefix(part.FormattedBody, "<p>"+label+"</p>") {
+						t.Errorf("part %d is not labelled %s", i+1, label)
+					}
+					checkBalancedTables(t, part.FormattedBody)
+					rows = append(rows, rowPattern.FindAllString(part.FormattedBody, -1)...)
+				}
+				// The header row is repeated per part; body rows appear once, in order.
+				var bodyRows []string
+				for _, row := range rows {
+					if !strings.Contains(row, "<th>") {
+						bodyRows = append(bodyRows, row)
+					}
+				}
+				want := rowPattern.FindAllString(msg.FormattedBody, -1)[1:]
+				if strings.Join(bodyRows, "\n") != strings.Join(want, "\n") {
+					t.Errorf("parts carry %d body rows, want the original %d in order", len(bodyRows), len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestSplitHTMLEveryCut(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+	}{
+		{
+			name: "table between paragraphs",
+			html: "<p>a</p>\n<table>\n<thead><tr><th>h</th></tr></thead>\n<tbody>\n<tr><td>1</td></tr>\n<tr><td>2</td></tr>\n<tr><td>3</td></tr>\n</tbody>\n</table>\n<p>b</p>",
+		},
+		{
+			name: "rows on one line",
+			html: "<table><thead><tr><th>h</th></tr></thead><tbody><tr><td>1</td></tr><tr><td>2</td></tr></tbody></table>",
+		},
+		{
+			name: "two tables with the same header",
+			html: "<table>\n<tbody>\n<tr><td>1</td></tr>\n</tbody>\n</table>\n<table>\n<tbody>\n<tr><td>2</td></tr>\n</tbody>\n</table>",
+		},
+		{
+			name: "table without a body",
+			html: "<p>a</p>\n<table><tr><td>1</td></tr></table>",
+		},
+		{
+			name: "table with a footer",
+			html: "<table>\n<tbody>\n<tr><td>1</td></tr>\n</tbody>\n<tfoot><tr><td>f</td></tr></tfoot>\n</table>",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			units := splitHTML(tc.html)
+			whole := renderHTML(units)
+			checkBalancedTables(t, whole)
+			if got, want := len(rowPattern.FindAllString(whole, -1)), len(rowPattern.FindAllString(tc.html, -1)); got != want {
+				t.Fatalf("round trip has %d rows, want %d", got, want)
+			}
+			for cut := 1; cut < len(units); cut++ {
+				checkBalancedTables(t, renderHTML(units[:cut]))
+				checkBalancedTables(t, renderHTML(units[cut:]))
+			}
+		})
+	}
+}
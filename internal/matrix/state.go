@@ -0,0 +1,69 @@
+package matrix
+
+import (
+	"context"
+	"errors"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// ErrInsufficientPowerLevel is returned when the bot may not send the state
+// event a change needs.
+var ErrInsufficientPowerLevel = errors.New("bot lacks the required power level")
+
+// PinEvent pins eventID in roomID's m.room.pinned_events, unpinning
+// unpinEventID (when set) in the same update. Events pinned by others are
+// kept. It returns ErrInsufficientPowerLevel without changing anything when
+// the bot may not update pinned events.
+func (c *Client) PinEvent(ctx context.Context, roomID, eventID, unpinEventID string) error {
+	if roomID == "" || eventID == "" {
+		return errors.New("room ID and event ID are required")
+	}
+	ok, err := c.hasPowerLevel(ctx, roomID, func(pl *event.PowerLevelsEventContent) int {
+		return pl.GetEventLevel(event.StatePinnedEvents)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientPowerLevel
+	}
+
+	var current event.PinnedEventsEventContent
+	if err := c.client.StateEvent(ctx, id.RoomID(roomID), event.StatePinnedEvents, "", &current); err != nil && !errors.Is(err, mautrix.MNotFound) {
+		return err
+	}
+
+	pinned := make([]id.EventID, 0, len(current.Pinned)+1)
+	for _, existing := range current.Pinned {
+		if existing.String() == eventID || (unpinEventID != "" && existing.String() == unpinEventID) {
+			continue
+		}
+		pinned = append(pinned, existing)
+	}
+	pinned = append(pinned, id.EventID(eventID))
+
+	_, err = c.client.SendStateEvent(ctx, id.RoomID(roomID), event.StatePinnedEvents, "", &event.PinnedEventsEventContent{Pinned: pinned})
+	return err
+}
+
+// SetTopic replaces roomID's m.room.topic. It returns
+// ErrInsufficientPowerLevel when the bot may not change the topic.
+func (c *Client) SetTopic(ctx context.Context, roomID, topic string) error {
+	if roomID == "" {
+		return errors.New("room ID is required")
+	}
+	ok, err := c.hasPowerLevel(ctx, roomID, func(pl *event.PowerLevelsEventContent) int {
+		return pl.GetEventLevel(event.StateTopic)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientPowerLevel
+	}
+	_, err = c.client.SendStateEvent(ctx, id.RoomID(roomID), event.StateTopic, "", &event.TopicEventContent{Topic: topic})
+	return err
+}
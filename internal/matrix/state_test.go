@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPinEvent(t *testing.T) {
+	cases := []struct {
+		name    string
+		pinned  string
+		botPL   int
+		unpin   string
+		want    string
+		wantErr error
+	}{
+		{name: "first pin", botPL: 50, want: `["$today"]`},
+		{name: "replaces the previous pin and keeps others", pinned: `["$notice","$yesterday"]`, botPL: 50, unpin: "$yesterday", want: `["$notice","$today"]`},
+		{name: "moves an existing pin to the end", pinned: `["$today","$notice"]`, botPL: 50, want: `["$notice","$today"]`},
+		{name: "no power level", pinned: `["$yesterday"]`, botPL: 0, unpin: "$yesterday", wantErr: ErrInsufficientPowerLevel},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sent string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "/state/m.room.power_levels"):
+					_, _ = fmt.Fprintf(w, `{"users":{"@bot:example.org":%d},"events":{"m.room.pinned_events":50}}`, tc.botPL)
+				case strings.Contains(r.URL.Path, "/state/m.room.pinned_events") && r.Method == http.MethodGet:
+					if tc.pinned == "" {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = io.WriteString(w, `{"errcode":"M_NOT_FOUND"}`)
+						return
+					}
+					_, _ = fmt.Fprintf(w, `{"pinned":%s}`, tc.pinned)
+				case strings.Contains(r.URL.Path, "/state/m.room.pinned_events") && r.Method == http.MethodPut:
+					var content struct {
+						Pinned json.RawMessage `json:"pinned"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&content)
+					sent = string(content.Pinned)
+					_, _ = io.WriteString(w, `{"event_id":"$state"}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "@bot:example.org", "token", nil, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			err = c.PinEvent(context.Background(), "!a:example.org", "$today", tc.unpin)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PinEvent error = %v, want %v", err, tc.wantErr)
+			}
+			if sent != tc.want {
+				t.Errorf("pinned = %s, want %s", sent, tc.want)
+			}
+		})
+	}
+}
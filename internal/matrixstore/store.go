@@ -0,0 +1,308 @@
+// Package matrixstore implements mautrix's SyncStore and StateStore on top
+// of the adapter database, so the Matrix client resumes sync from its last
+// next_batch token after a restart.
+package matrixstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"adapter-matrix/internal/repository"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+var (
+	_ mautrix.SyncStore  = (*Store)(nil)
+	_ mautrix.StateStore = (*Store)(nil)
+)
+
+// Store is a Postgres-backed mautrix SyncStore and StateStore.
+//
+// mautrix saves next_batch before the batch's events are handled, so the
+// token is only held in memory until CommitNextBatch writes it. A crash
+// mid-batch then replays the batch after a restart instead of skipping it.
+type Store struct {
+	repo repo
+
+	mu      sync.Mutex
+	pending map[id.UserID]string
+}
+
+// repo is the part of MatrixStoreRepository the store uses.
+type repo interface {
+	FilterID(ctx context.Context, userID string) (string, error)
+	SetFilterID(ctx context.Context, userID, filterID string) error
+	NextBatch(ctx context.Context, userID string) (string, error)
+	SetNextBatch(ctx context.Context, userID, token string) error
+	Member(ctx context.Context, roomID, userID string) (repository.RoomMember, bool, error)
+	Members(ctx context.Context, roomID string, memberships []string) ([]repository.RoomMember, error)
+	SetMember(ctx context.Context, roomID string, member repository.RoomMember) error
+	SetMembership(ctx context.Context, roomID, userID, membership string) error
+	ClearMembers(ctx context.Context, roomID string, memberships []string) error
+	MembersFetched(ctx context.Context, roomID string) (bool, error)
+	MarkMembersFetched(ctx context.Context, roomID string) error
+	RoomState(ctx context.Context, roomID, kind string) ([]byte, error)
+	SetRoomState(ctx context.Context, roomID, kind string, content []byte) error
+}
+
+func New(repo *repository.MatrixStoreRepository) (*Store, error) {
+	if repo == nil {
+		return nil, errors.New("matrix store repository is required")
+	}
+	return newStore(repo), nil
+}
+
+func newStore(r repo) *Store {
+	return &Store{repo: r, pending: make(map[id.UserID]string)}
+}
+
+func (s *Store) SaveFilterID(ctx context.Context, userID id.UserID, filterID string) error {
+	return s.repo.SetFilterID(ctx, userID.String(), filterID)
+}
+
+func (s *Store) LoadFilterID(ctx context.Context, userID id.UserID) (string, error) {
+	return s.repo.FilterID(ctx, userID.String())
+}
+
+// SaveNextBatch holds the token until CommitNextBatch.
+func (s *Store) SaveNextBatch(ctx context.Context, userID id.UserID, nextBatchToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[userID] = nextBatchToken
+	return nil
+}
+
+// CommitNextBatch writes the token last passed to SaveNextBatch, once the
+// batch it ends has been handled.
+func (s *Store) CommitNextBatch(ctx context.Context, userID id.UserID) error {
+	s.mu.Lock()
+	token, ok := s.pending[userID]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := s.repo.SetNextBatch(ctx, userID.String(), token); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.pending[userID] == token {
+		delete(s.pending, userID)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// LoadNextBatch returns the last committed token.
+func (s *Store) LoadNextBatch(ctx context.Context, userID id.UserID) (string, error) {
+	return s.repo.NextBatch(ctx, userID.String())
+}
+
+func (s *Store) IsInRoom(ctx context.Context, roomID id.RoomID, userID id.UserID) bool {
+	return s.IsMembership(ctx, roomID, userID, event.MembershipJoin)
+}
+
+func (s *Store) IsInvited(ctx context.Context, roomID id.RoomID, userID id.UserID) bool {
+	return s.IsMembership(ctx, roomID, userID, event.MembershipJoin, event.MembershipInvite)
+}
+
+func (s *Store) IsMembership(ctx context.Context, roomID id.RoomID, userID id.UserID, allowedMemberships ...event.Membership) bool {
+	member, err := s.GetMember(ctx, roomID, userID)
+	if err != nil {
+		return false
+	}
+	for _, membership := range allowedMemberships {
+		if member.Membership == membership {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMember returns the cached member, or a leave membership when none is
+// cached.
+func (s *Store) GetMember(ctx context.Context, roomID id.RoomID, userID id.UserID) (*event.MemberEventContent, error) {
+	member, err := s.TryGetMember(ctx, roomID, userID)
+	if member == nil && err == nil {
+		member = &event.MemberEventContent{Membership: event.MembershipLeave}
+	}
+	return member, err
+}
+
+func (s *Store) TryGetMember(ctx context.Context, roomID id.RoomID, userID id.UserID) (*event.MemberEventContent, error) {
+	row, found, err := s.repo.Member(ctx, roomID.String(), userID.String())
+	if err != nil || !found {
+		return nil, err
+	}
+	return decodeMember(row)
+}
+
+func (s *Store) SetMembership(ctx context.Context, roomID id.RoomID, userID id.UserID, membership event.Membership) error {
+	return s.repo.SetMembership(ctx, roomID.String(), userID.String(), string(membership))
+}
+
+func (s *Store) SetMember(ctx context.Context, roomID id.RoomID, userID id.UserID, member *event.MemberEventContent) error {
+	content, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetMember(ctx, roomID.String(), repository.RoomMember{
+		UserID:     userID.String(),
+		Membership: string(member.Membership),
+		Content:    content,
+	})
+}
+
+// IsConfusableName is not tracked; the adapter never renders member names.
+func (s *Store) IsConfusableName(ctx context.Context, roomID id.RoomID, currentUser id.UserID, name string) ([]id.UserID, error) {
+	return nil, nil
+}
+
+func (s *Store) ClearCachedMembers(ctx context.Context, roomID id.RoomID, memberships ...event.Membership) error {
+	return s.repo.ClearMembers(ctx, roomID.String(), membershipStrings(memberships))
+}
+
+func (s *Store) ReplaceCachedMembers(ctx context.Context, roomID id.RoomID, evts []*event.Event, onlyMemberships ...event.Membership) error {
+	if err := s.ClearCachedMembers(ctx, roomID, onlyMemberships...); err != nil {
+		return err
+	}
+	for _, evt := range evts {
+		mautrix.UpdateStateStore(ctx, s, evt)
+	}
+	if len(onlyMemberships) == 0 {
+		return s.MarkMembersFetched(ctx, roomID)
+	}
+	return nil
+}
+
+func (s *Store) HasFetchedMembers(ctx context.Context, roomID id.RoomID) (bool, error) {
+	return s.repo.MembersFetched(ctx, roomID.String())
+}
+
+func (s *Store) MarkMembersFetched(ctx context.Context, roomID id.RoomID) error {
+	return s.repo.MarkMembersFetched(ctx, roomID.String())
+}
+
+func (s *Store) GetAllMembers(ctx context.Context, roomID id.RoomID) (map[id.UserID]*event.MemberEventContent, error) {
+	rows, err := s.repo.Members(ctx, roomID.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[id.UserID]*event.MemberEventContent, len(rows))
+	for _, row := range rows {
+		member, err := decodeMember(row)
+		if err != nil {
+			return nil, err
+		}
+		out[id.UserID(row.UserID)] = member
+	}
+	return out, nil
+}
+
+func (s *Store) GetRoomJoinedOrInvitedMembers(ctx context.Context, roomID id.RoomID) ([]id.UserID, error) {
+	rows, err := s.repo.Members(ctx, roomID.String(), []string{string(event.MembershipJoin), string(event.MembershipInvite)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]id.UserID, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, id.UserID(row.UserID))
+	}
+	return out, nil
+}
+
+func (s *Store) SetPowerLevels(ctx context.Context, roomID id.RoomID, levels *event.PowerLevelsEventContent) error {
+	return s.setState(ctx, roomID, repository.RoomStatePowerLevels, levels)
+}
+
+// GetPowerLevels returns the cached power levels with the room's create
+// event attached, or nil when none are cached.
+func (s *Store) GetPowerLevels(ctx context.Context, roomID id.RoomID) (*event.PowerLevelsEventContent, error) {
+	var levels *event.PowerLevelsEventContent
+	if err := s.getState(ctx, roomID, repository.RoomStatePowerLevels, &levels); err != nil || levels == nil {
+		return nil, err
+	}
+	create, err := s.GetCreate(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	levels.CreateEvent = create
+	return levels, nil
+}
+
+func (s *Store) SetCreate(ctx context.Context, evt *event.Event) error {
+	return s.setState(ctx, evt.RoomID, repository.RoomStateCreate, evt)
+}
+
+func (s *Store) GetCreate(ctx context.Context, roomID id.RoomID) (*event.Event, error) {
+	var evt *event.Event
+	if err := s.getState(ctx, roomID, repository.RoomStateCreate, &evt); err != nil || evt == nil {
+		return nil, err
+	}
+	evt.Type.Class = event.StateEventType
+	if err := evt.Content.ParseRaw(evt.Type); err != nil && !errors.Is(err, event.ErrContentAlreadyParsed) {
+		return nil, err
+	}
+	return evt, nil
+}
+
+func (s *Store) GetJoinRules(ctx context.Context, roomID id.RoomID) (*event.JoinRulesEventContent, error) {
+	var content *event.JoinRulesEventContent
+	err := s.getState(ctx, roomID, repository.RoomStateJoinRules, &content)
+	return content, err
+}
+
+func (s *Store) SetJoinRules(ctx context.Context, roomID id.RoomID, content *event.JoinRulesEventContent) error {
+	return s.setState(ctx, roomID, repository.RoomStateJoinRules, content)
+}
+
+func (s *Store) SetEncryptionEvent(ctx context.Context, roomID id.RoomID, content *event.EncryptionEventContent) error {
+	return s.setState(ctx, roomID, repository.RoomStateEncryption, content)
+}
+
+func (s *Store) IsEncrypted(ctx context.Context, roomID id.RoomID) (bool, error) {
+	var content *event.EncryptionEventContent
+	if err := s.getState(ctx, roomID, repository.RoomStateEncryption, &content); err != nil {
+		return false, err
+	}
+	return content != nil && content.Algorithm == id.AlgorithmMegolmV1, nil
+}
+
+func (s *Store) setState(ctx context.Context, roomID id.RoomID, kind string, content any) error {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetRoomState(ctx, roomID.String(), kind, raw)
+}
+
+// getState decodes the cached state into out, leaving it untouched when
+// nothing is cached.
+func (s *Store) getState(ctx context.Context, roomID id.RoomID, kind string, out any) error {
+	raw, err := s.repo.RoomState(ctx, roomID.String(), kind)
+	if err != nil || raw == nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func decodeMember(row repository.RoomMember) (*event.MemberEventContent, error) {
+	var member event.MemberEventContent
+	if err := json.Unmarshal(row.Content, &member); err != nil {
+		return nil, err
+	}
+	member.Membership = event.Membership(row.Membership)
+	return &member, nil
+}
+
+func membershipStrings(memberships []event.Membership) []string {
+	out := make([]string, 0, len(memberships))
+	for _, m := range memberships {
+		out = append(out, string(m))
+	}
+	return out
+}
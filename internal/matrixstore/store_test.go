@@ -0,0 +1,360 @@
+package matrixstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"adapter-matrix/internal/repository"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// fakeRepo keeps the store's rows in memory, merging SetMembership into the
+// cached content the way the SQL does.
+type fakeRepo struct {
+	tokens  map[string]map[string]string
+	members map[string]map[string]repository.RoomMember
+	fetched map[string]bool
+	state   map[string]map[string][]byte
+	failSet error
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		tokens:  map[string]map[string]string{},
+		members: map[string]map[string]repository.RoomMember{},
+		fetched: map[string]bool{},
+		state:   map[string]map[string][]byte{},
+	}
+}
+
+func (f *fakeRepo) token(userID, column string) string { return f.tokens[userID][column] }
+
+func (f *fakeRepo) setToken(userID, column, value string) error {
+	if f.failSet != nil {
+		return f.failSet
+	}
+	if f.tokens[userID] == nil {
+		f.tokens[userID] = map[string]string{}
+	}
+	f.tokens[userID][column] = value
+	return nil
+}
+
+func (f *fakeRepo) FilterID(ctx context.Context, userID string) (string, error) {
+	return f.token(userID, "filter_id"), nil
+}
+
+func (f *fakeRepo) SetFilterID(ctx context.Context, userID, filterID string) error {
+	return f.setToken(userID, "filter_id", filterID)
+}
+
+func (f *fakeRepo) NextBatch(ctx context.Context, userID string) (string, error) {
+	return f.token(userID, "next_batch"), nil
+}
+
+func (f *fakeRepo) SetNextBatch(ctx context.Context, userID, token string) error {
+	return f.setToken(userID, "next_batch", token)
+}
+
+func (f *fakeRepo) Member(ctx context.Context, roomID, userID string) (repository.RoomMember, bool, error) {
+	m, ok := f.members[roomID][userID]
+	return m, ok, nil
+}
+
+func (f *fakeRepo) Members(ctx context.Context, roomID string, memberships []string) ([]repository.RoomMember, error) {
+	var out []repository.RoomMember
+	for _, m := range f.members[roomID] {
+		if len(memberships) == 0 || contains(memberships, m.Membership) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out, nil
+}
+
+func (f *fakeRepo) SetMember(ctx context.Context, roomID string, member repository.RoomMember) error {
+	if f.members[roomID] == nil {
+		f.members[roomID] = map[string]repository.RoomMember{}
+	}
+	f.members[roomID][member.UserID] = member
+	return nil
+}
+
+func (f *fakeRepo) SetMembership(ctx context.Context, roomID, userID, membership string) error {
+	content := map[string]any{}
+	if m, ok := f.members[roomID][userID]; ok {
+		if err := json.Unmarshal(m.Content, &content); err != nil {
+			return err
+		}
+	}
+	content["membership"] = membership
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return f.SetMember(ctx, roomID, repository.RoomMember{UserID: userID, Membership: membership, Content: raw})
+}
+
+func (f *fakeRepo) ClearMembers(ctx context.Context, roomID string, memberships []string) error {
+	for userID, m := range f.members[roomID] {
+		if len(memberships) == 0 || contains(memberships, m.Membership) {
+			delete(f.members[roomID], userID)
+		}
+	}
+	f.fetched[roomID] = false
+	return nil
+}
+
+func (f *fakeRepo) MembersFetched(ctx context.Context, roomID string) (bool, error) {
+	return f.fetched[roomID], nil
+}
+
+func (f *fakeRepo) MarkMembersFetched(ctx context.Context, roomID string) error {
+	f.fetched[roomID] = true
+	return nil
+}
+
+func (f *fakeRepo) RoomState(ctx context.Context, roomID, kind string) ([]byte, error) {
+	return f.state[roomID][kind], nil
+}
+
+func (f *fakeRepo) SetRoomState(ctx context.Context, roomID, kind string, content []byte) error {
+	if f.state[roomID] == nil {
+		f.state[roomID] = map[string][]byte{}
+	}
+	f.state[roomID][kind] = content
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	bot  = id.UserID("@bot:example.org")
+	room = id.RoomID("!room:example.org")
+)
+
+func TestNextBatchCommittedAfterTheBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	s := newStore(repo)
+
+	if err := s.SaveNextBatch(ctx, bot, "s1"); err != nil {
+		t.Fatalf("SaveNextBatch: %v", err)
+	}
+	if got, _ := s.LoadNextBatch(ctx, bot); got != "" {
+		t.Fatalf("LoadNextBatch before commit = %q, want the token held back", got)
+	}
+	if err := s.CommitNextBatch(ctx, bot); err != nil {
+		t.Fatalf("CommitNextBatch: %v", err)
+	}
+	if got, _ := s.LoadNextBatch(ctx, bot); got != "s1" {
+		t.Fatalf("LoadNextBatch after commit = %q, want s1", got)
+	}
+
+	repo.failSet = errors.New("db down")
+	_ = s.SaveNextBatch(ctx, bot, "s2")
+	if err := s.CommitNextBatch(ctx, bot); err == nil {
+		t.Fatal("CommitNextBatch hid a write failure")
+	}
+	repo.failSet = nil
+	if err := s.CommitNextBatch(ctx, bot); err != nil {
+		t.Fatalf("CommitNextBatch retry: %v", err)
+	}
+	if got, _ := s.LoadNextBatch(ctx, bot); got != "s2" {
+		t.Fatalf("LoadNextBatch = %q, want s2 committed on the retry", got)
+	}
+
+	repo.tokens[bot.String()]["next_batch"] = "external"
+	if err := s.CommitNextBatch(ctx, bot); err != nil {
+		t.Fatalf("CommitNextBatch with nothing pending: %v", err)
+	}
+	if got, _ := s.LoadNextBatch(ctx, bot); got != "external" {
+		t.Errorf("CommitNextBatch rewrote an already committed token: %q", got)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(newFakeRepo())
+	ana := id.UserID("@ana:example.org")
+	ben := id.UserID("@ben:example.org")
+	cal := id.UserID("@cal:example.org")
+
+	if err := s.SetMember(ctx, room, ana, &event.MemberEventContent{Membership: event.MembershipJoin, Displayname: "Ana", AvatarURL: "mxc://example.org/a"}); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+	if err := s.SetMembership(ctx, room, ben, event.MembershipInvite); err != nil {
+		t.Fatalf("SetMembership: %v", err)
+	}
+	if err := s.SetMember(ctx, room, cal, &event.MemberEventContent{Membership: event.MembershipBan}); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+
+	member, err := s.GetMember(ctx, room, ana)
+	if err != nil || member.Displayname != "Ana" || member.AvatarURL != "mxc://example.org/a" || member.Membership != event.MembershipJoin {
+		t.Fatalf("GetMember = %+v, %v", member, err)
+	}
+	if err := s.SetMembership(ctx, room, ana, event.MembershipLeave); err != nil {
+		t.Fatalf("SetMembership: %v", err)
+	}
+	member, _ = s.GetMember(ctx, room, ana)
+	if member.Membership != event.MembershipLeave || member.Displayname != "Ana" {
+		t.Errorf("GetMember after leave = %+v, want the profile kept", member)
+	}
+
+	unknown, err := s.GetMember(ctx, room, "@nobody:example.org")
+	if err != nil || unknown.Membership != event.MembershipLeave {
+		t.Errorf("GetMember(unknown) = %+v, %v; want leave", unknown, err)
+	}
+	if tried, err := s.TryGetMember(ctx, room, "@nobody:example.org"); tried != nil || err != nil {
+		t.Errorf("TryGetMember(unknown) = %+v, %v; want nil", tried, err)
+	}
+
+	if err := s.SetMembership(ctx, room, ana, event.MembershipJoin); err != nil {
+		t.Fatalf("SetMembership: %v", err)
+	}
+	cases := []struct {
+		user              id.UserID
+		inRoom, isInvited bool
+	}{
+		{ana, true, true},
+		{ben, false, true},
+		{cal, false, false},
+		{"@nobody:example.org", false, false},
+	}
+	for _, tc := range cases {
+		if got := s.IsInRoom(ctx, room, tc.user); got != tc.inRoom {
+			t.Errorf("IsInRoom(%s) = %t, want %t", tc.user, got, tc.inRoom)
+		}
+		if got := s.IsInvited(ctx, room, tc.user); got != tc.isInvited {
+			t.Errorf("IsInvited(%s) = %t, want %t", tc.user, got, tc.isInvited)
+		}
+	}
+
+	all, err := s.GetAllMembers(ctx, room)
+	if err != nil || len(all) != 3 || all[cal].Membership != event.MembershipBan {
+		t.Errorf("GetAllMembers = %+v, %v", all, err)
+	}
+	joined, err := s.GetRoomJoinedOrInvitedMembers(ctx, room)
+	if err != nil || len(joined) != 2 || joined[0] != ana || joined[1] != ben {
+		t.Errorf("GetRoomJoinedOrInvitedMembers = %v, %v; want ana and ben", joined, err)
+	}
+}
+
+func TestReplaceCachedMembers(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(newFakeRepo())
+	_ = s.SetMembership(ctx, room, "@gone:example.org", event.MembershipJoin)
+
+	stateKey := "@ana:example.org"
+	evt := &event.Event{
+		Type:     event.StateMember,
+		RoomID:   room,
+		StateKey: &stateKey,
+		Content:  event.Content{Parsed: &event.MemberEventContent{Membership: event.MembershipJoin, Displayname: "Ana"}},
+	}
+	if err := s.ReplaceCachedMembers(ctx, room, []*event.Event{evt}); err != nil {
+		t.Fatalf("ReplaceCachedMembers: %v", err)
+	}
+	all, _ := s.GetAllMembers(ctx, room)
+	if len(all) != 1 || all["@ana:example.org"].Displayname != "Ana" {
+		t.Errorf("members = %+v, want only ana", all)
+	}
+	if fetched, _ := s.HasFetchedMembers(ctx, room); !fetched {
+		t.Error("HasFetchedMembers = false after a full replace")
+	}
+	if err := s.ClearCachedMembers(ctx, room, event.MembershipInvite); err != nil {
+		t.Fatalf("ClearCachedMembers: %v", err)
+	}
+	if fetched, _ := s.HasFetchedMembers(ctx, room); fetched {
+		t.Error("HasFetchedMembers = true after clearing")
+	}
+}
+
+func TestRoomState(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(newFakeRepo())
+
+	if levels, err := s.GetPowerLevels(ctx, room); levels != nil || err != nil {
+		t.Fatalf("GetPowerLevels with nothing cached = %+v, %v", levels, err)
+	}
+	if create, err := s.GetCreate(ctx, room); create != nil || err != nil {
+		t.Fatalf("GetCreate with nothing cached = %+v, %v", create, err)
+	}
+
+	creator := id.UserID("@admin:example.org")
+	stateKey := ""
+	create := &event.Event{
+		Type:     event.StateCreate,
+		RoomID:   room,
+		Sender:   creator,
+		StateKey: &stateKey,
+		ID:       "$create",
+		Content:  event.Content{Parsed: &event.CreateEventContent{RoomVersion: "11"}},
+	}
+	raw, err := json.Marshal(create.Content.Parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create.Content.VeryRaw = raw
+	if err := s.SetCreate(ctx, create); err != nil {
+		t.Fatalf("SetCreate: %v", err)
+	}
+	levels := &event.PowerLevelsEventContent{
+		Users:         map[id.UserID]int{creator: 100, bot: 50},
+		EventsDefault: 0,
+		Events:        map[string]int{event.StateTopic.Type: 50},
+	}
+	if err := s.SetPowerLevels(ctx, room, levels); err != nil {
+		t.Fatalf("SetPowerLevels: %v", err)
+	}
+
+	gotCreate, err := s.GetCreate(ctx, room)
+	if err != nil {
+		t.Fatalf("GetCreate: %v", err)
+	}
+	content, ok := gotCreate.Content.Parsed.(*event.CreateEventContent)
+	if !ok || content.RoomVersion != "11" || gotCreate.Sender != creator || gotCreate.Type != event.StateCreate {
+		t.Fatalf("GetCreate = %+v (content %T), want the parsed create event", gotCreate, gotCreate.Content.Parsed)
+	}
+
+	gotLevels, err := s.GetPowerLevels(ctx, room)
+	if err != nil {
+		t.Fatalf("GetPowerLevels: %v", err)
+	}
+	if gotLevels.CreateEvent == nil || gotLevels.CreateEvent.ID != "$create" {
+		t.Errorf("GetPowerLevels CreateEvent = %+v, want the cached create event", gotLevels.CreateEvent)
+	}
+	if gotLevels.GetUserLevel(bot) != 50 || gotLevels.GetEventLevel(event.StateTopic) != 50 {
+		t.Errorf("GetPowerLevels = %+v", gotLevels)
+	}
+
+	if encrypted, err := s.IsEncrypted(ctx, room); encrypted || err != nil {
+		t.Errorf("IsEncrypted = %t, %v; want false", encrypted, err)
+	}
+	if err := s.SetEncryptionEvent(ctx, room, &event.EncryptionEventContent{Algorithm: id.AlgorithmMegolmV1}); err != nil {
+		t.Fatalf("SetEncryptionEvent: %v", err)
+	}
+	if encrypted, err := s.IsEncrypted(ctx, room); !encrypted || err != nil {
+		t.Errorf("IsEncrypted = %t, %v; want true", encrypted, err)
+	}
+
+	if err := s.SetJoinRules(ctx, room, &event.JoinRulesEventContent{JoinRule: event.JoinRuleInvite}); err != nil {
+		t.Fatalf("SetJoinRules: %v", err)
+	}
+	if rules, err := s.GetJoinRules(ctx, room); err != nil || rules.JoinRule != event.JoinRuleInvite {
+		t.Errorf("GetJoinRules = %+v, %v", rules, err)
+	}
+}
@@ -0,0 +1,143 @@
+// Package media loads files referenced by outbox payloads.
+package media
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"adapter-matrix/internal/repository"
+)
+
+// maxFileBytes bounds what is read into memory before the homeserver's own
+// upload limit is checked.
+const maxFileBytes = 100 << 20
+
+// Ref points at a file in exactly one of three places: inline base64, a path
+// under the media root, or a bytea column.
+type Ref struct {
+	FileName   string    `json:"file_name"`
+	MimeType   string    `json:"mimetype"`
+	DataBase64 string    `json:"data_base64"`
+	Path       string    `json:"path"`
+	Bytea      *ByteaRef `json:"bytea"`
+}
+
+// ByteaRef names a bytea column and the id of the row holding the file.
+type ByteaRef struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	ID     string `json:"id"`
+}
+
+// File is a loaded file with its name and mimetype resolved.
+type File struct {
+	FileName string
+	MimeType string
+	Data     []byte
+}
+
+// Loader resolves Refs. An empty root disables path references and a nil
+// repository disables bytea references.
+type Loader struct {
+	root  string
+	bytea *repository.MediaRepository
+}
+
+func NewLoader(root string, bytea *repository.MediaRepository) *Loader {
+	return &Loader{root: strings.TrimSpace(root), bytea: bytea}
+}
+
+// Load reads the file ref points at. A missing file name falls back to the
+// path's base name, and a missing mimetype is guessed from the extension and
+// then the content.
+func (l *Loader) Load(ctx context.Context, ref Ref) (File, error) {
+	sources := 0
+	for _, set := range []bool{ref.DataBase64 != "", ref.Path != "", ref.Bytea != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return File{}, errors.New("media needs exactly one of data_base64, path or bytea")
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case ref.DataBase64 != "":
+		data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(ref.DataBase64))
+		if err != nil {
+			return File{}, fmt.Errorf("media data_base64: %w", err)
+		}
+	case ref.Path != "":
+		data, err = l.readPath(ref.Path)
+	default:
+		data, err = l.readBytea(ctx, *ref.Bytea)
+	}
+	if err != nil {
+		return File{}, err
+	}
+	if len(data) == 0 {
+		return File{}, errors.New("media file is empty")
+	}
+	if len(data) > maxFileBytes {
+		return File{}, fmt.Errorf("media file is larger than %d bytes", maxFileBytes)
+	}
+
+	name := strings.TrimSpace(ref.FileName)
+	if name == "" && ref.Path != "" {
+		name = path.Base(ref.Path)
+	}
+	if name == "" {
+		name = "attachment"
+	}
+	mimeType := strings.TrimSpace(ref.MimeType)
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(path.Ext(name))
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return File{FileName: name, MimeType: mimeType, Data: data}, nil
+}
+
+// readPath reads a file relative to the media root. os.Root rejects paths
+// that escape it, including through symlinks.
+func (l *Loader) readPath(name string) ([]byte, error) {
+	if l.root == "" {
+		return nil, errors.New("media path references are disabled (MEDIA_ROOT is not set)")
+	}
+	root, err := os.OpenRoot(l.root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+	info, err := root.Stat(rel)
+	if err != nil {
+		return nil, fmt.Errorf("media path: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("media path %s: %w", name, fs.ErrInvalid)
+	}
+	if info.Size() > maxFileBytes {
+		return nil, fmt.Errorf("media file is larger than %d bytes", maxFileBytes)
+	}
+	return root.ReadFile(rel)
+}
+
+func (l *Loader) readBytea(ctx context.Context, ref ByteaRef) ([]byte, error) {
+	if l.bytea == nil {
+		return nil, errors.New("media bytea references are disabled")
+	}
+	return l.bytea.Bytea(ctx, strings.TrimSpace(ref.Table), strings.TrimSpace(ref.Column), strings.TrimSpace(ref.ID))
+}
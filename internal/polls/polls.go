@@ -0,0 +1,246 @@
+// Package polls collects responses to polls the adapter started and reports
+// the results when they close.
+package polls
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+// closedBySchedule is PollResultsCollected.closed_by for polls closed at
+// closes_at rather than by a room member.
+const closedBySchedule = "schedule"
+
+// store is the part of repository.PollRepository the service uses.
+type store interface {
+	Get(ctx context.Context, pollEventID string) (repository.Poll, bool, error)
+	HasUnsent(ctx context.Context, roomID string) (bool, error)
+	DueForClose(ctx context.Context, now time.Time) ([]repository.Poll, error)
+	RecordResponse(ctx context.Context, pollEventID, sender, responseEventID string, answers []string, respondedAt time.Time) error
+	Responses(ctx context.Context, pollEventID string, until time.Time) ([]repository.PollResponse, error)
+	Close(ctx context.Context, results events.PollResultsCollected) (bool, error)
+	SetEndEvent(ctx context.Context, pollEventID, endEventID string) error
+}
+
+type userDirectory interface {
+	CR45UserIDs(ctx context.Context, matrixUserIDs []string) (map[string]string, error)
+}
+
+type room interface {
+	UserID() string
+	CanEndPoll(ctx context.Context, roomID, userID string) (bool, error)
+	SendPollEnd(ctx context.Context, roomID, pollEventID, summary string) (string, error)
+}
+
+type Service struct {
+	repo    store
+	users   userDirectory
+	matrix  room
+	locales *i18n.Resolver
+	logger  *log.Logger
+}
+
+func NewService(repo *repository.PollRepository, users *repository.UserDirectoryRepository, matrixClient *matrix.Client, locales *i18n.Resolver, logger *log.Logger) (*Service, error) {
+	if repo == nil || users == nil || matrixClient == nil || locales == nil {
+		return nil, errors.New("poll repository, user directory, matrix client and locales are required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	return &Service{repo: repo, users: users, matrix: matrixClient, locales: locales, logger: logger}, nil
+}
+
+// Run closes due polls every interval until ctx is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.CloseDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Printf("polls: close due polls failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CloseDue closes every open poll whose closes_at has passed.
+func (s *Service) CloseDue(ctx context.Context) error {
+	due, err := s.repo.DueForClose(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, poll := range due {
+		if err := s.close(ctx, poll, poll.ClosesAt, closedBySchedule, true); err != nil {
+			s.logger.Printf("polls: close %s failed: %v", poll.PollEventID, err)
+		}
+	}
+	return nil
+}
+
+// HandleResponse records a vote on one of the adapter's open polls. A vote
+// for an unknown poll is kept while the room has a poll still being sent,
+// since it may answer that poll; closing only reads the responses to the
+// polls the adapter knows.
+func (s *Service) HandleResponse(ctx context.Context, resp matrix.PollResponse) {
+	if resp.Sender == s.matrix.UserID() {
+		return
+	}
+	poll, found, err := s.repo.Get(ctx, resp.PollEventID)
+	if err != nil {
+		s.logger.Printf("polls: load poll %s: %v", resp.PollEventID, err)
+		return
+	}
+	if !found {
+		unsent, err := s.repo.HasUnsent(ctx, resp.RoomID)
+		if err != nil {
+			s.logger.Printf("polls: check unsent polls in %s: %v", resp.RoomID, err)
+			return
+		}
+		if !unsent {
+			return
+		}
+	} else if poll.RoomID != resp.RoomID || poll.ClosedAt != nil || resp.At.After(poll.ClosesAt) {
+		return
+	}
+	if err := s.repo.RecordResponse(ctx, resp.PollEventID, resp.Sender, resp.EventID, resp.Answers, resp.At); err != nil {
+		s.logger.Printf("polls: record response %s: %v", resp.EventID, err)
+	}
+}
+
+// HandleEnd closes one of the adapter's polls ended early by a room member
+// allowed to end it.
+func (s *Service) HandleEnd(ctx context.Context, end matrix.PollEnd) {
+	if end.Sender == s.matrix.UserID() {
+		return
+	}
+	poll, found, err := s.repo.Get(ctx, end.PollEventID)
+	if err != nil {
+		s.logger.Printf("polls: load poll %s: %v", end.PollEventID, err)
+		return
+	}
+	if !found || poll.RoomID != end.RoomID || poll.ClosedAt != nil {
+		return
+	}
+	allowed, err := s.matrix.CanEndPoll(ctx, end.RoomID, end.Sender)
+	if err != nil {
+		s.logger.Printf("polls: check power level of %s: %v", end.Sender, err)
+		return
+	}
+	if !allowed {
+		s.logger.Printf("polls: ignoring end of %s by %s, who lacks the redact power level", end.PollEventID, end.Sender)
+		return
+	}
+	closedAt := end.At
+	if closedAt.After(poll.ClosesAt) {
+		closedAt = poll.ClosesAt
+	}
+	if err := s.close(ctx, poll, closedAt, end.Sender, false); err != nil {
+		s.logger.Printf("polls: close %s failed: %v", poll.PollEventID, err)
+	}
+}
+
+// close tallies the responses given by closedAt, emits PollResultsCollected
+// and, when sendEnd is set, ends the poll in the room. The end event only
+// informs the room, so a failure to send it is logged and not retried.
+func (s *Service) close(ctx context.Context, poll repository.Poll, closedAt time.Time, closedBy string, sendEnd bool) error {
+	responses, err := s.repo.Responses(ctx, poll.PollEventID, closedAt)
+	if err != nil {
+		return err
+	}
+	results := tally(poll, responses)
+	results.ClosedAt = closedAt
+	results.ClosedBy = closedBy
+
+	voters := make([]string, 0, len(results.Responses))
+	for _, resp := range results.Responses {
+		voters = append(voters, resp.MatrixUserID)
+	}
+	cr45IDs, err := s.users.CR45UserIDs(ctx, voters)
+	if err != nil {
+		return err
+	}
+	for i := range results.Responses {
+		results.Responses[i].CR45UserID = cr45IDs[results.Responses[i].MatrixUserID]
+	}
+
+	closed, err := s.repo.Close(ctx, results)
+	if err != nil || !closed || !sendEnd {
+		return err
+	}
+	loc := s.locales.Localizer(ctx, poll.RoomID, "")
+	endEventID, err := s.matrix.SendPollEnd(ctx, poll.RoomID, poll.PollEventID, summarize(loc, results))
+	if err != nil {
+		s.logger.Printf("polls: send end for %s: %v", poll.PollEventID, err)
+		return nil
+	}
+	return s.repo.SetEndEvent(ctx, poll.PollEventID, endEventID)
+}
+
+// tally counts valid responses. Unknown answer IDs are dropped and a voter's
+// selections beyond max_selections are ignored; responses left with no valid
+// answer do not count.
+func tally(poll repository.Poll, responses []repository.PollResponse) events.PollResultsCollected {
+	results := events.PollResultsCollected{
+		PollID:          poll.PollID,
+		OriginalEventID: poll.EventID,
+		Adapter:         "adapter-matrix",
+		MatrixRoomID:    poll.RoomID,
+		MatrixEventID:   poll.PollEventID,
+		Question:        poll.Question,
+		Context:         poll.Context,
+		Answers:         make([]events.PollAnswerResult, len(poll.Answers)),
+		Responses:       []events.PollVoterResponse{},
+	}
+	index := make(map[string]int, len(poll.Answers))
+	for i, answer := range poll.Answers {
+		index[answer.ID] = i
+		results.Answers[i] = events.PollAnswerResult{ID: answer.ID, Text: answer.Text}
+	}
+
+	for _, resp := range responses {
+		var valid []string
+		seen := make(map[string]struct{}, len(resp.Answers))
+		for _, answerID := range resp.Answers {
+			if _, ok := index[answerID]; !ok {
+				continue
+			}
+			if _, dup := seen[answerID]; dup {
+				continue
+			}
+			if len(valid) == poll.MaxSelections {
+				break
+			}
+			seen[answerID] = struct{}{}
+			valid = append(valid, answerID)
+		}
+		if len(valid) == 0 {
+			continue
+		}
+		for _, answerID := range valid {
+			results.Answers[index[answerID]].Count++
+		}
+		results.Responses = append(results.Responses, events.PollVoterResponse{MatrixUserID: resp.Sender, Answers: valid})
+	}
+	results.TotalVoters = len(results.Responses)
+	return results
+}
+
+// summarize is the text fallback of the end event.
+func summarize(loc *i18n.Localizer, results events.PollResultsCollected) string {
+	lines := []string{loc.T("poll.ended", results.Question, results.TotalVoters)}
+	for _, answer := range results.Answers {
+		lines = append(lines, loc.T("poll.result", answer.Text, answer.Count))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,284 @@
+package polls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+	"adapter-matrix/internal/i18n"
+	"adapter-matrix/internal/matrix"
+	"adapter-matrix/internal/repository"
+)
+
+type fakeStore struct {
+	polls     map[string]repository.Poll
+	unsent    map[string]bool
+	responses map[string][]repository.PollResponse
+	closed    []events.PollResultsCollected
+	endEvents map[string]string
+}
+
+func (f *fakeStore) Get(_ context.Context, pollEventID string) (repository.Poll, bool, error) {
+	poll, ok := f.polls[pollEventID]
+	return poll, ok, nil
+}
+
+func (f *fakeStore) HasUnsent(_ context.Context, roomID string) (bool, error) {
+	return f.unsent[roomID], nil
+}
+
+func (f *fakeStore) DueForClose(_ context.Context, now time.Time) ([]repository.Poll, error) {
+	var due []repository.Poll
+	for _, poll := range f.polls {
+		if poll.ClosedAt == nil && !poll.ClosesAt.After(now) {
+			due = append(due, poll)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeStore) RecordResponse(_ context.Context, pollEventID, sender, _ string, answers []string, respondedAt time.Time) error {
+	f.responses[pollEventID] = append(f.responses[pollEventID], repository.PollResponse{Sender: sender, Answers: answers, RespondedAt: respondedAt})
+	return nil
+}
+
+func (f *fakeStore) Responses(_ context.Context, pollEventID string, until time.Time) ([]repository.PollResponse, error) {
+	var out []repository.PollResponse
+	for _, resp := range f.responses[pollEventID] {
+		if !resp.RespondedAt.After(until) {
+			out = append(out, resp)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) Close(_ context.Context, results events.PollResultsCollected) (bool, error) {
+	poll := f.polls[results.MatrixEventID]
+	if poll.ClosedAt != nil {
+		return false, nil
+	}
+	poll.ClosedAt = &results.ClosedAt
+	f.polls[results.MatrixEventID] = poll
+	f.closed = append(f.closed, results)
+	return true, nil
+}
+
+func (f *fakeStore) SetEndEvent(_ context.Context, pollEventID, endEventID string) error {
+	f.endEvents[pollEventID] = endEventID
+	return nil
+}
+
+type fakeDirectory map[string]string
+
+func (f fakeDirectory) CR45UserIDs(_ context.Context, matrixUserIDs []string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, userID := range matrixUserIDs {
+		if id, ok := f[userID]; ok {
+			out[userID] = id
+		}
+	}
+	return out, nil
+}
+
+type fakeRoom struct {
+	canEnd    bool
+	summaries map[string]string
+}
+
+func (f *fakeRoom) UserID() string { return "@bot:example.org" }
+
+func (f *fakeRoom) CanEndPoll(context.Context, string, string) (bool, error) {
+	return f.canEnd, nil
+}
+
+func (f *fakeRoom) SendPollEnd(_ context.Context, _, pollEventID, summary string) (string, error) {
+	f.summaries[pollEventID] = summary
+	return "$end-" + pollEventID, nil
+}
+
+var closesAt = time.Date(2024, 10, 14, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, canEnd bool) (*Service, *fakeStore, *fakeRoom) {
+	t.Helper()
+	logger := log.New(io.Discard, "", 0)
+	locales, err := i18n.NewResolver(i18n.Default(), nil, "", nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeStore{
+		polls: map[string]repository.Poll{
+			"$poll": {
+				PollEventID:   "$poll",
+				EventID:       "2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11",
+				PollID:        "p1",
+				RoomID:        "!room:example.org",
+				Question:      "Move the quiz?",
+				Answers:       []repository.PollAnswer{{ID: "yes", Text: "Yes"}, {ID: "no", Text: "No"}},
+				MaxSelections: 1,
+				ClosesAt:      closesAt,
+			},
+		},
+		unsent:    map[string]bool{"!pending:example.org": true},
+		responses: map[string][]repository.PollResponse{},
+		endEvents: map[string]string{},
+	}
+	matrixRoom := &fakeRoom{canEnd: canEnd, summaries: map[string]string{}}
+	s := &Service{repo: repo, users: fakeDirectory{"@ana:example.org": "cr45-ana"}, matrix: matrixRoom, locales: locales, logger: logger}
+	return s, repo, matrixRoom
+}
+
+func TestTally(t *testing.T) {
+	poll := repository.Poll{
+		PollEventID:   "$poll",
+		Answers:       []repository.PollAnswer{{ID: "a", Text: "A"}, {ID: "b", Text: "B"}, {ID: "c", Text: "C"}},
+		MaxSelections: 2,
+	}
+	cases := []struct {
+		name       string
+		answers    []string
+		wantCounts [3]int
+		wantVoted  bool
+	}{
+		{name: "single answer", answers: []string{"a"}, wantCounts: [3]int{1, 0, 0}, wantVoted: true},
+		{name: "two answers", answers: []string{"a", "c"}, wantCounts: [3]int{1, 0, 1}, wantVoted: true},
+		{name: "beyond max_selections", answers: []string{"b", "c", "a"}, wantCounts: [3]int{0, 1, 1}, wantVoted: true},
+		{name: "duplicate answer", answers: []string{"b", "b", "a"}, wantCounts: [3]int{1, 1, 0}, wantVoted: true},
+		{name: "unknown answer dropped", answers: []string{"z", "a"}, wantCounts: [3]int{1, 0, 0}, wantVoted: true},
+		{name: "only unknown answers", answers: []string{"z"}},
+		{name: "empty response", answers: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results := tally(poll, []repository.PollResponse{{Sender: "@ana:example.org", Answers: tc.answers}})
+			for i, answer := range results.Answers {
+				if answer.Count != tc.wantCounts[i] {
+					t.Errorf("answer %s count = %d, want %d", answer.ID, answer.Count, tc.wantCounts[i])
+				}
+			}
+			if voted := results.TotalVoters == 1 && len(results.Responses) == 1; voted != tc.wantVoted {
+				t.Errorf("voters = %d, responses = %+v, want voted %t", results.TotalVoters, results.Responses, tc.wantVoted)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := events.PollResultsCollected{
+		Question:    "Move the quiz?",
+		TotalVoters: 3,
+		Answers:     []events.PollAnswerResult{{ID: "yes", Text: "Yes", Count: 2}, {ID: "no", Text: "No", Count: 1}},
+	}
+	cases := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "en", want: "The poll has ended: Move the quiz? (3 votes)\nYes: 2\nNo: 1"},
+		{locale: "hi", want: "पोल समाप्त हो गया: Move the quiz? (3 वोट)\nYes: 2\nNo: 1"},
+		{locale: "ta-IN", want: "வாக்கெடுப்பு முடிந்தது: Move the quiz? (3 வாக்குகள்)\nYes: 2\nNo: 1"},
+	}
+	for _, tc := range cases {
+		if got := summarize(i18n.Default().Localizer(tc.locale), results); got != tc.want {
+			t.Errorf("summarize(%s) = %q, want %q", tc.locale, got, tc.want)
+		}
+	}
+}
+
+func TestHandleResponse(t *testing.T) {
+	cases := []struct {
+		name     string
+		resp     matrix.PollResponse
+		recorded bool
+	}{
+		{name: "vote on an open poll", resp: matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ana:example.org", At: closesAt.Add(-time.Hour)}, recorded: true},
+		{name: "vote after closes_at", resp: matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ana:example.org", At: closesAt.Add(time.Second)}},
+		{name: "vote from another room", resp: matrix.PollResponse{RoomID: "!other:example.org", PollEventID: "$poll", Sender: "@ana:example.org", At: closesAt.Add(-time.Hour)}},
+		{name: "the adapter's own vote", resp: matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@bot:example.org", At: closesAt.Add(-time.Hour)}},
+		{name: "unknown poll while one is being sent", resp: matrix.PollResponse{RoomID: "!pending:example.org", PollEventID: "$new", Sender: "@ana:example.org", At: closesAt}, recorded: true},
+		{name: "unknown poll", resp: matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$new", Sender: "@ana:example.org", At: closesAt}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, repo, _ := newTestService(t, true)
+			s.HandleResponse(context.Background(), tc.resp)
+			if recorded := len(repo.responses[tc.resp.PollEventID]) == 1; recorded != tc.recorded {
+				t.Errorf("recorded = %t, want %t", recorded, tc.recorded)
+			}
+		})
+	}
+}
+
+func TestHandleEnd(t *testing.T) {
+	cases := []struct {
+		name       string
+		canEnd     bool
+		at         time.Time
+		wantClosed bool
+		wantAt     time.Time
+	}{
+		{name: "ended early", canEnd: true, at: closesAt.Add(-30 * time.Minute), wantClosed: true, wantAt: closesAt.Add(-30 * time.Minute)},
+		{name: "ended after closes_at", canEnd: true, at: closesAt.Add(time.Hour), wantClosed: true, wantAt: closesAt},
+		{name: "sender lacks the power level", canEnd: false, at: closesAt.Add(-30 * time.Minute)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, repo, matrixRoom := newTestService(t, tc.canEnd)
+			ctx := context.Background()
+			s.HandleResponse(ctx, matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ana:example.org", Answers: []string{"yes"}, At: closesAt.Add(-time.Hour)})
+			s.HandleResponse(ctx, matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ben:example.org", Answers: []string{"no"}, At: closesAt.Add(-10 * time.Minute)})
+
+			s.HandleEnd(ctx, matrix.PollEnd{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@teacher:example.org", At: tc.at})
+			if closed := len(repo.closed) == 1; closed != tc.wantClosed {
+				t.Fatalf("closed = %t, want %t", closed, tc.wantClosed)
+			}
+			if len(matrixRoom.summaries) != 0 {
+				t.Errorf("an end event was sent for a poll the room already ended")
+			}
+			if !tc.wantClosed {
+				return
+			}
+			results := repo.closed[0]
+			if !results.ClosedAt.Equal(tc.wantAt) || results.ClosedBy != "@teacher:example.org" {
+				t.Errorf("closed at %s by %s, want %s by @teacher:example.org", results.ClosedAt, results.ClosedBy, tc.wantAt)
+			}
+			wantVoters := 1
+			if tc.wantAt.Equal(closesAt) {
+				wantVoters = 2
+			}
+			if results.TotalVoters != wantVoters {
+				t.Errorf("total voters = %d, want %d", results.TotalVoters, wantVoters)
+			}
+		})
+	}
+}
+
+func TestCloseDue(t *testing.T) {
+	s, repo, matrixRoom := newTestService(t, true)
+	ctx := context.Background()
+	s.HandleResponse(ctx, matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ana:example.org", Answers: []string{"yes"}, At: closesAt.Add(-time.Hour)})
+	s.HandleResponse(ctx, matrix.PollResponse{RoomID: "!room:example.org", PollEventID: "$poll", Sender: "@ben:example.org", Answers: []string{"no", "yes"}, At: closesAt.Add(-time.Minute)})
+
+	for range 2 {
+		if err := s.CloseDue(ctx); err != nil {
+			t.Fatalf("CloseDue: %v", err)
+		}
+	}
+	if len(repo.closed) != 1 {
+		t.Fatalf("emitted %d results, want 1", len(repo.closed))
+	}
+	results := repo.closed[0]
+	got := fmt.Sprintf("%s %s %s %d %+v %+v", results.PollID, results.OriginalEventID, results.ClosedBy, results.TotalVoters, results.Answers, results.Responses)
+	want := "p1 2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11 schedule 2 [{ID:yes Text:Yes Count:1} {ID:no Text:No Count:1}] [{MatrixUserID:@ana:example.org CR45UserID:cr45-ana Answers:[yes]} {MatrixUserID:@ben:example.org CR45UserID: Answers:[no]}]"
+	if got != want {
+		t.Errorf("results = %s\nwant %s", got, want)
+	}
+	if summary := matrixRoom.summaries["$poll"]; summary != "The poll has ended: Move the quiz? (2 votes)\nYes: 1\nNo: 1" {
+		t.Errorf("end summary = %q", summary)
+	}
+	if repo.endEvents["$poll"] != "$end-$poll" {
+		t.Errorf("end event = %q, want it recorded", repo.endEvents["$poll"])
+	}
+}
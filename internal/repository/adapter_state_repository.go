@@ -71,6 +71,17 @@ func (r *AdapterStateRepository) ClaimEvent(ctx context.Context, eventID string)
 	return attempts, true, nil
 }
 
+// SetEventRoom records the room eventID targets, once its payload has been
+// rendered.
+func (r *AdapterStateRepository) SetEventRoom(ctx context.Context, eventID, roomID string) error {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `UPDATE adapter_event_state SET room_id = $2 WHERE event_id = $1`, parsed, roomID)
+	return err
+}
+
 func (r *AdapterStateRepository) MarkSent(ctx context.Context, eventID string) error {
 	parsed, err := uuid.Parse(eventID)
 	if err != nil {
@@ -139,3 +150,284 @@ func (r *AdapterStateRepository) EmitDeliveryFailed(ctx context.Context, origina
 	_, err = r.db.ExecContext(ctx, query, uuid.New(), "DeliveryFailed", payloadBytes, time.Now().UTC())
 	return err
 }
+
+// DeliveredEvent is one Matrix event sent for an outbox event. Messages that
+// were split have one row per part. Anchor is the handler-assigned key later
+// messages use to find this one, e.g. to reply in its thread.
+type DeliveredEvent struct {
+	EventID       string
+	Part          int
+	PartCount     int
+	RoomID        string
+	MatrixEventID string
+	Anchor        string
+	SentAt        time.Time
+	RedactedAt    *time.Time
+}
+
+func (r *AdapterStateRepository) RecordDelivery(ctx context.Context, eventID string, part, partCount int, roomID, matrixEventID, anchor string) error {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO adapter_delivered_events (event_id, part, part_count, room_id, matrix_event_id, anchor, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (event_id, part) DO UPDATE
+		SET part_count = EXCLUDED.part_count,
+			room_id = EXCLUDED.room_id,
+			matrix_event_id = EXCLUDED.matrix_event_id,
+			anchor = EXCLUDED.anchor,
+			sent_at = EXCLUDED.sent_at,
+			origin_server_ts = NULL
+	`
+	_, err = r.db.ExecContext(ctx, query, parsed, part, partCount, roomID, matrixEventID, anchor, time.Now().UTC())
+	return err
+}
+
+// DeleteStaleDeliveries removes eventID's deliveries recorded with a part
+// count other than partCount, left behind when a retry splits the message
+// differently.
+func (r *AdapterStateRepository) DeleteStaleDeliveries(ctx context.Context, eventID string, partCount int) error {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	query := `
+		DELETE FROM adapter_delivered_events
+		WHERE event_id = $1 AND part_count <> $2
+	`
+	_, err = r.db.ExecContext(ctx, query, parsed, partCount)
+	return err
+}
+
+// DeliveredEventByMatrixID returns the delivery that produced matrixEventID.
+func (r *AdapterStateRepository) DeliveredEventByMatrixID(ctx context.Context, matrixEventID string) (DeliveredEvent, bool, error) {
+	query := `
+		SELECT event_id, part, part_count, room_id, matrix_event_id, anchor, sent_at, redacted_at
+		FROM adapter_delivered_events
+		WHERE matrix_event_id = $1
+	`
+	var d DeliveredEvent
+	var redactedAt sql.NullTime
+	row := r.db.QueryRowContext(ctx, query, matrixEventID)
+	if err := row.Scan(&d.EventID, &d.Part, &d.PartCount, &d.RoomID, &d.MatrixEventID, &d.Anchor, &d.SentAt, &redactedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeliveredEvent{}, false, nil
+		}
+		return DeliveredEvent{}, false, err
+	}
+	if redactedAt.Valid {
+		d.RedactedAt = &redactedAt.Time
+	}
+	return d, true, nil
+}
+
+// DeliveredEvents returns the Matrix events recorded for an outbox event,
+// ordered by part.
+func (r *AdapterStateRepository) DeliveredEvents(ctx context.Context, eventID string) ([]DeliveredEvent, error) {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT event_id, part, part_count, room_id, matrix_event_id, anchor, sent_at, redacted_at
+		FROM adapter_delivered_events
+		WHERE event_id = $1
+		ORDER BY part
+	`
+	rows, err := r.db.QueryContext(ctx, query, parsed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delivered []DeliveredEvent
+	for rows.Next() {
+		var d DeliveredEvent
+		var redactedAt sql.NullTime
+		if err := rows.Scan(&d.EventID, &d.Part, &d.PartCount, &d.RoomID, &d.MatrixEventID, &d.Anchor, &d.SentAt, &redactedAt); err != nil {
+			return nil, err
+		}
+		if redactedAt.Valid {
+			d.RedactedAt = &redactedAt.Time
+		}
+		delivered = append(delivered, d)
+	}
+	return delivered, rows.Err()
+}
+
+// AnchorEvent returns the Matrix event ID of the first part of the latest
+// message delivered to roomID under anchor. found is false when none was.
+func (r *AdapterStateRepository) AnchorEvent(ctx context.Context, roomID, anchor string) (string, bool, error) {
+	query := `
+		SELECT matrix_event_id
+		FROM adapter_delivered_events
+		WHERE room_id = $1 AND anchor = $2 AND part = 1 AND redacted_at IS NULL
+		ORDER BY sent_at DESC
+		LIMIT 1
+	`
+	var matrixEventID string
+	if err := r.db.QueryRowContext(ctx, query, roomID, anchor).Scan(&matrixEventID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return matrixEventID, true, nil
+}
+
+// MarkRedacted records that a delivered Matrix event was redacted in response
+// to the retraction outbox event retractionEventID.
+func (r *AdapterStateRepository) MarkRedacted(ctx context.Context, matrixEventID, retractionEventID, redactionEventID, reason string) error {
+	parsed, err := uuid.Parse(retractionEventID)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE adapter_delivered_events
+		SET redacted_at = $2,
+			redacted_by = $3,
+			redaction_event_id = $4,
+			redaction_reason = $5
+		WHERE matrix_event_id = $1
+	`
+	_, err = r.db.ExecContext(ctx, query, matrixEventID, time.Now().UTC(), parsed, redactionEventID, reason)
+	return err
+}
+
+// PinnedEvent returns the Matrix event the adapter last pinned in roomID.
+func (r *AdapterStateRepository) PinnedEvent(ctx context.Context, roomID string) (string, bool, error) {
+	query := `
+		SELECT matrix_event_id
+		FROM adapter_room_pins
+		WHERE room_id = $1
+	`
+	var matrixEventID string
+	if err := r.db.QueryRowContext(ctx, query, roomID).Scan(&matrixEventID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return matrixEventID, true, nil
+}
+
+func (r *AdapterStateRepository) SetPinnedEvent(ctx context.Context, roomID, matrixEventID string) error {
+	query := `
+		INSERT INTO adapter_room_pins (room_id, matrix_event_id, pinned_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (room_id) DO UPDATE
+		SET matrix_event_id = EXCLUDED.matrix_event_id,
+			pinned_at = EXCLUDED.pinned_at
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID, matrixEventID, time.Now().UTC())
+	return err
+}
+
+// EmitMessageAcknowledged records a reaction and emits MessageAcknowledged for
+// it in one transaction. emitted is false when the reaction was already
+// recorded, e.g. when sync replays history after a restart.
+func (r *AdapterStateRepository) EmitMessageAcknowledged(ctx context.Context, reactionEventID string, ack events.MessageAcknowledged) (emitted bool, err error) {
+	parsed, err := uuid.Parse(ack.OriginalEventID)
+	if err != nil {
+		return false, err
+	}
+	payloadBytes, err := json.Marshal(ack)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO adapter_acknowledgements (reaction_event_id, event_id, room_id, matrix_event_id, matrix_user_id, reaction_key, reacted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reaction_event_id) DO NOTHING
+	`, reactionEventID, parsed, ack.MatrixRoomID, ack.MatrixEventID, ack.MatrixUserID, ack.ReactionKey, ack.ReactedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, tx.Rollback()
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, r.outboxTable)
+	if _, err = tx.ExecContext(ctx, query, uuid.New(), "MessageAcknowledged", payloadBytes, time.Now().UTC()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// AnchoredEvents returns the first parts of unredacted messages delivered to
+// roomID whose anchor matches the SQL LIKE pattern, newest first.
+func (r *AdapterStateRepository) AnchoredEvents(ctx context.Context, roomID, anchorPattern string) ([]DeliveredEvent, error) {
+	query := `
+		SELECT event_id, part, part_count, room_id, matrix_event_id, anchor, sent_at
+		FROM adapter_delivered_events
+		WHERE room_id = $1 AND anchor LIKE $2 AND part = 1 AND redacted_at IS NULL
+		ORDER BY sent_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, roomID, anchorPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delivered []DeliveredEvent
+	for rows.Next() {
+		var d DeliveredEvent
+		if err := rows.Scan(&d.EventID, &d.Part, &d.PartCount, &d.RoomID, &d.MatrixEventID, &d.Anchor, &d.SentAt); err != nil {
+			return nil, err
+		}
+		delivered = append(delivered, d)
+	}
+	return delivered, rows.Err()
+}
+
+// EventState is the adapter's processing state for one outbox event.
+type EventState struct {
+	EventID   string
+	RoomID    string
+	Status    string
+	Attempts  int
+	LastError string
+	UpdatedAt time.Time
+}
+
+// EventState returns the processing state of eventID.
+func (r *AdapterStateRepository) EventState(ctx context.Context, eventID string) (EventState, bool, error) {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return EventState{}, false, err
+	}
+	query := `
+		SELECT event_id, COALESCE(room_id, ''), status, attempts, COALESCE(last_error, ''), updated_at
+		FROM adapter_event_state
+		WHERE event_id = $1
+	`
+	var state EventState
+	row := r.db.QueryRowContext(ctx, query, parsed)
+	if err := row.Scan(&state.EventID, &state.RoomID, &state.Status, &state.Attempts, &state.LastError, &state.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return EventState{}, false, nil
+		}
+		return EventState{}, false, err
+	}
+	return state, true, nil
+}
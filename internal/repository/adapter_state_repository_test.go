@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+
+	"github.com/google/uuid"
+)
+
+func TestAdapterStateRepositoryAnchorEvent(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewAdapterStateRepository(testDB(t, "adapter_delivered_events"), "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const room, anchor = "!a:example.org", "DailyTimetableAnnounced:cse-3a:2024-10-14"
+
+	if _, found, err := repo.AnchorEvent(ctx, room, anchor); found || err != nil {
+		t.Fatalf("AnchorEvent before any delivery = %t, %v", found, err)
+	}
+	first, second := uuid.NewString(), uuid.NewString()
+	deliveries := []struct {
+		eventID       string
+		part, count   int
+		roomID        string
+		matrixEventID string
+	}{
+		{first, 1, 2, room, "$first-1"},
+		{first, 2, 2, room, "$first-2"},
+		{second, 1, 1, room, "$second"},
+		{uuid.NewString(), 1, 1, "!b:example.org", "$other-room"},
+	}
+	for _, d := range deliveries {
+		if err := repo.RecordDelivery(ctx, d.eventID, d.part, d.count, d.roomID, d.matrixEventID, anchor); err != nil {
+			t.Fatalf("RecordDelivery: %v", err)
+		}
+	}
+
+	if got, found, err := repo.AnchorEvent(ctx, room, anchor); got != "$second" || !found || err != nil {
+		t.Errorf("AnchorEvent = %q, %t, %v; want the latest first part", got, found, err)
+	}
+	if err := repo.MarkRedacted(ctx, "$second", uuid.NewString(), "$redaction", "retracted"); err != nil {
+		t.Fatalf("MarkRedacted: %v", err)
+	}
+	if got, _, _ := repo.AnchorEvent(ctx, room, anchor); got != "$first-1" {
+		t.Errorf("AnchorEvent after redaction = %q, want $first-1", got)
+	}
+	if _, found, _ := repo.AnchorEvent(ctx, room, "DailyTimetableAnnounced:cse-3a:2024-10-15"); found {
+		t.Error("AnchorEvent found an anchor that was never delivered")
+	}
+}
+
+func TestAdapterStateRepositoryMarkRedacted(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewAdapterStateRepository(testDB(t, "adapter_delivered_events"), "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := uuid.NewString()
+	for part, matrixEventID := range []string{"$part1", "$part2"} {
+		if err := repo.RecordDelivery(ctx, original, part+1, 2, "!a:example.org", matrixEventID, ""); err != nil {
+			t.Fatalf("RecordDelivery: %v", err)
+		}
+	}
+	if err := repo.MarkRedacted(ctx, "$part2", uuid.NewString(), "$redaction", "posted by mistake"); err != nil {
+		t.Fatalf("MarkRedacted: %v", err)
+	}
+
+	delivered, err := repo.DeliveredEvents(ctx, original)
+	if err != nil || len(delivered) != 2 {
+		t.Fatalf("DeliveredEvents = %+v, %v", delivered, err)
+	}
+	if delivered[0].MatrixEventID != "$part1" || delivered[0].RedactedAt != nil || delivered[1].RedactedAt == nil {
+		t.Errorf("DeliveredEvents = %+v, want only part 2 redacted", delivered)
+	}
+	if d, found, err := repo.DeliveredEventByMatrixID(ctx, "$part2"); !found || err != nil || d.RedactedAt == nil || d.Part != 2 {
+		t.Errorf("DeliveredEventByMatrixID = %+v, %t, %v", d, found, err)
+	}
+}
+
+func TestAdapterStateRepositoryPinnedEvent(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewAdapterStateRepository(testDB(t, "adapter_room_pins"), "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := repo.PinnedEvent(ctx, "!a:example.org"); found || err != nil {
+		t.Fatalf("PinnedEvent before any pin = %t, %v", found, err)
+	}
+	for _, matrixEventID := range []string{"$yesterday", "$today"} {
+		if err := repo.SetPinnedEvent(ctx, "!a:example.org", matrixEventID); err != nil {
+			t.Fatalf("SetPinnedEvent: %v", err)
+		}
+	}
+	if got, found, err := repo.PinnedEvent(ctx, "!a:example.org"); got != "$today" || !found || err != nil {
+		t.Errorf("PinnedEvent = %q, %t, %v; want the latest pin", got, found, err)
+	}
+}
+
+func TestAdapterStateRepositoryEmitMessageAcknowledged(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "adapter_acknowledgements", "adapter_outbox")
+	repo, err := NewAdapterStateRepository(db, "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack := events.MessageAcknowledged{
+		OriginalEventID: uuid.NewString(),
+		Adapter:         "adapter-matrix",
+		MatrixRoomID:    "!a:example.org",
+		MatrixEventID:   "$part1",
+		MatrixUserID:    "@ana:example.org",
+		ReactionKey:     "👍",
+		ReactedAt:       time.Date(2024, 10, 14, 3, 35, 0, 0, time.UTC),
+	}
+	for i, want := range []bool{true, false} {
+		emitted, err := repo.EmitMessageAcknowledged(ctx, "$reaction", ack)
+		if err != nil || emitted != want {
+			t.Fatalf("EmitMessageAcknowledged #%d = %t, %v; want %t", i+1, emitted, err, want)
+		}
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM adapter_outbox WHERE event_type = 'MessageAcknowledged'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("outbox has %d MessageAcknowledged rows, want 1 for a replayed reaction", count)
+	}
+}
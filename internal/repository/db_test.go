@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"adapter-matrix/migrations"
+)
+
+// testDB returns a migrated database with tables emptied, or skips the test
+// when ADAPTER_MATRIX_TEST_DATABASE_URL is unset. Point it at a throwaway
+// database: the tables are truncated.
+func testDB(t *testing.T, tables ...string) *sql.DB {
+	t.Helper()
+	url := os.Getenv("ADAPTER_MATRIX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("ADAPTER_MATRIX_TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(tables) > 0 {
+		if _, err := db.Exec(fmt.Sprintf("TRUNCATE %s", strings.Join(tables, ", "))); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+	}
+	return db
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type CourseEntry struct {
+	CourseCode string
+	Title      string
+	Faculty    string
+}
+
+type VenueEntry struct {
+	VenueCode string
+	Building  string
+	Floor     string
+	MapURL    string
+}
+
+// DirectoryRepository reads the course and venue directories used to enrich
+// timetable announcements.
+type DirectoryRepository struct {
+	db *sql.DB
+}
+
+func NewDirectoryRepository(db *sql.DB) (*DirectoryRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &DirectoryRepository{db: db}, nil
+}
+
+// Courses returns the directory entries for codes, keyed by course code.
+// Codes without an entry are absent from the map.
+func (r *DirectoryRepository) Courses(ctx context.Context, codes []string) (map[string]CourseEntry, error) {
+	out := make(map[string]CourseEntry, len(codes))
+	if len(codes) == 0 {
+		return out, nil
+	}
+	query := `
+		SELECT course_code, title, faculty
+		FROM adapter_course_directory
+		WHERE course_code = ANY($1)
+	`
+	rows, err := r.db.QueryContext(ctx, query, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry CourseEntry
+		if err := rows.Scan(&entry.CourseCode, &entry.Title, &entry.Faculty); err != nil {
+			return nil, err
+		}
+		out[entry.CourseCode] = entry
+	}
+	return out, rows.Err()
+}
+
+// Venues returns the directory entries for codes, keyed by venue code.
+// Codes without an entry are absent from the map.
+func (r *DirectoryRepository) Venues(ctx context.Context, codes []string) (map[string]VenueEntry, error) {
+	out := make(map[string]VenueEntry, len(codes))
+	if len(codes) == 0 {
+		return out, nil
+	}
+	query := `
+		SELECT venue_code, building, floor, map_url
+		FROM adapter_venue_directory
+		WHERE venue_code = ANY($1)
+	`
+	rows, err := r.db.QueryContext(ctx, query, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry VenueEntry
+		if err := rows.Scan(&entry.VenueCode, &entry.Building, &entry.Floor, &entry.MapURL); err != nil {
+			return nil, err
+		}
+		out[entry.VenueCode] = entry
+	}
+	return out, rows.Err()
+}
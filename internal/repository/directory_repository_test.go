@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDirectoryRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "adapter_course_directory", "adapter_venue_directory")
+	repo, err := NewDirectoryRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO adapter_course_directory (course_code, title, faculty) VALUES ('CS301', 'Operating Systems', 'Dr. X'), ('MA201', 'Linear Algebra', '');
+		INSERT INTO adapter_venue_directory (venue_code, building, map_url) VALUES ('LHC-2', 'Lecture Hall Complex 2', 'https://maps.example.org/lhc-2');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	courses, err := repo.Courses(ctx, []string{"CS301", "EE101"})
+	if err != nil {
+		t.Fatalf("Courses: %v", err)
+	}
+	if len(courses) != 1 || courses["CS301"] != (CourseEntry{CourseCode: "CS301", Title: "Operating Systems", Faculty: "Dr. X"}) {
+		t.Errorf("Courses = %+v", courses)
+	}
+	venues, err := repo.Venues(ctx, []string{"LHC-2"})
+	if err != nil {
+		t.Fatalf("Venues: %v", err)
+	}
+	if venues["LHC-2"] != (VenueEntry{VenueCode: "LHC-2", Building: "Lecture Hall Complex 2", MapURL: "https://maps.example.org/lhc-2"}) {
+		t.Errorf("Venues = %+v", venues)
+	}
+	if got, err := repo.Courses(ctx, nil); len(got) != 0 || err != nil {
+		t.Errorf("Courses(nil) = %+v, %v", got, err)
+	}
+}
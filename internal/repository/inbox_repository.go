@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter-matrix/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// InboxRepository writes messages received from Matrix to the inbox table,
+// which CR45 consumes like an outbox. The table must have a unique
+// matrix_event_id column; see migrations/017_adapter_inbox.sql.
+type InboxRepository struct {
+	db    *sql.DB
+	table string
+}
+
+func NewInboxRepository(db *sql.DB, table string) (*InboxRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if !IsValidTableName(table) {
+		return nil, errors.New("inbox table name contains invalid characters")
+	}
+	return &InboxRepository{db: db, table: table}, nil
+}
+
+// Insert writes msg as a MessageReceived row. It reports false when the
+// Matrix event was already in the inbox.
+func (r *InboxRepository) Insert(ctx context.Context, msg events.MessageReceived) (bool, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, event_type, matrix_event_id, room_id, sender, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (matrix_event_id) DO NOTHING
+	`, r.table)
+	res, err := r.db.ExecContext(ctx, query, uuid.New(), "MessageReceived", msg.MatrixEventID, msg.MatrixRoomID, msg.MatrixUserID, payload, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
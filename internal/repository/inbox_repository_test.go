@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+)
+
+func TestInboxRepositoryInsert(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "adapter_inbox")
+	repo, err := NewInboxRepository(db, "adapter_inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := events.MessageReceived{
+		Adapter:       "adapter-matrix",
+		MatrixRoomID:  "!class:example.org",
+		MatrixEventID: "$1",
+		MatrixUserID:  "@ana:example.org",
+		MsgType:       "m.text",
+		Body:          "Is CS301 on?",
+		SentAt:        time.Date(2024, 10, 14, 4, 0, 0, 0, time.UTC),
+	}
+
+	if inserted, err := repo.Insert(ctx, msg); !inserted || err != nil {
+		t.Fatalf("Insert = %t, %v", inserted, err)
+	}
+	msg.Body = "replayed"
+	if inserted, err := repo.Insert(ctx, msg); inserted || err != nil {
+		t.Fatalf("Insert of a replayed event = %t, %v, want false", inserted, err)
+	}
+
+	var count int
+	var payload []byte
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*), MIN(payload::text) FROM adapter_inbox WHERE matrix_event_id = '$1'`).Scan(&count, &payload); err != nil {
+		t.Fatal(err)
+	}
+	var got events.MessageReceived
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || got.Body != "Is CS301 on?" {
+		t.Errorf("rows = %d with body %q, want the first write kept once", count, got.Body)
+	}
+
+	if _, err := NewInboxRepository(db, "adapter_inbox; DROP TABLE x"); err == nil {
+		t.Error("NewInboxRepository accepted an invalid table name")
+	}
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Room state kinds kept by MatrixStoreRepository, each the JSON content of
+// the room's state event of that type.
+const (
+	RoomStatePowerLevels = "power_levels"
+	RoomStateEncryption  = "encryption"
+	RoomStateCreate      = "create_event"
+	RoomStateJoinRules   = "join_rules"
+)
+
+var roomStateColumns = map[string]struct{}{
+	RoomStatePowerLevels: {},
+	RoomStateEncryption:  {},
+	RoomStateCreate:      {},
+	RoomStateJoinRules:   {},
+}
+
+// RoomMember is a cached m.room.member state event.
+type RoomMember struct {
+	UserID     string
+	Membership string
+	Content    []byte
+}
+
+// MatrixStoreRepository persists the Matrix client's sync tokens and the
+// room state it caches, so a restart resumes sync where it stopped.
+type MatrixStoreRepository struct {
+	db *sql.DB
+}
+
+func NewMatrixStoreRepository(db *sql.DB) (*MatrixStoreRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &MatrixStoreRepository{db: db}, nil
+}
+
+func (r *MatrixStoreRepository) FilterID(ctx context.Context, userID string) (string, error) {
+	return r.syncToken(ctx, userID, "filter_id")
+}
+
+func (r *MatrixStoreRepository) SetFilterID(ctx context.Context, userID, filterID string) error {
+	return r.setSyncToken(ctx, userID, "filter_id", filterID)
+}
+
+func (r *MatrixStoreRepository) NextBatch(ctx context.Context, userID string) (string, error) {
+	return r.syncToken(ctx, userID, "next_batch")
+}
+
+func (r *MatrixStoreRepository) SetNextBatch(ctx context.Context, userID, token string) error {
+	return r.setSyncToken(ctx, userID, "next_batch", token)
+}
+
+// column is one of the fixed adapter_sync_tokens columns above.
+func (r *MatrixStoreRepository) syncToken(ctx context.Context, userID, column string) (string, error) {
+	query := fmt.Sprintf(`SELECT %s FROM adapter_sync_tokens WHERE user_id = $1`, column)
+	var value string
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (r *MatrixStoreRepository) setSyncToken(ctx context.Context, userID, column, value string) error {
+	query := fmt.Sprintf(`
+		INSERT INTO adapter_sync_tokens (user_id, %[1]s, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET %[1]s = EXCLUDED.%[1]s, updated_at = EXCLUDED.updated_at
+	`, column)
+	_, err := r.db.ExecContext(ctx, query, userID, value, time.Now().UTC())
+	return err
+}
+
+// Member returns userID's cached membership in roomID.
+func (r *MatrixStoreRepository) Member(ctx context.Context, roomID, userID string) (RoomMember, bool, error) {
+	query := `
+		SELECT user_id, membership, content
+		FROM adapter_room_members
+		WHERE room_id = $1 AND user_id = $2
+	`
+	var m RoomMember
+	err := r.db.QueryRowContext(ctx, query, roomID, userID).Scan(&m.UserID, &m.Membership, &m.Content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RoomMember{}, false, nil
+	}
+	if err != nil {
+		return RoomMember{}, false, err
+	}
+	return m, true, nil
+}
+
+// Members returns the cached members of roomID with one of memberships, or
+// all of them when memberships is empty.
+func (r *MatrixStoreRepository) Members(ctx context.Context, roomID string, memberships []string) ([]RoomMember, error) {
+	query := `
+		SELECT user_id, membership, content
+		FROM adapter_room_members
+		WHERE room_id = $1 AND (cardinality($2::text[]) = 0 OR membership = ANY($2))
+		ORDER BY user_id
+	`
+	if memberships == nil {
+		memberships = []string{}
+	}
+	rows, err := r.db.QueryContext(ctx, query, roomID, memberships)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RoomMember
+	for rows.Next() {
+		var m RoomMember
+		if err := rows.Scan(&m.UserID, &m.Membership, &m.Content); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *MatrixStoreRepository) SetMember(ctx context.Context, roomID string, member RoomMember) error {
+	query := `
+		INSERT INTO adapter_room_members (room_id, user_id, membership, content)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (room_id, user_id) DO UPDATE
+		SET membership = EXCLUDED.membership, content = EXCLUDED.content
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID, member.UserID, member.Membership, member.Content)
+	return err
+}
+
+// SetMembership changes userID's membership in roomID, keeping the rest of
+// any cached member content.
+func (r *MatrixStoreRepository) SetMembership(ctx context.Context, roomID, userID, membership string) error {
+	query := `
+		INSERT INTO adapter_room_members (room_id, user_id, membership, content)
+		VALUES ($1, $2, $3, jsonb_build_object('membership', $3::text))
+		ON CONFLICT (room_id, user_id) DO UPDATE
+		SET membership = EXCLUDED.membership,
+			content = adapter_room_members.content || jsonb_build_object('membership', EXCLUDED.membership)
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID, userID, membership)
+	return err
+}
+
+// ClearMembers drops the cached members of roomID with one of memberships
+// (all of them when empty) and marks the member list as not fetched.
+func (r *MatrixStoreRepository) ClearMembers(ctx context.Context, roomID string, memberships []string) (err error) {
+	if memberships == nil {
+		memberships = []string{}
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM adapter_room_members
+		WHERE room_id = $1 AND (cardinality($2::text[]) = 0 OR membership = ANY($2))
+	`, roomID, memberships); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE adapter_room_state SET members_fetched = false WHERE room_id = $1
+	`, roomID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MatrixStoreRepository) MembersFetched(ctx context.Context, roomID string) (bool, error) {
+	var fetched bool
+	err := r.db.QueryRowContext(ctx, `SELECT members_fetched FROM adapter_room_state WHERE room_id = $1`, roomID).Scan(&fetched)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return fetched, err
+}
+
+func (r *MatrixStoreRepository) MarkMembersFetched(ctx context.Context, roomID string) error {
+	query := `
+		INSERT INTO adapter_room_state (room_id, members_fetched)
+		VALUES ($1, true)
+		ON CONFLICT (room_id) DO UPDATE SET members_fetched = true
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID)
+	return err
+}
+
+// RoomState returns the cached content of one of the RoomState kinds, or
+// nil when none is cached.
+func (r *MatrixStoreRepository) RoomState(ctx context.Context, roomID, kind string) ([]byte, error) {
+	if _, ok := roomStateColumns[kind]; !ok {
+		return nil, fmt.Errorf("unknown room state %q", kind)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM adapter_room_state WHERE room_id = $1`, kind)
+	var content []byte
+	err := r.db.QueryRowContext(ctx, query, roomID).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (r *MatrixStoreRepository) SetRoomState(ctx context.Context, roomID, kind string, content []byte) error {
+	if _, ok := roomStateColumns[kind]; !ok {
+		return fmt.Errorf("unknown room state %q", kind)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO adapter_room_state (room_id, %[1]s)
+		VALUES ($1, $2)
+		ON CONFLICT (room_id) DO UPDATE SET %[1]s = EXCLUDED.%[1]s
+	`, kind)
+	_, err := r.db.ExecContext(ctx, query, roomID, content)
+	return err
+}
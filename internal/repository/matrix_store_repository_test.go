@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMatrixStoreRepositorySyncTokens(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewMatrixStoreRepository(testDB(t, "adapter_sync_tokens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := repo.NextBatch(ctx, "@bot:example.org"); got != "" || err != nil {
+		t.Fatalf("NextBatch before any write = %q, %v", got, err)
+	}
+	if err := repo.SetFilterID(ctx, "@bot:example.org", "f1"); err != nil {
+		t.Fatalf("SetFilterID: %v", err)
+	}
+	for _, token := range []string{"s1", "s2"} {
+		if err := repo.SetNextBatch(ctx, "@bot:example.org", token); err != nil {
+			t.Fatalf("SetNextBatch: %v", err)
+		}
+	}
+	if got, _ := repo.NextBatch(ctx, "@bot:example.org"); got != "s2" {
+		t.Errorf("NextBatch = %q, want s2", got)
+	}
+	if got, _ := repo.FilterID(ctx, "@bot:example.org"); got != "f1" {
+		t.Errorf("FilterID = %q, want f1 kept across next_batch writes", got)
+	}
+}
+
+func TestMatrixStoreRepositoryMembers(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewMatrixStoreRepository(testDB(t, "adapter_room_members", "adapter_room_state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const room = "!room:example.org"
+
+	if err := repo.SetMember(ctx, room, RoomMember{UserID: "@ana:example.org", Membership: "join", Content: []byte(`{"membership":"join","displayname":"Ana"}`)}); err != nil {
+		t.Fatalf("SetMember: %v", err)
+	}
+	if err := repo.SetMembership(ctx, room, "@ben:example.org", "invite"); err != nil {
+		t.Fatalf("SetMembership new member: %v", err)
+	}
+	if err := repo.SetMembership(ctx, room, "@ana:example.org", "leave"); err != nil {
+		t.Fatalf("SetMembership existing member: %v", err)
+	}
+
+	ana, found, err := repo.Member(ctx, room, "@ana:example.org")
+	if err != nil || !found || ana.Membership != "leave" {
+		t.Fatalf("Member = %+v, %t, %v", ana, found, err)
+	}
+	var content map[string]string
+	if err := json.Unmarshal(ana.Content, &content); err != nil || content["displayname"] != "Ana" || content["membership"] != "leave" {
+		t.Errorf("content = %s, want the profile kept and membership updated", ana.Content)
+	}
+	if _, found, err := repo.Member(ctx, room, "@nobody:example.org"); found || err != nil {
+		t.Errorf("Member(unknown) found = %t, %v", found, err)
+	}
+
+	cases := []struct {
+		memberships []string
+		want        []string
+	}{
+		{nil, []string{"@ana:example.org", "@ben:example.org"}},
+		{[]string{"join", "invite"}, []string{"@ben:example.org"}},
+		{[]string{"ban"}, nil},
+	}
+	for _, tc := range cases {
+		rows, err := repo.Members(ctx, room, tc.memberships)
+		if err != nil {
+			t.Fatalf("Members(%v): %v", tc.memberships, err)
+		}
+		var got []string
+		for _, row := range rows {
+			got = append(got, row.UserID)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("Members(%v) = %v, want %v", tc.memberships, got, tc.want)
+		}
+	}
+
+	if err := repo.MarkMembersFetched(ctx, room); err != nil {
+		t.Fatalf("MarkMembersFetched: %v", err)
+	}
+	if fetched, _ := repo.MembersFetched(ctx, room); !fetched {
+		t.Error("MembersFetched = false after MarkMembersFetched")
+	}
+	if err := repo.ClearMembers(ctx, room, []string{"invite"}); err != nil {
+		t.Fatalf("ClearMembers: %v", err)
+	}
+	if rows, _ := repo.Members(ctx, room, nil); len(rows) != 1 || rows[0].UserID != "@ana:example.org" {
+		t.Errorf("Members after clearing invites = %+v", rows)
+	}
+	if fetched, _ := repo.MembersFetched(ctx, room); fetched {
+		t.Error("MembersFetched = true after ClearMembers")
+	}
+}
+
+func TestMatrixStoreRepositoryRoomState(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewMatrixStoreRepository(testDB(t, "adapter_room_state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const room = "!room:example.org"
+
+	if got, err := repo.RoomState(ctx, room, RoomStatePowerLevels); got != nil || err != nil {
+		t.Fatalf("RoomState before any write = %s, %v", got, err)
+	}
+	if err := repo.SetRoomState(ctx, room, RoomStatePowerLevels, []byte(`{"users":{"@bot:example.org":50}}`)); err != nil {
+		t.Fatalf("SetRoomState: %v", err)
+	}
+	if err := repo.SetRoomState(ctx, room, RoomStateCreate, []byte(`{"type":"m.room.create"}`)); err != nil {
+		t.Fatalf("SetRoomState: %v", err)
+	}
+	got, err := repo.RoomState(ctx, room, RoomStatePowerLevels)
+	var levels struct {
+		Users map[string]int `json:"users"`
+	}
+	if err != nil || json.Unmarshal(got, &levels) != nil || levels.Users["@bot:example.org"] != 50 {
+		t.Errorf("RoomState(power_levels) = %s, %v", got, err)
+	}
+	if got, _ := repo.RoomState(ctx, room, RoomStateJoinRules); got != nil {
+		t.Errorf("RoomState(join_rules) = %s, want nil", got)
+	}
+	if _, err := repo.RoomState(ctx, room, "users; DROP TABLE x"); err == nil {
+		t.Error("RoomState accepted an unknown state kind")
+	}
+	if err := repo.SetRoomState(ctx, room, "topic", nil); err == nil {
+		t.Error("SetRoomState accepted an unknown state kind")
+	}
+}
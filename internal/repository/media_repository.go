@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MediaRepository reads file contents from bytea columns that producers
+// reference in payloads. Only allow-listed table.column pairs are readable.
+type MediaRepository struct {
+	db      *sql.DB
+	allowed map[string]struct{}
+}
+
+// NewMediaRepository accepts columns as "table.column" or
+// "schema.table.column".
+func NewMediaRepository(db *sql.DB, columns []string) (*MediaRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	allowed := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		trimmed := strings.TrimSpace(column)
+		if !strings.Contains(trimmed, ".") || !IsValidTableName(trimmed) {
+			return nil, fmt.Errorf("media column %q must look like table.column", column)
+		}
+		allowed[trimmed] = struct{}{}
+	}
+	return &MediaRepository{db: db, allowed: allowed}, nil
+}
+
+// Bytea returns column of the row in table whose id matches rowID.
+func (r *MediaRepository) Bytea(ctx context.Context, table, column, rowID string) ([]byte, error) {
+	if strings.Contains(column, ".") {
+		return nil, errors.New("media column name contains invalid characters")
+	}
+	if _, ok := r.allowed[table+"."+column]; !ok {
+		return nil, fmt.Errorf("media column %s.%s is not allow-listed", table, column)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id::text = $1", column, table)
+	var data []byte
+	if err := r.db.QueryRowContext(ctx, query, rowID).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no row %s in %s", rowID, table)
+		}
+		return nil, err
+	}
+	return data, nil
+}
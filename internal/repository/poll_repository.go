@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter-matrix/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// Poll is a poll the adapter started, keyed by its outbox event ID.
+// PollEventID is empty until the poll has been sent to the room.
+type Poll struct {
+	PollEventID   string
+	EventID       string
+	PollID        string
+	RoomID        string
+	Question      string
+	Answers       []PollAnswer
+	MaxSelections int
+	Context       json.RawMessage
+	ClosesAt      time.Time
+	ClosedAt      *time.Time
+}
+
+type PollAnswer struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// PollResponse is a voter's latest response to a poll.
+type PollResponse struct {
+	Sender      string
+	Answers     []string
+	RespondedAt time.Time
+}
+
+type PollRepository struct {
+	db          *sql.DB
+	outboxTable string
+}
+
+func NewPollRepository(db *sql.DB, outboxTable string) (*PollRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if !IsValidTableName(outboxTable) {
+		return nil, errors.New("outbox table name contains invalid characters")
+	}
+	return &PollRepository{db: db, outboxTable: outboxTable}, nil
+}
+
+// Create stores a poll before it is sent, so votes that reach the sync loop
+// before SetPollEvent are not lost. Creating the same poll twice is a no-op.
+func (r *PollRepository) Create(ctx context.Context, poll Poll) error {
+	parsed, err := uuid.Parse(poll.EventID)
+	if err != nil {
+		return err
+	}
+	answers, err := json.Marshal(poll.Answers)
+	if err != nil {
+		return err
+	}
+	var pollContext any
+	if len(poll.Context) > 0 {
+		pollContext = []byte(poll.Context)
+	}
+	query := `
+		INSERT INTO adapter_polls (event_id, poll_id, room_id, question, answers, max_selections, context, closes_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id) DO NOTHING
+	`
+	_, err = r.db.ExecContext(ctx, query, parsed, poll.PollID, poll.RoomID, poll.Question, answers, poll.MaxSelections, pollContext, poll.ClosesAt.UTC(), time.Now().UTC())
+	return err
+}
+
+// SetPollEvent records the Matrix event ID the poll was sent as.
+func (r *PollRepository) SetPollEvent(ctx context.Context, eventID, pollEventID string) error {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE adapter_polls
+		SET poll_event_id = $2
+		WHERE event_id = $1
+	`, parsed, pollEventID)
+	return err
+}
+
+// HasUnsent reports whether roomID has a poll still being sent, whose Matrix
+// event ID is not known yet.
+func (r *PollRepository) HasUnsent(ctx context.Context, roomID string) (bool, error) {
+	var unsent bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM adapter_polls
+			WHERE room_id = $1 AND poll_event_id IS NULL AND closed_at IS NULL
+		)
+	`, roomID).Scan(&unsent)
+	return unsent, err
+}
+
+// Get returns the poll started as pollEventID.
+func (r *PollRepository) Get(ctx context.Context, pollEventID string) (Poll, bool, error) {
+	query := `
+		SELECT poll_event_id, event_id, poll_id, room_id, question, answers, max_selections, context, closes_at, closed_at
+		FROM adapter_polls
+		WHERE poll_event_id = $1
+	`
+	poll, err := scanPoll(r.db.QueryRowContext(ctx, query, pollEventID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Poll{}, false, nil
+		}
+		return Poll{}, false, err
+	}
+	return poll, true, nil
+}
+
+// DueForClose returns open polls whose closes_at is at or before now.
+func (r *PollRepository) DueForClose(ctx context.Context, now time.Time) ([]Poll, error) {
+	query := `
+		SELECT poll_event_id, event_id, poll_id, room_id, question, answers, max_selections, context, closes_at, closed_at
+		FROM adapter_polls
+		WHERE closed_at IS NULL AND closes_at <= $1 AND poll_event_id IS NOT NULL
+		ORDER BY closes_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []Poll
+	for rows.Next() {
+		poll, err := scanPoll(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, poll)
+	}
+	return due, rows.Err()
+}
+
+// RecordResponse stores sender's response, replacing an older one. A
+// response older than the stored one is ignored, so replayed sync history
+// cannot undo a changed vote.
+func (r *PollRepository) RecordResponse(ctx context.Context, pollEventID, sender, responseEventID string, answers []string, respondedAt time.Time) error {
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO adapter_poll_responses (poll_event_id, sender, answers, response_event_id, responded_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (poll_event_id, sender) DO UPDATE
+		SET answers = EXCLUDED.answers,
+			response_event_id = EXCLUDED.response_event_id,
+			responded_at = EXCLUDED.responded_at
+		WHERE adapter_poll_responses.responded_at <= EXCLUDED.responded_at
+	`
+	_, err = r.db.ExecContext(ctx, query, pollEventID, sender, answersJSON, responseEventID, respondedAt.UTC())
+	return err
+}
+
+// Responses returns each voter's latest response given at or before until.
+func (r *PollRepository) Responses(ctx context.Context, pollEventID string, until time.Time) ([]PollResponse, error) {
+	query := `
+		SELECT sender, answers, responded_at
+		FROM adapter_poll_responses
+		WHERE poll_event_id = $1 AND responded_at <= $2
+		ORDER BY sender
+	`
+	rows, err := r.db.QueryContext(ctx, query, pollEventID, until.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var responses []PollResponse
+	for rows.Next() {
+		var resp PollResponse
+		var answers []byte
+		if err := rows.Scan(&resp.Sender, &answers, &resp.RespondedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(answers, &resp.Answers); err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, rows.Err()
+}
+
+// Close marks the poll closed and emits PollResultsCollected to the adapter
+// outbox in one transaction. closed is false when the poll was already
+// closed, in which case nothing is emitted.
+func (r *PollRepository) Close(ctx context.Context, results events.PollResultsCollected) (closed bool, err error) {
+	payload, err := json.Marshal(results)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE adapter_polls
+		SET closed_at = $2,
+			closed_by = $3
+		WHERE poll_event_id = $1 AND closed_at IS NULL
+	`, results.MatrixEventID, results.ClosedAt.UTC(), results.ClosedBy)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, tx.Rollback()
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, r.outboxTable)
+	if _, err = tx.ExecContext(ctx, query, uuid.New(), "PollResultsCollected", payload, time.Now().UTC()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetEndEvent records the m.poll.end the adapter sent for a closed poll.
+func (r *PollRepository) SetEndEvent(ctx context.Context, pollEventID, endEventID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE adapter_polls
+		SET end_event_id = $2
+		WHERE poll_event_id = $1
+	`, pollEventID, endEventID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPoll(row rowScanner) (Poll, error) {
+	var poll Poll
+	var pollEventID sql.NullString
+	var answers, pollContext []byte
+	var closedAt sql.NullTime
+	if err := row.Scan(&pollEventID, &poll.EventID, &poll.PollID, &poll.RoomID, &poll.Question, &answers, &poll.MaxSelections, &pollContext, &poll.ClosesAt, &closedAt); err != nil {
+		return Poll{}, err
+	}
+	poll.PollEventID = pollEventID.String
+	if err := json.Unmarshal(answers, &poll.Answers); err != nil {
+		return Poll{}, err
+	}
+	if len(pollContext) > 0 {
+		poll.Context = json.RawMessage(pollContext)
+	}
+	if closedAt.Valid {
+		poll.ClosedAt = &closedAt.Time
+	}
+	return poll, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"adapter-matrix/internal/events"
+)
+
+func TestPollRepositoryLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "adapter_polls", "adapter_poll_responses", "adapter_outbox")
+	repo, err := NewPollRepository(db, "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		eventID = "2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11"
+		room    = "!room:example.org"
+	)
+	closesAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	poll := Poll{EventID: eventID, PollID: "p1", RoomID: room, Question: "Move the quiz?", Answers: []PollAnswer{{ID: "yes", Text: "Yes"}}, MaxSelections: 1, ClosesAt: closesAt}
+	for range 2 {
+		if err := repo.Create(ctx, poll); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if unsent, err := repo.HasUnsent(ctx, room); err != nil || !unsent {
+		t.Fatalf("HasUnsent before SetPollEvent = %t, %v", unsent, err)
+	}
+	if due, _ := repo.DueForClose(ctx, time.Now()); len(due) != 0 {
+		t.Errorf("DueForClose returned an unsent poll: %+v", due)
+	}
+	// A vote seen in sync before the poll's event ID is recorded.
+	if err := repo.RecordResponse(ctx, "$poll", "@ana:example.org", "$vote1", []string{"yes"}, closesAt.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordResponse before SetPollEvent: %v", err)
+	}
+	if err := repo.SetPollEvent(ctx, eventID, "$poll"); err != nil {
+		t.Fatalf("SetPollEvent: %v", err)
+	}
+	if unsent, _ := repo.HasUnsent(ctx, room); unsent {
+		t.Error("HasUnsent after SetPollEvent = true")
+	}
+
+	got, found, err := repo.Get(ctx, "$poll")
+	if err != nil || !found || got.EventID != eventID || got.Answers[0].Text != "Yes" || got.ClosedAt != nil {
+		t.Fatalf("Get = %+v, %t, %v", got, found, err)
+	}
+	if err := repo.RecordResponse(ctx, "$poll", "@ana:example.org", "$vote0", []string{"no"}, closesAt.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("RecordResponse older vote: %v", err)
+	}
+	responses, err := repo.Responses(ctx, "$poll", closesAt)
+	if err != nil || len(responses) != 1 || responses[0].Answers[0] != "yes" {
+		t.Fatalf("Responses = %+v, %v, want the newer vote kept", responses, err)
+	}
+	if due, _ := repo.DueForClose(ctx, time.Now()); len(due) != 1 {
+		t.Fatalf("DueForClose = %+v, want the poll", due)
+	}
+
+	results := events.PollResultsCollected{PollID: "p1", OriginalEventID: eventID, MatrixEventID: "$poll", ClosedAt: closesAt, ClosedBy: "schedule", TotalVoters: 1}
+	for i, want := range []bool{true, false} {
+		closed, err := repo.Close(ctx, results)
+		if err != nil || closed != want {
+			t.Fatalf("Close #%d = %t, %v, want %t", i+1, closed, err, want)
+		}
+	}
+	var payload []byte
+	if err := db.QueryRow(`SELECT payload FROM adapter_outbox WHERE event_type = 'PollResultsCollected'`).Scan(&payload); err != nil {
+		t.Fatalf("results not emitted once: %v", err)
+	}
+	var emitted events.PollResultsCollected
+	if err := json.Unmarshal(payload, &emitted); err != nil || emitted.MatrixEventID != "$poll" || emitted.TotalVoters != 1 {
+		t.Errorf("emitted = %s, %v", payload, err)
+	}
+}
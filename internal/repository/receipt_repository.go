@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter-matrix/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// receiptWindow bounds how far back a read receipt marks delivered messages
+// as read, so a user's first receipt in an old room does not touch its whole
+// history.
+const receiptWindow = 30 * 24 * time.Hour
+
+// ReadSummary is the read state of one delivered message.
+type ReadSummary struct {
+	EventID       string
+	RoomID        string
+	MatrixEventID string
+	SentAt        time.Time
+	Readers       []Reader
+}
+
+type Reader struct {
+	MatrixUserID string
+	ReadAt       time.Time
+}
+
+// ReceiptRepository keeps per-message read receipts for delivered messages.
+// A message counts as read by a user once their receipt covers its first
+// part.
+type ReceiptRepository struct {
+	db          *sql.DB
+	outboxTable string
+}
+
+func NewReceiptRepository(db *sql.DB, outboxTable string) (*ReceiptRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if !IsValidTableName(outboxTable) {
+		return nil, errors.New("outbox table name contains invalid characters")
+	}
+	return &ReceiptRepository{db: db, outboxTable: outboxTable}, nil
+}
+
+// Delivered messages are ordered by the homeserver's origin_server_ts once
+// sync has echoed them back, and by the adapter's sent_at until then, so
+// that read positions taken from receipted events compare against the same
+// clock.
+const deliveredAt = `COALESCE(origin_server_ts, sent_at)`
+
+// RecordOriginTime stores the homeserver's origin_server_ts for the delivered
+// event matrixEventID.
+func (r *ReceiptRepository) RecordOriginTime(ctx context.Context, matrixEventID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE adapter_delivered_events
+		SET origin_server_ts = $2
+		WHERE matrix_event_id = $1 AND origin_server_ts IS NULL
+	`, matrixEventID, at.UTC())
+	return err
+}
+
+// DeliveredAt returns when the adapter's event matrixEventID in roomID was
+// sent; found is false for events the adapter did not deliver.
+func (r *ReceiptRepository) DeliveredAt(ctx context.Context, roomID, matrixEventID string) (at time.Time, found bool, err error) {
+	query := `
+		SELECT ` + deliveredAt + `
+		FROM adapter_delivered_events
+		WHERE matrix_event_id = $1 AND room_id = $2
+	`
+	if err := r.db.QueryRowContext(ctx, query, matrixEventID, roomID).Scan(&at); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return at, true, nil
+}
+
+// HasUnreadDeliveries reports whether the adapter delivered anything to
+// roomID, within receiptWindow, after userID's last recorded read position.
+func (r *ReceiptRepository) HasUnreadDeliveries(ctx context.Context, roomID, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM adapter_delivered_events
+			WHERE room_id = $1 AND part = 1 AND redacted_at IS NULL AND ` + deliveredAt + ` > $3
+				AND ` + deliveredAt + ` > COALESCE((
+					SELECT read_up_to FROM adapter_read_positions
+					WHERE room_id = $1 AND matrix_user_id = $2
+				), '-infinity')
+		)
+	`
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, roomID, userID, time.Now().Add(-receiptWindow).UTC()).Scan(&exists)
+	return exists, err
+}
+
+// RecordReadUpTo marks every message delivered to roomID at or before upTo
+// (and within receiptWindow) as read by userID at readAt, and moves userID's
+// read position in roomID forward to upTo.
+func (r *ReceiptRepository) RecordReadUpTo(ctx context.Context, roomID, userID string, upTo, readAt time.Time) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO adapter_read_receipts (event_id, matrix_user_id, read_at)
+		SELECT event_id, $2, $3
+		FROM adapter_delivered_events
+		WHERE room_id = $1 AND part = 1 AND redacted_at IS NULL
+			AND `+deliveredAt+` <= $4 AND `+deliveredAt+` > $5
+		ON CONFLICT (event_id, matrix_user_id) DO NOTHING
+	`, roomID, userID, readAt.UTC(), upTo.UTC(), upTo.Add(-receiptWindow).UTC()); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO adapter_read_positions (room_id, matrix_user_id, read_up_to)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (room_id, matrix_user_id) DO UPDATE
+		SET read_up_to = GREATEST(adapter_read_positions.read_up_to, EXCLUDED.read_up_to)
+	`, roomID, userID, upTo.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordRead marks the message delivered as matrixEventID as read.
+func (r *ReceiptRepository) RecordRead(ctx context.Context, matrixEventID, userID string, readAt time.Time) error {
+	query := `
+		INSERT INTO adapter_read_receipts (event_id, matrix_user_id, read_at)
+		SELECT event_id, $2, $3
+		FROM adapter_delivered_events
+		WHERE matrix_event_id = $1 AND redacted_at IS NULL
+		ON CONFLICT (event_id, matrix_user_id) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query, matrixEventID, userID, readAt.UTC())
+	return err
+}
+
+// Summary returns the readers of the outbox event eventID.
+func (r *ReceiptRepository) Summary(ctx context.Context, eventID string) (ReadSummary, bool, error) {
+	parsed, err := uuid.Parse(eventID)
+	if err != nil {
+		return ReadSummary{}, false, err
+	}
+	summaries, err := r.summaries(ctx, `d.event_id = $1`, parsed)
+	if err != nil || len(summaries) == 0 {
+		return ReadSummary{}, false, err
+	}
+	return summaries[0], true, nil
+}
+
+// RoomSummaries returns the read state of messages delivered to roomID since
+// since, newest first.
+func (r *ReceiptRepository) RoomSummaries(ctx context.Context, roomID string, since time.Time) ([]ReadSummary, error) {
+	return r.summaries(ctx, `d.room_id = $1 AND d.sent_at >= $2`, roomID, since.UTC())
+}
+
+func (r *ReceiptRepository) summaries(ctx context.Context, where string, args ...any) ([]ReadSummary, error) {
+	query := fmt.Sprintf(`
+		SELECT d.event_id, d.room_id, d.matrix_event_id, d.sent_at, rr.matrix_user_id, rr.read_at
+		FROM adapter_delivered_events d
+		LEFT JOIN adapter_read_receipts rr ON rr.event_id = d.event_id
+		WHERE d.part = 1 AND %s
+		ORDER BY d.sent_at DESC, d.event_id, rr.read_at
+	`, where)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ReadSummary
+	for rows.Next() {
+		var s ReadSummary
+		var userID sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(&s.EventID, &s.RoomID, &s.MatrixEventID, &s.SentAt, &userID, &readAt); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].EventID != s.EventID {
+			out = append(out, s)
+		}
+		if userID.Valid {
+			last := &out[len(out)-1]
+			last.Readers = append(last.Readers, Reader{MatrixUserID: userID.String, ReadAt: readAt.Time})
+		}
+	}
+	return out, rows.Err()
+}
+
+// PendingSummaries returns the messages with reads not yet reported.
+func (r *ReceiptRepository) PendingSummaries(ctx context.Context) ([]ReadSummary, error) {
+	return r.summaries(ctx, `d.event_id IN (SELECT DISTINCT event_id FROM adapter_read_receipts WHERE NOT summarized)`)
+}
+
+// EmitSummary writes ReadReceiptSummary to the adapter outbox and marks the
+// reads it covers as reported, in one transaction.
+func (r *ReceiptRepository) EmitSummary(ctx context.Context, summary events.ReadReceiptSummary) (err error) {
+	parsed, err := uuid.Parse(summary.OriginalEventID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	readers := make([]string, 0, len(summary.Readers))
+	for _, reader := range summary.Readers {
+		readers = append(readers, reader.MatrixUserID)
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE adapter_read_receipts
+		SET summarized = true
+		WHERE event_id = $1 AND matrix_user_id = ANY($2)
+	`, parsed, readers); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, r.outboxTable)
+	if _, err = tx.ExecContext(ctx, query, uuid.New(), "ReadReceiptSummary", payload, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReceiptRepositoryReadUpTo(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "adapter_delivered_events", "adapter_read_receipts", "adapter_read_positions")
+	state, err := NewAdapterStateRepository(db, "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewReceiptRepository(db, "adapter_outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const room = "!a:example.org"
+	deliveries := []struct {
+		eventID, matrixEventID string
+		origin                 time.Duration
+	}{
+		{"2b7e1c1e-8f7a-4c57-9a38-0d3f7b0b6a11", "$first", -3 * time.Minute},
+		{"5d0f4c4a-1f52-4a9e-8c55-7c1f6b2e9d22", "$second", -2 * time.Minute},
+		{"8a1c2e3f-4b5d-4e6f-9a7b-0c1d2e3f4a33", "$third", -time.Minute},
+	}
+	// The homeserver clock runs behind the adapter's, so each event's
+	// origin_server_ts is earlier than its sent_at.
+	now := time.Now()
+	for _, d := range deliveries {
+		if err := state.RecordDelivery(ctx, d.eventID, 1, 1, room, d.matrixEventID, ""); err != nil {
+			t.Fatalf("RecordDelivery: %v", err)
+		}
+		if err := repo.RecordOriginTime(ctx, d.matrixEventID, now.Add(d.origin)); err != nil {
+			t.Fatalf("RecordOriginTime: %v", err)
+		}
+	}
+
+	upTo, found, err := repo.DeliveredAt(ctx, room, "$second")
+	if err != nil || !found || !upTo.Equal(now.Add(-2*time.Minute).Truncate(time.Microsecond)) {
+		t.Fatalf("DeliveredAt = %s, %t, %v", upTo, found, err)
+	}
+	if _, found, _ := repo.DeliveredAt(ctx, "!b:example.org", "$second"); found {
+		t.Error("DeliveredAt found the event in another room")
+	}
+	if unread, _ := repo.HasUnreadDeliveries(ctx, room, "@ana:example.org"); !unread {
+		t.Error("HasUnreadDeliveries = false before any receipt")
+	}
+
+	if err := repo.RecordReadUpTo(ctx, room, "@ana:example.org", upTo, now); err != nil {
+		t.Fatalf("RecordReadUpTo: %v", err)
+	}
+	for _, d := range deliveries {
+		summary, _, err := repo.Summary(ctx, d.eventID)
+		if err != nil {
+			t.Fatalf("Summary: %v", err)
+		}
+		wantRead := d.matrixEventID != "$third"
+		if read := len(summary.Readers) == 1; read != wantRead {
+			t.Errorf("%s read = %t, want %t", d.matrixEventID, read, wantRead)
+		}
+	}
+	if unread, _ := repo.HasUnreadDeliveries(ctx, room, "@ana:example.org"); !unread {
+		t.Error("HasUnreadDeliveries = false with $third unread")
+	}
+	if err := repo.RecordReadUpTo(ctx, room, "@ana:example.org", now, now); err != nil {
+		t.Fatalf("RecordReadUpTo: %v", err)
+	}
+	if unread, _ := repo.HasUnreadDeliveries(ctx, room, "@ana:example.org"); unread {
+		t.Error("HasUnreadDeliveries = true after reading everything")
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// RoomSettings are per-room overrides for how messages are rendered. Empty
+// fields mean "use the adapter default".
+type RoomSettings struct {
+	RoomID   string
+	Locale   string
+	Timezone string
+	MsgType  string
+	// ThreadUpdates sends timetable updates as thread replies to the day's
+	// announcement instead of standalone messages.
+	ThreadUpdates bool
+	// TopicSync keeps m.room.topic set to a summary of the latest timetable.
+	TopicSync bool
+}
+
+type RoomSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewRoomSettingsRepository(db *sql.DB) (*RoomSettingsRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &RoomSettingsRepository{db: db}, nil
+}
+
+// Get returns the settings for roomID, or zero-valued settings when the room
+// has no row.
+func (r *RoomSettingsRepository) Get(ctx context.Context, roomID string) (RoomSettings, error) {
+	query := `
+		SELECT room_id, locale, timezone, msgtype, thread_updates, topic_sync
+		FROM adapter_room_settings
+		WHERE room_id = $1
+	`
+	settings := RoomSettings{RoomID: roomID}
+	row := r.db.QueryRowContext(ctx, query, roomID)
+	if err := row.Scan(&settings.RoomID, &settings.Locale, &settings.Timezone, &settings.MsgType, &settings.ThreadUpdates, &settings.TopicSync); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoomSettings{RoomID: roomID}, nil
+		}
+		return RoomSettings{}, err
+	}
+	return settings, nil
+}
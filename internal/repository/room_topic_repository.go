@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RoomTopic is the topic the adapter last set in a room and, when a newer
+// one arrived inside the rate limit, the topic still waiting to be set.
+type RoomTopic struct {
+	RoomID       string
+	Topic        string
+	PendingTopic string
+	SetAt        time.Time
+}
+
+type RoomTopicRepository struct {
+	db *sql.DB
+}
+
+func NewRoomTopicRepository(db *sql.DB) (*RoomTopicRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &RoomTopicRepository{db: db}, nil
+}
+
+// Get returns the topic state for roomID, zero-valued when the adapter has
+// never set its topic.
+func (r *RoomTopicRepository) Get(ctx context.Context, roomID string) (RoomTopic, error) {
+	query := `
+		SELECT room_id, topic, pending_topic, set_at
+		FROM adapter_room_topics
+		WHERE room_id = $1
+	`
+	topic := RoomTopic{RoomID: roomID}
+	var setAt sql.NullTime
+	row := r.db.QueryRowContext(ctx, query, roomID)
+	if err := row.Scan(&topic.RoomID, &topic.Topic, &topic.PendingTopic, &setAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoomTopic{RoomID: roomID}, nil
+		}
+		return RoomTopic{}, err
+	}
+	topic.SetAt = setAt.Time
+	return topic, nil
+}
+
+// MarkSet records that topic is now the room's topic and clears any pending
+// one.
+func (r *RoomTopicRepository) MarkSet(ctx context.Context, roomID, topic string) error {
+	query := `
+		INSERT INTO adapter_room_topics (room_id, topic, pending_topic, set_at)
+		VALUES ($1, $2, '', $3)
+		ON CONFLICT (room_id) DO UPDATE
+		SET topic = EXCLUDED.topic,
+			pending_topic = '',
+			set_at = EXCLUDED.set_at
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID, topic, time.Now().UTC())
+	return err
+}
+
+// SetPending stores topic to be set once the rate limit allows. An empty
+// topic cancels the pending one.
+func (r *RoomTopicRepository) SetPending(ctx context.Context, roomID, topic string) error {
+	query := `
+		INSERT INTO adapter_room_topics (room_id, pending_topic)
+		VALUES ($1, $2)
+		ON CONFLICT (room_id) DO UPDATE
+		SET pending_topic = EXCLUDED.pending_topic
+	`
+	_, err := r.db.ExecContext(ctx, query, roomID, topic)
+	return err
+}
+
+// DuePending returns rooms with a pending topic whose last topic change was
+// at or before setBefore.
+func (r *RoomTopicRepository) DuePending(ctx context.Context, setBefore time.Time) ([]RoomTopic, error) {
+	query := `
+		SELECT room_id, topic, pending_topic, set_at
+		FROM adapter_room_topics
+		WHERE pending_topic <> '' AND (set_at IS NULL OR set_at <= $1)
+		ORDER BY room_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, setBefore.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []RoomTopic
+	for rows.Next() {
+		var topic RoomTopic
+		var setAt sql.NullTime
+		if err := rows.Scan(&topic.RoomID, &topic.Topic, &topic.PendingTopic, &setAt); err != nil {
+			return nil, err
+		}
+		topic.SetAt = setAt.Time
+		due = append(due, topic)
+	}
+	return due, rows.Err()
+}
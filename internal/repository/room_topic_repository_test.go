@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomTopicRepositoryPending(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewRoomTopicRepository(testDB(t, "adapter_room_topics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err := repo.Get(ctx, "!a:example.org"); err != nil || state.Topic != "" || !state.SetAt.IsZero() {
+		t.Fatalf("Get before any write = %+v, %v", state, err)
+	}
+	if err := repo.MarkSet(ctx, "!a:example.org", "Today: CS301 09:00"); err != nil {
+		t.Fatalf("MarkSet: %v", err)
+	}
+	if err := repo.SetPending(ctx, "!a:example.org", "Today: MA201 10:00"); err != nil {
+		t.Fatalf("SetPending: %v", err)
+	}
+	if err := repo.SetPending(ctx, "!b:example.org", "Today: Lab 14:00"); err != nil {
+		t.Fatalf("SetPending: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		setBefore time.Time
+		want      []string
+	}{
+		{name: "within the interval", setBefore: time.Now().Add(-time.Hour), want: []string{"!b:example.org"}},
+		{name: "after the interval", setBefore: time.Now().Add(time.Second), want: []string{"!a:example.org", "!b:example.org"}},
+	}
+	for _, tc := range cases {
+		due, err := repo.DuePending(ctx, tc.setBefore)
+		if err != nil {
+			t.Fatalf("%s: DuePending: %v", tc.name, err)
+		}
+		var rooms []string
+		for _, state := range due {
+			rooms = append(rooms, state.RoomID)
+		}
+		if strings.Join(rooms, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: due rooms = %v, want %v", tc.name, rooms, tc.want)
+		}
+	}
+
+	if err := repo.MarkSet(ctx, "!a:example.org", "Today: MA201 10:00"); err != nil {
+		t.Fatalf("MarkSet: %v", err)
+	}
+	state, err := repo.Get(ctx, "!a:example.org")
+	if err != nil || state.Topic != "Today: MA201 10:00" || state.PendingTopic != "" {
+		t.Errorf("Get after flushing = %+v, %v", state, err)
+	}
+	if err := repo.SetPending(ctx, "!b:example.org", ""); err != nil {
+		t.Fatalf("SetPending(empty): %v", err)
+	}
+	if due, _ := repo.DuePending(ctx, time.Now().Add(time.Second)); len(due) != 0 {
+		t.Errorf("DuePending after clearing = %+v", due)
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// TemplateRecord is one row of adapter_templates. Empty RoomID and Locale
+// mean the template applies to every room or locale.
+type TemplateRecord struct {
+	EventType string
+	RoomID    string
+	Locale    string
+	Variant   string
+	Body      string
+}
+
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) (*TemplateRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &TemplateRepository{db: db}, nil
+}
+
+func (r *TemplateRepository) List(ctx context.Context) ([]TemplateRecord, error) {
+	query := `
+		SELECT event_type, room_id, locale, variant, body
+		FROM adapter_templates
+		ORDER BY event_type, room_id, locale, variant
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []TemplateRecord
+	for rows.Next() {
+		var rec TemplateRecord
+		if err := rows.Scan(&rec.EventType, &rec.RoomID, &rec.Locale, &rec.Variant, &rec.Body); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Fingerprint returns a hash of the whole table so callers can detect edits
+// without relying on updated_at being maintained by admins.
+func (r *TemplateRepository) Fingerprint(ctx context.Context) (string, error) {
+	query := `
+		SELECT md5(COALESCE(string_agg(
+			event_type || E'\x1f' || room_id || E'\x1f' || locale || E'\x1f' || variant || E'\x1f' || body,
+			E'\x1e' ORDER BY event_type, room_id, locale, variant
+		), ''))
+		FROM adapter_templates
+	`
+	var fingerprint string
+	if err := r.db.QueryRowContext(ctx, query).Scan(&fingerprint); err != nil {
+		return "", err
+	}
+	return fingerprint, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// UserDirectoryRepository maps CR45 user IDs to Matrix user IDs.
+type UserDirectoryRepository struct {
+	db *sql.DB
+}
+
+func NewUserDirectoryRepository(db *sql.DB) (*UserDirectoryRepository, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	return &UserDirectoryRepository{db: db}, nil
+}
+
+// MatrixUserIDs returns the Matrix ID for each mapped CR45 user ID. IDs
+// without a mapping are absent from the map.
+func (r *UserDirectoryRepository) MatrixUserIDs(ctx context.Context, cr45UserIDs []string) (map[string]string, error) {
+	out := make(map[string]string, len(cr45UserIDs))
+	if len(cr45UserIDs) == 0 {
+		return out, nil
+	}
+	query := `
+		SELECT cr45_user_id, matrix_user_id
+		FROM adapter_user_directory
+		WHERE cr45_user_id = ANY($1)
+	`
+	rows, err := r.db.QueryContext(ctx, query, cr45UserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cr45UserID, matrixUserID string
+		if err := rows.Scan(&cr45UserID, &matrixUserID); err != nil {
+			return nil, err
+		}
+		out[cr45UserID] = matrixUserID
+	}
+	return out, rows.Err()
+}
+
+// CR45UserIDs is the reverse of MatrixUserIDs.
+func (r *UserDirectoryRepository) CR45UserIDs(ctx context.Context, matrixUserIDs []string) (map[string]string, error) {
+	out := make(map[string]string, len(matrixUserIDs))
+	if len(matrixUserIDs) == 0 {
+		return out, nil
+	}
+	query := `
+		SELECT matrix_user_id, cr45_user_id
+		FROM adapter_user_directory
+		WHERE matrix_user_id = ANY($1)
+	`
+	rows, err := r.db.QueryContext(ctx, query, matrixUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var matrixUserID, cr45UserID string
+		if err := rows.Scan(&matrixUserID, &cr45UserID); err != nil {
+			return nil, err
+		}
+		out[matrixUserID] = cr45UserID
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,316 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// node is a compiled subset of JSON Schema (draft 2020-12): type, enum,
+// const, required, properties, additionalProperties, items, minItems,
+// maxItems, minLength, maxLength, pattern, minimum and maximum.
+type node struct {
+	types                []string
+	enum                 []any
+	constValue           any
+	hasConst             bool
+	required             []string
+	properties           map[string]*node
+	additionalProperties *bool
+	items                *node
+	minItems             *int
+	maxItems             *int
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+	minimum              *float64
+	maximum              *float64
+}
+
+var knownKeywords = map[string]struct{}{
+	"$schema": {}, "$id": {}, "$comment": {}, "title": {}, "description": {}, "examples": {},
+	"type": {}, "enum": {}, "const": {}, "required": {}, "properties": {},
+	"additionalProperties": {}, "items": {}, "minItems": {}, "maxItems": {},
+	"minLength": {}, "maxLength": {}, "pattern": {}, "minimum": {}, "maximum": {},
+}
+
+func compile(doc map[string]any) (*node, error) {
+	for key := range doc {
+		if _, ok := knownKeywords[key]; !ok {
+			return nil, fmt.Errorf("unsupported keyword %q", key)
+		}
+	}
+
+	n := &node{}
+	switch v := doc["type"].(type) {
+	case nil:
+	case string:
+		n.types = []string{v}
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("type entries must be strings")
+			}
+			n.types = append(n.types, s)
+		}
+	default:
+		return nil, fmt.Errorf("type must be a string or array")
+	}
+
+	if raw, ok := doc["enum"]; ok {
+		values, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+		n.enum = values
+	}
+	if raw, ok := doc["const"]; ok {
+		n.constValue = raw
+		n.hasConst = true
+	}
+
+	if raw, ok := doc["required"]; ok {
+		values, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("required must be an array")
+		}
+		for _, item := range values {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("required entries must be strings")
+			}
+			n.required = append(n.required, s)
+		}
+	}
+
+	if raw, ok := doc["properties"]; ok {
+		props, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("properties must be an object")
+		}
+		n.properties = make(map[string]*node, len(props))
+		for name, sub := range props {
+			subDoc, ok := sub.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("property %q must be a schema object", name)
+			}
+			child, err := compile(subDoc)
+			if err != nil {
+				return nil, fmt.Errorf("property %q: %w", name, err)
+			}
+			n.properties[name] = child
+		}
+	}
+
+	if raw, ok := doc["additionalProperties"]; ok {
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("additionalProperties must be a boolean")
+		}
+		n.additionalProperties = &b
+	}
+
+	if raw, ok := doc["items"]; ok {
+		subDoc, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("items must be a schema object")
+		}
+		child, err := compile(subDoc)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		n.items = child
+	}
+
+	var err error
+	if n.minItems, err = intKeyword(doc, "minItems"); err != nil {
+		return nil, err
+	}
+	if n.maxItems, err = intKeyword(doc, "maxItems"); err != nil {
+		return nil, err
+	}
+	if n.minLength, err = intKeyword(doc, "minLength"); err != nil {
+		return nil, err
+	}
+	if n.maxLength, err = intKeyword(doc, "maxLength"); err != nil {
+		return nil, err
+	}
+	if n.minimum, err = numberKeyword(doc, "minimum"); err != nil {
+		return nil, err
+	}
+	if n.maximum, err = numberKeyword(doc, "maximum"); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := doc["pattern"]; ok {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		n.pattern = re
+	}
+
+	return n, nil
+}
+
+func intKeyword(doc map[string]any, key string) (*int, error) {
+	raw, ok := doc[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := raw.(float64)
+	if !ok || f != float64(int(f)) || f < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	v := int(f)
+	return &v, nil
+}
+
+func numberKeyword(doc map[string]any, key string) (*float64, error) {
+	raw, ok := doc[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := raw.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
+}
+
+func (n *node) validate(value any, ptr string, errs *ValidationErrors) {
+	if len(n.types) > 0 && !matchesType(value, n.types) {
+		*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("expected %s, got %s", strings.Join(n.types, " or "), typeName(value))})
+		return
+	}
+	if n.hasConst && !jsonEqual(value, n.constValue) {
+		*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must equal %s", jsonText(n.constValue))})
+	}
+	if len(n.enum) > 0 {
+		found := false
+		for _, candidate := range n.enum {
+			if jsonEqual(value, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must be one of %s", jsonText(n.enum))})
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n.minLength != nil && length < *n.minLength {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must be at least %d characters", *n.minLength)})
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must be at most %d characters", *n.maxLength)})
+		}
+		if n.pattern != nil && !n.pattern.MatchString(v) {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("does not match pattern %s", n.pattern.String())})
+		}
+	case float64:
+		if n.minimum != nil && v < *n.minimum {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must be >= %v", *n.minimum)})
+		}
+		if n.maximum != nil && v > *n.maximum {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must be <= %v", *n.maximum)})
+		}
+	case []any:
+		if n.minItems != nil && len(v) < *n.minItems {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must contain at least %d items", *n.minItems)})
+		}
+		if n.maxItems != nil && len(v) > *n.maxItems {
+			*errs = append(*errs, ValidationError{Path: ptr, Message: fmt.Sprintf("must contain at most %d items", *n.maxItems)})
+		}
+		if n.items != nil {
+			for i, item := range v {
+				n.items.validate(item, ptr+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range n.required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, ValidationError{Path: ptr + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child, ok := n.properties[key]
+			if !ok {
+				if n.additionalProperties != nil && !*n.additionalProperties {
+					*errs = append(*errs, ValidationError{Path: ptr + "/" + escapePointer(key), Message: "is not allowed"})
+				}
+				continue
+			}
+			child.validate(v[key], ptr+"/"+escapePointer(key), errs)
+		}
+	}
+}
+
+func matchesType(value any, types []string) bool {
+	actual := typeName(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func jsonEqual(a, b any) bool {
+	return jsonText(a) == jsonText(b)
+}
+
+func jsonText(value any) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
+
+func escapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return strings.ReplaceAll(token, "/", "~1")
+}
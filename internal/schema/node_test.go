@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func mustCompile(t *testing.T, doc string) *node {
+	t.Helper()
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(doc), &raw); err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+	n, err := compile(raw)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return n
+}
+
+func validateJSON(t *testing.T, n *node, value string) ValidationErrors {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		t.Fatalf("parse value: %v", err)
+	}
+	var errs ValidationErrors
+	n.validate(doc, "", &errs)
+	return errs
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		value  string
+		// want lists the expected "path: message" errors; nil means valid.
+		want []string
+	}{
+		{"integer accepts whole numbers", `{"type":"integer"}`, `3`, nil},
+		{"integer accepts 3.0", `{"type":"integer"}`, `3.0`, nil},
+		{"integer rejects fractions", `{"type":"integer"}`, `3.5`, []string{"/: expected integer, got number"}},
+		{"number accepts integers", `{"type":"number"}`, `3`, nil},
+		{"number accepts fractions", `{"type":"number"}`, `3.5`, nil},
+		{"string rejects numbers", `{"type":"string"}`, `3`, []string{"/: expected string, got integer"}},
+		{"type union", `{"type":["string","null"]}`, `null`, nil},
+		{"type union mismatch", `{"type":["string","null"]}`, `true`, []string{"/: expected string or null, got boolean"}},
+		{"object rejects arrays", `{"type":"object"}`, `[]`, []string{"/: expected object, got array"}},
+
+		{"required present", `{"required":["a"]}`, `{"a":1}`, nil},
+		{"required missing", `{"required":["a","b"]}`, `{"a":1}`, []string{"/b: is required"}},
+		{"additional properties allowed by default", `{"properties":{"a":{}}}`, `{"a":1,"b":2}`, nil},
+		{"additional properties rejected", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"c":2,"b":3}`, []string{"/b: is not allowed", "/c: is not allowed"}},
+		{"nested property path", `{"properties":{"a":{"properties":{"b":{"type":"string"}}}}}`, `{"a":{"b":1}}`, []string{"/a/b: expected string, got integer"}},
+
+		{"enum match", `{"enum":["x",1,null]}`, `1`, nil},
+		{"enum mismatch", `{"enum":["x","y"]}`, `"z"`, []string{`/: must be one of ["x","y"]`}},
+		{"const match", `{"const":{"a":[1,2]}}`, `{"a":[1,2]}`, nil},
+		{"const mismatch", `{"const":2}`, `1`, []string{"/: must equal 2"}},
+
+		{"pattern match", `{"pattern":"^![^:]+:.+$"}`, `"!room:example.org"`, nil},
+		{"pattern mismatch", `{"pattern":"^![^:]+:.+$"}`, `"room"`, []string{"/: does not match pattern ^![^:]+:.+$"}},
+		{"pattern ignores non-strings", `{"pattern":"^a$"}`, `1`, nil},
+		{"minLength counts runes", `{"minLength":3}`, `"हिंदी"`, nil},
+		{"minLength", `{"minLength":2}`, `"a"`, []string{"/: must be at least 2 characters"}},
+		{"maxLength counts runes", `{"maxLength":2}`, `"éé"`, nil},
+		{"maxLength", `{"maxLength":2}`, `"abc"`, []string{"/: must be at most 2 characters"}},
+		{"minimum", `{"minimum":1}`, `0`, []string{"/: must be >= 1"}},
+		{"minimum inclusive", `{"minimum":1}`, `1`, nil},
+		{"maximum", `{"maximum":10}`, `10.5`, []string{"/: must be <= 10"}},
+		{"minItems", `{"minItems":1}`, `[]`, []string{"/: must contain at least 1 items"}},
+		{"maxItems", `{"maxItems":1}`, `[1,2]`, []string{"/: must contain at most 1 items"}},
+		{"items path", `{"items":{"type":"string"}}`, `["a",2,"c",4]`, []string{"/1: expected string, got integer", "/3: expected string, got integer"}},
+
+		{"pointer escapes slash and tilde", `{"properties":{"a/b~c":{"type":"string"}}}`, `{"a/b~c":1}`, []string{"/a~1b~0c: expected string, got integer"}},
+		{"pointer escapes required names", `{"required":["x/y"]}`, `{}`, []string{"/x~1y: is required"}},
+		{"pointer escapes additional properties", `{"additionalProperties":false}`, `{"~":1}`, []string{"/~0: is not allowed"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := validateJSON(t, mustCompile(t, tc.schema), tc.value)
+			got := make([]string, 0, len(errs))
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("errors = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsInvalidSchemas(t *testing.T) {
+	cases := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{"unknown keyword", `{"type":"string","format":"date"}`, `unsupported keyword "format"`},
+		{"unknown nested keyword", `{"properties":{"a":{"oneOf":[]}}}`, `property "a": unsupported keyword "oneOf"`},
+		{"unknown keyword in items", `{"items":{"anyOf":[]}}`, `items: unsupported keyword "anyOf"`},
+		{"type not a string", `{"type":1}`, "type must be a string or array"},
+		{"type entry not a string", `{"type":["string",1]}`, "type entries must be strings"},
+		{"enum not an array", `{"enum":"a"}`, "enum must be an array"},
+		{"required not an array", `{"required":"a"}`, "required must be an array"},
+		{"additionalProperties schema", `{"additionalProperties":{}}`, "additionalProperties must be a boolean"},
+		{"negative minLength", `{"minLength":-1}`, "minLength must be a non-negative integer"},
+		{"fractional maxItems", `{"maxItems":1.5}`, "maxItems must be a non-negative integer"},
+		{"string minimum", `{"minimum":"1"}`, "minimum must be a number"},
+		{"bad pattern", `{"pattern":"("}`, "pattern:"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var raw map[string]any
+			if err := json.Unmarshal([]byte(tc.schema), &raw); err != nil {
+				t.Fatalf("parse schema: %v", err)
+			}
+			_, err := compile(raw)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("compile error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+// MessageEventType is the schema name used for generic message payloads that
+// carry room_id/body directly instead of a domain event shape.
+const MessageEventType = "Message"
+
+var fileNamePattern = regexp.MustCompile(`^([A-Za-z0-9]+)\.v([0-9]+)\.json$`)
+
+var defaultSet = mustLoad()
+
+// Schema is a compiled JSON Schema for one version of one event type.
+type Schema struct {
+	ID        string
+	EventType string
+	Version   int
+	root      *node
+}
+
+// Set holds every embedded schema version, keyed by event type.
+type Set struct {
+	byType map[string][]*Schema
+}
+
+// ValidationError describes a single schema violation at a JSON pointer path.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	p := e.Path
+	if p == "" {
+		p = "/"
+	}
+	return p + ": " + e.Message
+}
+
+// ValidationErrors is returned when a payload violates its schema.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, item := range e {
+		parts = append(parts, item.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Default returns the set of schemas embedded in the binary.
+func Default() *Set {
+	return defaultSet
+}
+
+// Validate checks payload against the latest (or payload-requested) schema
+// version for eventType in the default set.
+func Validate(eventType string, payload []byte) error {
+	return defaultSet.Validate(eventType, payload)
+}
+
+// Has reports whether a schema is registered for eventType.
+func (s *Set) Has(eventType string) bool {
+	_, ok := s.byType[eventType]
+	return ok
+}
+
+// EventTypes returns the event types that have at least one schema.
+func (s *Set) EventTypes() []string {
+	out := make([]string, 0, len(s.byType))
+	for eventType := range s.byType {
+		out = append(out, eventType)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lookup returns the schema for eventType at version, or the latest version
+// when version is 0.
+func (s *Set) Lookup(eventType string, version int) (*Schema, error) {
+	versions, ok := s.byType[eventType]
+	if !ok {
+		return nil, fmt.Errorf("no schema for event type %q", eventType)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, candidate := range versions {
+		if candidate.Version == version {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("no schema version %d for event type %q", version, eventType)
+}
+
+// Validate checks payload against the schema version named by its
+// schema_version field. A payload without one is checked against the latest
+// version rather than v1: later versions only loosen formats and add optional
+// fields, so payloads written for v1 still pass, while producers that send
+// newer fields without pinning keep working.
+func (s *Set) Validate(eventType string, payload []byte) error {
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	version := 0
+	if obj, ok := doc.(map[string]any); ok {
+		if raw, ok := obj["schema_version"]; ok {
+			n, ok := raw.(float64)
+			if !ok || n != float64(int(n)) || n < 1 {
+				return ValidationErrors{{Path: "/schema_version", Message: "must be a positive integer"}}
+			}
+			version = int(n)
+		}
+	}
+
+	sch, err := s.Lookup(eventType, version)
+	if err != nil {
+		return err
+	}
+	return sch.ValidateValue(doc)
+}
+
+// ValidateValue validates an already decoded JSON value.
+func (s *Schema) ValidateValue(doc any) error {
+	var errs ValidationErrors
+	s.root.validate(doc, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func mustLoad() *Set {
+	set, err := load(files)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func load(fsys fs.FS) (*Set, error) {
+	names, err := fs.Glob(fsys, "schemas/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("list embedded schemas: %w", err)
+	}
+
+	set := &Set{byType: make(map[string][]*Schema)}
+	for _, name := range names {
+		match := fileNamePattern.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			return nil, fmt.Errorf("schema file %s must be named <EventType>.v<N>.json", name)
+		}
+		version, _ := strconv.Atoi(match[2])
+
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read schema %s: %w", name, err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("parse schema %s: %w", name, err)
+		}
+		root, err := compile(doc)
+		if err != nil {
+			return nil, fmt.Errorf("compile schema %s: %w", name, err)
+		}
+		id, _ := doc["$id"].(string)
+		set.byType[match[1]] = append(set.byType[match[1]], &Schema{
+			ID:        id,
+			EventType: match[1],
+			Version:   version,
+			root:      root,
+		})
+	}
+
+	for _, versions := range set.byType {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	}
+	if len(set.byType) == 0 {
+		return nil, errors.New("no embedded schemas found")
+	}
+	return set, nil
+}
@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testSet(t *testing.T) *Set {
+	t.Helper()
+	set, err := load(fstest.MapFS{
+		"schemas/Note.v1.json": {Data: []byte(`{
+			"type": "object",
+			"required": ["text"],
+			"properties": {
+				"schema_version": {"type": "integer", "const": 1},
+				"text": {"type": "string"}
+			}
+		}`)},
+		"schemas/Note.v2.json": {Data: []byte(`{
+			"type": "object",
+			"required": ["body"],
+			"additionalProperties": false,
+			"properties": {
+				"schema_version": {"type": "integer", "const": 2},
+				"body": {"type": "string"}
+			}
+		}`)},
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return set
+}
+
+func TestSchemaVersionPinning(t *testing.T) {
+	set := testSet(t)
+	cases := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"latest by default", `{"body":"hi"}`, ""},
+		{"latest rejects old shape", `{"text":"hi"}`, "/body: is required"},
+		{"pinned to v1", `{"schema_version":1,"text":"hi"}`, ""},
+		{"pinned to v2", `{"schema_version":2,"body":"hi"}`, ""},
+		{"pinned v1 rejects new shape", `{"schema_version":1,"body":"hi"}`, "/text: is required"},
+		{"unknown version", `{"schema_version":3,"body":"hi"}`, `no schema version 3 for event type "Note"`},
+		{"zero version", `{"schema_version":0,"body":"hi"}`, "/schema_version: must be a positive integer"},
+		{"fractional version", `{"schema_version":1.5,"body":"hi"}`, "/schema_version: must be a positive integer"},
+		{"string version", `{"schema_version":"2","body":"hi"}`, "/schema_version: must be a positive integer"},
+		{"invalid JSON", `{`, "invalid JSON"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := set.Validate("Note", []byte(tc.payload))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	if err := set.Validate("Other", []byte(`{}`)); err == nil {
+		t.Error("Validate accepted an unknown event type")
+	}
+	var verrs ValidationErrors
+	if err := set.Validate("Note", []byte(`{"body":1}`)); !errors.As(err, &verrs) || len(verrs) != 1 {
+		t.Errorf("Validate error = %v, want one ValidationError", err)
+	}
+}
+
+func TestLoadRejectsBadSchemaFiles(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "unknown keyword",
+			files:   fstest.MapFS{"schemas/Note.v1.json": {Data: []byte(`{"type":"object","oneOf":[]}`)}},
+			wantErr: `compile schema schemas/Note.v1.json: unsupported keyword "oneOf"`,
+		},
+		{
+			name:    "bad file name",
+			files:   fstest.MapFS{"schemas/Note.json": {Data: []byte(`{}`)}},
+			wantErr: "must be named <EventType>.v<N>.json",
+		},
+		{
+			name:    "invalid JSON",
+			files:   fstest.MapFS{"schemas/Note.v1.json": {Data: []byte(`{`)}},
+			wantErr: "parse schema schemas/Note.v1.json",
+		},
+		{
+			name:    "no schemas",
+			files:   fstest.MapFS{},
+			wantErr: "no embedded schemas found",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(tc.files)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("load error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmbeddedSchemasCompile(t *testing.T) {
+	set, err := load(files)
+	if err != nil {
+		t.Fatalf("load embedded schemas: %v", err)
+	}
+	for _, eventType := range set.EventTypes() {
+		versions := set.byType[eventType]
+		for i, s := range versions {
+			if s.Version != i+1 {
+				t.Errorf("%s: versions %v are not numbered 1..n", eventType, versionNumbers(versions))
+				break
+			}
+		}
+	}
+}
+
+func versionNumbers(schemas []*Schema) []int {
+	out := make([]int, 0, len(schemas))
+	for _, s := range schemas {
+		out = append(out, s.Version)
+	}
+	return out
+}
+
+func TestUnpinnedV1PayloadsPassLatest(t *testing.T) {
+	cases := []struct {
+		eventType string
+		payload   string
+	}{
+		{"DailyTimetableAnnounced", `{"class_id":"c1","date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"2024-10-14T09:50:00+05:30"}]}`},
+		{"TimetableUpdated", `{"class_id":"c1","date":"2024-10-14","matrix_room_id":"!a:example.org","slots":[{"slot_index":1,"course_code":"CS301","start_time":"09:00","end_time":"09:50"}]}`},
+	}
+	for _, tc := range cases {
+		pinned := strings.Replace(tc.payload, "{", `{"schema_version":1,`, 1)
+		if err := Validate(tc.eventType, []byte(pinned)); err != nil {
+			t.Fatalf("%s is not a valid v1 payload: %v", tc.eventType, err)
+		}
+		if err := Validate(tc.eventType, []byte(tc.payload)); err != nil {
+			t.Errorf("%s v1 payload without schema_version: %v", tc.eventType, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package slotstatus
+
+import "strings"
+
+// Known slot statuses sent by CR45.
+const (
+	Scheduled    = "scheduled"
+	Cancelled    = "cancelled"
+	Rescheduled  = "rescheduled"
+	VenueChanged = "venue_changed"
+	Substitute   = "substitute"
+	Exam         = "exam"
+	Free         = "free"
+)
+
+// Slot fields a status can emphasise.
+const (
+	FieldCourse = "course_code"
+	FieldTime   = "time"
+	FieldVenue  = "venue"
+)
+
+// Info describes how a known status is rendered. Struck statuses strike the
+// whole slot through; Emphasis lists the fields shown in bold.
+type Info struct {
+	Status   string
+	Emoji    string
+	LabelKey string
+	Struck   bool
+	Emphasis []string
+}
+
+var vocabulary = map[string]Info{
+	Scheduled:    {Status: Scheduled, Emoji: "✅", LabelKey: "status.scheduled"},
+	Cancelled:    {Status: Cancelled, Emoji: "❌", LabelKey: "status.cancelled", Struck: true},
+	Rescheduled:  {Status: Rescheduled, Emoji: "🔁", LabelKey: "status.rescheduled", Emphasis: []string{FieldTime}},
+	VenueChanged: {Status: VenueChanged, Emoji: "📍", LabelKey: "status.venue_changed", Emphasis: []string{FieldVenue}},
+	Substitute:   {Status: Substitute, Emoji: "🔄", LabelKey: "status.substitute", Emphasis: []string{FieldCourse}},
+	Exam:         {Status: Exam, Emoji: "📝", LabelKey: "status.exam", Emphasis: []string{FieldCourse, FieldTime, FieldVenue}},
+	Free:         {Status: Free, Emoji: "🆓", LabelKey: "status.free"},
+}
+
+var aliases = map[string]string{
+	"canceled":     Cancelled,
+	"cancel":       Cancelled,
+	"venue_change": VenueChanged,
+	"venuechanged": VenueChanged,
+	"substituted":  Substitute,
+	"substitution": Substitute,
+	"examination":  Exam,
+	"free_period":  Free,
+	"on_schedule":  Scheduled,
+	"as_scheduled": Scheduled,
+	"re_scheduled": Rescheduled,
+	"postponed":    Rescheduled,
+	"preponed":     Rescheduled,
+}
+
+// Lookup normalises raw ("Venue Changed", "canceled", ...) and returns its
+// Info. ok is false for empty or unknown statuses, which callers should
+// render verbatim.
+func Lookup(raw string) (Info, bool) {
+	key := Normalize(raw)
+	if canonical, ok := aliases[key]; ok {
+		key = canonical
+	}
+	info, ok := vocabulary[key]
+	return info, ok
+}
+
+// Normalize lower-cases raw and folds spaces and hyphens to underscores.
+func Normalize(raw string) string {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+}
+
+// IsCancelled reports whether raw is a cancelled status.
+func IsCancelled(raw string) bool {
+	info, ok := Lookup(raw)
+	return ok && info.Status == Cancelled
+}
+
+// Emoji returns the emoji for raw, or "" for unknown statuses.
+func Emoji(raw string) string {
+	info, _ := Lookup(raw)
+	return info.Emoji
+}
@@ -0,0 +1,78 @@
+package slotstatus
+
+import (
+	"testing"
+
+	"adapter-matrix/internal/i18n"
+)
+
+func TestLookup(t *testing.T) {
+	cases := []struct {
+		raw    string
+		want   string
+		known  bool
+		emoji  string
+		struck bool
+	}{
+		{raw: "scheduled", want: Scheduled, known: true, emoji: "✅"},
+		{raw: " Cancelled ", want: Cancelled, known: true, emoji: "❌", struck: true},
+		{raw: "canceled", want: Cancelled, known: true, emoji: "❌", struck: true},
+		{raw: "Venue Changed", want: VenueChanged, known: true, emoji: "📍"},
+		{raw: "venue-change", want: VenueChanged, known: true, emoji: "📍"},
+		{raw: "Postponed", want: Rescheduled, known: true, emoji: "🔁"},
+		{raw: "substitution", want: Substitute, known: true, emoji: "🔄"},
+		{raw: "EXAMINATION", want: Exam, known: true, emoji: "📝"},
+		{raw: "free period", want: Free, known: true, emoji: "🆓"},
+		{raw: "as scheduled", want: Scheduled, known: true, emoji: "✅"},
+		{raw: "tentative"},
+		{raw: "  "},
+	}
+	for _, tc := range cases {
+		info, ok := Lookup(tc.raw)
+		if ok != tc.known || info.Status != tc.want {
+			t.Errorf("Lookup(%q) = %q, %t; want %q, %t", tc.raw, info.Status, ok, tc.want, tc.known)
+		}
+		if info.Struck != tc.struck || Emoji(tc.raw) != tc.emoji {
+			t.Errorf("Lookup(%q) struck %t, emoji %q; want %t, %q", tc.raw, info.Struck, Emoji(tc.raw), tc.struck, tc.emoji)
+		}
+		if IsCancelled(tc.raw) != (tc.want == Cancelled) {
+			t.Errorf("IsCancelled(%q) = %t", tc.raw, IsCancelled(tc.raw))
+		}
+	}
+}
+
+func TestVocabulary(t *testing.T) {
+	emphasis := map[string][]string{
+		Rescheduled:  {FieldTime},
+		VenueChanged: {FieldVenue},
+		Substitute:   {FieldCourse},
+		Exam:         {FieldCourse, FieldTime, FieldVenue},
+	}
+	for status, info := range vocabulary {
+		if info.Status != status || info.LabelKey != "status."+status || info.Emoji == "" {
+			t.Errorf("vocabulary[%q] = %+v", status, info)
+		}
+		for _, locale := range i18n.Default().Locales() {
+			if label := i18n.Default().Localizer(locale).T(info.LabelKey); label == info.LabelKey {
+				t.Errorf("%s has no label for %s", locale, info.LabelKey)
+			}
+		}
+		if got, want := info.Emphasis, emphasis[status]; len(got) != len(want) {
+			t.Errorf("%s emphasis = %v, want %v", status, got, want)
+		} else {
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("%s emphasis = %v, want %v", status, got, want)
+				}
+			}
+		}
+	}
+	for alias, status := range aliases {
+		if _, ok := vocabulary[status]; !ok {
+			t.Errorf("alias %q points at unknown status %q", alias, status)
+		}
+		if Normalize(alias) != alias {
+			t.Errorf("alias %q is not normalized, so Lookup never matches it", alias)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadDir reads templates named <EventType>.<variant>.tmpl from dir, e.g.
+// DailyTimetableAnnounced.html.tmpl. Other files are ignored.
+func LoadDir(dir string) ([]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read templates dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".tmpl") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	records := make([]Record, 0, len(names))
+	for _, name := range names {
+		parts := strings.Split(strings.TrimSuffix(name, ".tmpl"), ".")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("template file %s must be named <EventType>.<text|html>.tmpl", name)
+		}
+		path := filepath.Join(dir, name)
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read template %s: %w", path, err)
+		}
+		records = append(records, Record{
+			EventType: parts[0],
+			Variant:   parts[1],
+			Body:      string(body),
+			Origin:    path,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,56 @@
+package templates
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"adapter-matrix/internal/slotstatus"
+)
+
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+// Funcs returns the helper functions available to every template.
+func Funcs() map[string]any {
+	return map[string]any{
+		"date":           formatDate,
+		"statusEmoji":    slotstatus.Emoji,
+		"escapeHTML":     html.EscapeString,
+		"escapeMarkdown": markdownEscaper.Replace,
+		"default":        defaultString,
+		"trim":           strings.TrimSpace,
+		"upper":          strings.ToUpper,
+		"lower":          strings.ToLower,
+		"join":           strings.Join,
+		"add":            func(a, b int) int { return a + b },
+	}
+}
+
+// formatDate reformats a producer date (YYYY-MM-DD or RFC 3339) with a Go
+// layout, returning the input unchanged when it cannot be parsed.
+func formatDate(layout, value string) string {
+	trimmed := strings.TrimSpace(value)
+	for _, candidate := range dateLayouts {
+		if t, err := time.Parse(candidate, trimmed); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return value
+}
+
+func defaultString(fallback, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Source loads template records and reports a cheap fingerprint that changes
+// whenever the records do.
+type Source interface {
+	Load(ctx context.Context) ([]Record, error)
+	Fingerprint(ctx context.Context) (string, error)
+}
+
+// Store caches the compiled Set for a Source and swaps in a new one when the
+// source changes. A reload that fails to compile or validate keeps the
+// previous Set.
+type Store struct {
+	source   Source
+	validate func(*Set) error
+	logger   *log.Logger
+
+	mu          sync.RWMutex
+	set         *Set
+	fingerprint string
+}
+
+func NewStore(source Source, validate func(*Set) error, logger *log.Logger) (*Store, error) {
+	if source == nil {
+		return nil, errors.New("template source is required")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is required")
+	}
+	return &Store{source: source, validate: validate, logger: logger}, nil
+}
+
+// Load compiles and validates the source unconditionally. It is used at
+// startup, where a broken template should stop the adapter.
+func (s *Store) Load(ctx context.Context) error {
+	fingerprint, err := s.source.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+	set, err := s.compile(ctx)
+	if err != nil {
+		return err
+	}
+	s.swap(set, fingerprint)
+	return nil
+}
+
+func (s *Store) Render(key Key, data any) (Rendered, bool, error) {
+	s.mu.RLock()
+	set := s.set
+	s.mu.RUnlock()
+	return set.Render(key, data)
+}
+
+// Refresh reloads the source when its fingerprint has changed.
+func (s *Store) Refresh(ctx context.Context) error {
+	fingerprint, err := s.source.Fingerprint(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	unchanged := fingerprint == s.fingerprint
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	set, err := s.compile(ctx)
+	if err != nil {
+		return err
+	}
+	s.swap(set, fingerprint)
+	s.logger.Printf("templates: reloaded %d event type(s)", len(set.EventTypes()))
+	return nil
+}
+
+// Run polls the source every interval until ctx is cancelled.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Printf("templates: refresh failed, keeping previous templates: %v", err)
+		}
+	}
+}
+
+func (s *Store) compile(ctx context.Context) (*Set, error) {
+	records, err := s.source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set, err := Compile(records)
+	if err != nil {
+		return nil, err
+	}
+	if s.validate != nil {
+		if err := s.validate(set); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (s *Store) swap(set *Set, fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set = set
+	s.fingerprint = fingerprint
+}
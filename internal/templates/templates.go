@@ -0,0 +1,208 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Variant selects which output a template produces.
+const (
+	// VariantText renders the plain/markdown `body` with text/template.
+	VariantText = "text"
+	// VariantHTML renders `formatted_body` with html/template.
+	VariantHTML = "html"
+)
+
+// Record is one template definition before compilation. RoomID and Locale
+// are optional; empty values match any room or locale.
+type Record struct {
+	EventType string
+	RoomID    string
+	Locale    string
+	Variant   string
+	Body      string
+	Origin    string
+}
+
+// Key identifies the template to use for one rendered message.
+type Key struct {
+	EventType string
+	RoomID    string
+	Locale    string
+}
+
+// Rendered holds the outputs of whichever variants exist for an event type.
+type Rendered struct {
+	Text string
+	HTML string
+}
+
+// Renderer renders event data with a configured template, reporting ok=false
+// when no template exists so callers fall back to built-in rendering.
+type Renderer interface {
+	Render(key Key, data any) (Rendered, bool, error)
+}
+
+type entry struct {
+	text   *texttemplate.Template
+	html   *htmltemplate.Template
+	origin string
+}
+
+// Set is a compiled collection of templates keyed by event type, room and
+// locale.
+type Set struct {
+	entries map[Key]*entry
+}
+
+// Compile parses records into a Set. Later records for the same key and
+// variant replace earlier ones, so callers list lower-priority sources first.
+func Compile(records []Record) (*Set, error) {
+	set := &Set{entries: make(map[Key]*entry)}
+	for _, rec := range records {
+		eventType := strings.TrimSpace(rec.EventType)
+		if eventType == "" {
+			return nil, fmt.Errorf("template %s: event type is required", rec.Origin)
+		}
+		key := Key{
+			EventType: eventType,
+			RoomID:    strings.TrimSpace(rec.RoomID),
+			Locale:    strings.TrimSpace(rec.Locale),
+		}
+		e, ok := set.entries[key]
+		if !ok {
+			e = &entry{}
+			set.entries[key] = e
+		}
+		name := eventType + "." + rec.Variant
+		switch rec.Variant {
+		case VariantText:
+			tmpl, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(Funcs())).Option("missingkey=error").Parse(rec.Body)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", rec.Origin, err)
+			}
+			e.text = tmpl
+		case VariantHTML:
+			tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(Funcs())).Option("missingkey=error").Parse(rec.Body)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", rec.Origin, err)
+			}
+			e.html = tmpl
+		default:
+			return nil, fmt.Errorf("template %s: unknown variant %q", rec.Origin, rec.Variant)
+		}
+		e.origin = rec.Origin
+	}
+	return set, nil
+}
+
+// EventTypes lists the event types that have at least one template.
+func (s *Set) EventTypes() []string {
+	seen := make(map[string]struct{})
+	for key := range s.entries {
+		seen[key.EventType] = struct{}{}
+	}
+	out := make([]string, 0, len(seen))
+	for eventType := range seen {
+		out = append(out, eventType)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Render executes the most specific template for key, preferring an exact
+// room and locale match, then room only, then locale only, then the event
+// type default. The text and html variants are resolved independently, so an
+// override defining only one variant keeps the other from further down the
+// chain.
+func (s *Set) Render(key Key, data any) (Rendered, bool, error) {
+	if s == nil {
+		return Rendered{}, false, nil
+	}
+	e, ok := s.lookup(key)
+	if !ok {
+		return Rendered{}, false, nil
+	}
+	out, err := e.execute(data)
+	return out, true, err
+}
+
+func (s *Set) lookup(key Key) (*entry, bool) {
+	candidates := []Key{
+		key,
+		{EventType: key.EventType, RoomID: key.RoomID},
+		{EventType: key.EventType, Locale: key.Locale},
+		{EventType: key.EventType},
+	}
+	var out entry
+	for _, candidate := range candidates {
+		e, ok := s.entries[candidate]
+		if !ok {
+			continue
+		}
+		if out.text == nil {
+			out.text = e.text
+		}
+		if out.html == nil {
+			out.html = e.html
+		}
+	}
+	return &out, out.text != nil || out.html != nil
+}
+
+func (e *entry) execute(data any) (Rendered, error) {
+	var out Rendered
+	if e.text != nil {
+		var buf bytes.Buffer
+		if err := e.text.Execute(&buf, data); err != nil {
+			return Rendered{}, err
+		}
+		out.Text = strings.TrimSpace(buf.String())
+	}
+	if e.html != nil {
+		var buf bytes.Buffer
+		if err := e.html.Execute(&buf, data); err != nil {
+			return Rendered{}, err
+		}
+		out.HTML = strings.TrimSpace(buf.String())
+	}
+	return out, nil
+}
+
+// Validate executes every template against the sample data for its event
+// type so field typos surface at startup instead of on the first delivery.
+// Templates for event types without sample data are rejected.
+func (s *Set) Validate(samples map[string]any) error {
+	keys := make([]Key, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].EventType != keys[j].EventType {
+			return keys[i].EventType < keys[j].EventType
+		}
+		if keys[i].RoomID != keys[j].RoomID {
+			return keys[i].RoomID < keys[j].RoomID
+		}
+		return keys[i].Locale < keys[j].Locale
+	})
+
+	var errs []error
+	for _, key := range keys {
+		e := s.entries[key]
+		sample, ok := samples[key.EventType]
+		if !ok {
+			errs = append(errs, fmt.Errorf("template %s: no handler for event type %s", e.origin, key.EventType))
+			continue
+		}
+		if _, err := e.execute(sample); err != nil {
+			errs = append(errs, fmt.Errorf("template %s: %w", e.origin, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package templates
+
+import "testing"
+
+func TestRenderResolvesVariantsIndependently(t *testing.T) {
+	set, err := Compile([]Record{
+		{EventType: "E", Variant: VariantText, Body: "default text", Origin: "default.text"},
+		{EventType: "E", Variant: VariantHTML, Body: "<p>default html</p>", Origin: "default.html"},
+		{EventType: "E", Locale: "hi", Variant: VariantText, Body: "hi text", Origin: "hi.text"},
+		{EventType: "E", RoomID: "!a", Variant: VariantHTML, Body: "<p>room html</p>", Origin: "room.html"},
+		{EventType: "E", RoomID: "!a", Locale: "hi", Variant: VariantText, Body: "room hi text", Origin: "room-hi.text"},
+		{EventType: "HTMLOnly", Variant: VariantHTML, Body: "<p>only html</p>", Origin: "only.html"},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		key    Key
+		wantOK bool
+		want   Rendered
+	}{
+		{
+			name:   "default",
+			key:    Key{EventType: "E", RoomID: "!b", Locale: "en"},
+			wantOK: true,
+			want:   Rendered{Text: "default text", HTML: "<p>default html</p>"},
+		},
+		{
+			name:   "locale text with default html",
+			key:    Key{EventType: "E", RoomID: "!b", Locale: "hi"},
+			wantOK: true,
+			want:   Rendered{Text: "hi text", HTML: "<p>default html</p>"},
+		},
+		{
+			name:   "room html with default text",
+			key:    Key{EventType: "E", RoomID: "!a", Locale: "en"},
+			wantOK: true,
+			want:   Rendered{Text: "default text", HTML: "<p>room html</p>"},
+		},
+		{
+			name:   "exact text with room html",
+			key:    Key{EventType: "E", RoomID: "!a", Locale: "hi"},
+			wantOK: true,
+			want:   Rendered{Text: "room hi text", HTML: "<p>room html</p>"},
+		},
+		{
+			name:   "html only",
+			key:    Key{EventType: "HTMLOnly"},
+			wantOK: true,
+			want:   Rendered{HTML: "<p>only html</p>"},
+		},
+		{
+			name: "unknown event type",
+			key:  Key{EventType: "Other"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := set.Render(tc.key, nil)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf("Render(%+v) = %+v, %t; want %+v, %t", tc.key, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
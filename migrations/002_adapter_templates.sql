@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS adapter_templates (
+    event_type TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_type, variant)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE adapter_templates ADD COLUMN IF NOT EXISTS room_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE adapter_templates ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE adapter_templates DROP CONSTRAINT IF EXISTS adapter_templates_pkey;
+ALTER TABLE adapter_templates ADD PRIMARY KEY (event_type, room_id, locale, variant);
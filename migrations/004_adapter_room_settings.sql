@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS adapter_room_settings (
+    room_id TEXT PRIMARY KEY,
+    locale TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
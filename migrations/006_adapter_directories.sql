@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS adapter_course_directory (
+    course_code TEXT PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    faculty TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS adapter_venue_directory (
+    venue_code TEXT PRIMARY KEY,
+    building TEXT NOT NULL DEFAULT '',
+    floor TEXT NOT NULL DEFAULT '',
+    map_url TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
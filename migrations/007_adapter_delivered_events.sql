@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS adapter_delivered_events (
+    event_id UUID NOT NULL,
+    part INT NOT NULL,
+    part_count INT NOT NULL,
+    room_id TEXT NOT NULL,
+    matrix_event_id TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (event_id, part)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS adapter_delivered_events_matrix_event_id_idx
+    ON adapter_delivered_events (matrix_event_id);
@@ -0,0 +1 @@
+ALTER TABLE adapter_room_settings ADD COLUMN IF NOT EXISTS msgtype TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS adapter_user_directory (
+    cr45_user_id TEXT PRIMARY KEY,
+    matrix_user_id TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
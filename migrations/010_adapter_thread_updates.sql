@@ -0,0 +1,7 @@
+ALTER TABLE adapter_room_settings ADD COLUMN IF NOT EXISTS thread_updates BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS anchor TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS adapter_delivered_events_anchor_idx
+    ON adapter_delivered_events (room_id, anchor)
+    WHERE anchor <> '';
@@ -0,0 +1,4 @@
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS redacted_at TIMESTAMPTZ;
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS redacted_by UUID;
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS redaction_event_id TEXT;
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS redaction_reason TEXT;
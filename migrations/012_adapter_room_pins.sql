@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS adapter_room_pins (
+    room_id TEXT PRIMARY KEY,
+    matrix_event_id TEXT NOT NULL,
+    pinned_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,8 @@
+ALTER TABLE adapter_room_settings ADD COLUMN IF NOT EXISTS topic_sync BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS adapter_room_topics (
+    room_id TEXT PRIMARY KEY,
+    topic TEXT NOT NULL DEFAULT '',
+    pending_topic TEXT NOT NULL DEFAULT '',
+    set_at TIMESTAMPTZ
+);
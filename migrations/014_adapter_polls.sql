@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS adapter_polls (
+    event_id UUID PRIMARY KEY,
+    poll_event_id TEXT UNIQUE,
+    poll_id TEXT NOT NULL,
+    room_id TEXT NOT NULL,
+    question TEXT NOT NULL,
+    answers JSONB NOT NULL,
+    max_selections INT NOT NULL,
+    context JSONB,
+    closes_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ,
+    closed_by TEXT,
+    end_event_id TEXT
+);
+
+CREATE INDEX IF NOT EXISTS adapter_polls_open_idx
+    ON adapter_polls (closes_at)
+    WHERE closed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS adapter_polls_pending_idx
+    ON adapter_polls (room_id)
+    WHERE poll_event_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS adapter_poll_responses (
+    poll_event_id TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    answers JSONB NOT NULL,
+    response_event_id TEXT NOT NULL,
+    responded_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (poll_event_id, sender)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS adapter_acknowledgements (
+    reaction_event_id TEXT PRIMARY KEY,
+    event_id UUID NOT NULL,
+    room_id TEXT NOT NULL,
+    matrix_event_id TEXT NOT NULL,
+    matrix_user_id TEXT NOT NULL,
+    reaction_key TEXT NOT NULL,
+    reacted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS adapter_acknowledgements_event_id_idx
+    ON adapter_acknowledgements (event_id);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS adapter_read_receipts (
+    event_id UUID NOT NULL,
+    matrix_user_id TEXT NOT NULL,
+    read_at TIMESTAMPTZ NOT NULL,
+    summarized BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (event_id, matrix_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS adapter_read_receipts_unsummarized_idx
+    ON adapter_read_receipts (event_id)
+    WHERE NOT summarized;
+
+CREATE INDEX IF NOT EXISTS adapter_delivered_events_room_sent_idx
+    ON adapter_delivered_events (room_id, sent_at);
+
+CREATE TABLE IF NOT EXISTS adapter_read_positions (
+    room_id TEXT NOT NULL,
+    matrix_user_id TEXT NOT NULL,
+    read_up_to TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (room_id, matrix_user_id)
+);
+
+ALTER TABLE adapter_delivered_events ADD COLUMN IF NOT EXISTS origin_server_ts TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS adapter_delivered_events_room_origin_idx
+    ON adapter_delivered_events (room_id, (COALESCE(origin_server_ts, sent_at)));
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS adapter_inbox (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    matrix_event_id TEXT NOT NULL UNIQUE,
+    room_id TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS adapter_inbox_created_at_idx
+    ON adapter_inbox (created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS adapter_sync_tokens (
+    user_id TEXT PRIMARY KEY,
+    filter_id TEXT NOT NULL DEFAULT '',
+    next_batch TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS adapter_room_members (
+    room_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    membership TEXT NOT NULL,
+    content JSONB NOT NULL,
+    PRIMARY KEY (room_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS adapter_room_state (
+    room_id TEXT PRIMARY KEY,
+    power_levels JSONB,
+    encryption JSONB,
+    create_event JSONB,
+    join_rules JSONB,
+    members_fetched BOOLEAN NOT NULL DEFAULT false
+);
@@ -0,0 +1 @@
+ALTER TABLE adapter_event_state ADD COLUMN IF NOT EXISTS room_id TEXT;